@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/jeffreyyong/news-feeder/internal/config"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+var discoverCommand = &cli.Command{
+	Name:      "discover",
+	Usage:     "Finds the feeds of a website, and registers one of them as a source with --register.",
+	ArgsUsage: "<url>",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "register",
+			Usage: "url of the discovered feed to register as a source",
+		},
+		&cli.StringFlag{
+			Name:  "category",
+			Usage: "category of the registered source",
+		},
+		&cli.StringFlag{
+			Name:  "provider",
+			Usage: "provider of the registered source",
+		},
+	},
+	Action: discoverAction,
+}
+
+func discoverAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("a url is required", 1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return errors.Wrap(err, "loading_config")
+	}
+
+	svc, err := newCLIService(c.Context, cfg)
+	if err != nil {
+		return err
+	}
+
+	candidates, err := svc.DiscoverFeeds(c.Context, c.Args().First())
+	if err != nil {
+		return errors.Wrap(err, "discovering_feeds")
+	}
+
+	enc := json.NewEncoder(c.App.Writer)
+	enc.SetIndent("", "  ")
+
+	register := c.String("register")
+	if register == "" {
+		if candidates == nil {
+			candidates = []*domain.FeedCandidate{}
+		}
+		return enc.Encode(candidates)
+	}
+
+	var chosen *domain.FeedCandidate
+	for _, candidate := range candidates {
+		if candidate.URL == register {
+			chosen = candidate
+			break
+		}
+	}
+	if chosen == nil {
+		return cli.Exit(fmt.Sprintf("%s is not among the %d feeds discovered", register, len(candidates)), 1)
+	}
+	if chosen.SourceID != nil {
+		return cli.Exit(fmt.Sprintf("%s is already registered as source %s", register, chosen.SourceID), 1)
+	}
+
+	source, err := svc.CreateSource(c.Context, &domain.Source{
+		URL:      chosen.URL,
+		Type:     domain.SourceTypeFeed,
+		Enabled:  true,
+		Category: domain.Category(c.String("category")),
+		Provider: domain.Provider(c.String("provider")),
+	})
+	if err != nil {
+		return errors.Wrap(err, "registering_source")
+	}
+	return enc.Encode(source)
+}
@@ -5,17 +5,31 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/jeffreyyong/news-feeder/internal/app"
+	"github.com/jeffreyyong/news-feeder/internal/archive"
 	"github.com/jeffreyyong/news-feeder/internal/config"
 	"github.com/jeffreyyong/news-feeder/internal/crawler"
+	"github.com/jeffreyyong/news-feeder/internal/discover"
+	"github.com/jeffreyyong/news-feeder/internal/diskcache"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/extract"
+	"github.com/jeffreyyong/news-feeder/internal/fetch"
+	"github.com/jeffreyyong/news-feeder/internal/imageproxy"
+	"github.com/jeffreyyong/news-feeder/internal/localfeed"
 	"github.com/jeffreyyong/news-feeder/internal/logging"
 	"github.com/jeffreyyong/news-feeder/internal/rss"
+	"github.com/jeffreyyong/news-feeder/internal/schedule"
+	"github.com/jeffreyyong/news-feeder/internal/scrape"
+	"github.com/jeffreyyong/news-feeder/internal/secret"
 	"github.com/jeffreyyong/news-feeder/internal/service"
+	"github.com/jeffreyyong/news-feeder/internal/sitemap"
 	"github.com/jeffreyyong/news-feeder/internal/store"
 	"github.com/jeffreyyong/news-feeder/internal/twitter"
+	"github.com/jeffreyyong/news-feeder/internal/websub"
 	"github.com/jeffreyyong/news-feeder/pkg/apppostgres"
 	"github.com/jmoiron/sqlx"
 	cli "github.com/urfave/cli/v2"
@@ -23,6 +37,10 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	cliServiceName = "news-feeder-cli"
+)
+
 var command string
 
 func main() {
@@ -49,6 +67,9 @@ func main() {
 		Commands: []*cli.Command{
 			workerCommand,
 			serverCommand,
+			opmlCommand,
+			discoverCommand,
+			reprocessCommand,
 		},
 	}
 
@@ -73,13 +94,147 @@ func newStore(ctx context.Context, s *app.Service, cfg config.Config) (*store.St
 		}
 	})
 
-	return store.New(sqlx.NewDb(postgresDB, "postgres"))
+	opts, err := storeOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return store.New(sqlx.NewDb(postgresDB, "postgres"), opts...)
+}
+
+// newCLIStore creates a store for one-off commands which run outside of app.Run.
+// It expects the database to have been migrated by the server already.
+func newCLIStore(ctx context.Context, cfg config.Config) (*store.Store, error) {
+	postgresDB, err := apppostgres.NewBasicClient(ctx, cliServiceName, cfg.PostgresDSN)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating_postgres_client")
+	}
+
+	opts, err := storeOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return store.New(sqlx.NewDb(postgresDB, "postgres"), opts...)
+}
+
+// archiveOption keeps archived payloads in the configured storage. Payloads
+// archived to disk remain readable from postgres storage while archive.dir
+// exists, and those archived to postgres always do.
+func archiveOption(cfg config.Config, store *store.Store) (service.Option, error) {
+	retention := time.Duration(cfg.Archive.Retention) * time.Second
+	storage := domain.PayloadStorage(cfg.Archive.Storage)
+	if !storage.Valid() {
+		return nil, errors.Errorf("unknown archive storage %q", cfg.Archive.Storage)
+	}
+
+	bodies := map[domain.PayloadStorage]service.Archive{
+		domain.PayloadStoragePostgres: store,
+	}
+	_, statErr := os.Stat(cfg.Archive.Dir)
+	if storage == domain.PayloadStorageDisk || (cfg.Archive.Dir != "" && statErr == nil) {
+		disk, err := archive.NewDisk(cfg.Archive.Dir)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating_archive")
+		}
+		bodies[domain.PayloadStorageDisk] = disk
+	}
+	return service.WithArchive(bodies, storage, retention), nil
+}
+
+// storeOptions gives the store the key the credentials of sources are sealed
+// with, when one is configured.
+func storeOptions(cfg config.Config) ([]store.Option, error) {
+	if cfg.Secrets.Key == "" {
+		return nil, nil
+	}
+	box, err := secret.NewFromBase64(cfg.Secrets.Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating_secret_box")
+	}
+	return []store.Option{store.WithSecretBox(box)}, nil
+}
+
+// newCLIService creates the feed service for one-off commands.
+func newCLIService(ctx context.Context, cfg config.Config) (*service.Service, error) {
+	store, err := newCLIStore(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newService(ctx, nil, cfg, store)
 }
 
 func newService(ctx context.Context, s *app.Service, cfg config.Config, store *store.Store) (*service.Service, error) {
-	parser := rss.NewParser()
-	crawler := crawler.New(parser, cfg.Worker.URLSources)
-	svc, err := service.New(store, crawler)
+	fetcher := fetch.New(
+		fetch.WithUserAgent(cfg.Fetch.UserAgent),
+		fetch.WithRateLimit(cfg.Fetch.RequestsPerSecond, cfg.Fetch.Burst),
+		fetch.WithRobotsTTL(time.Duration(cfg.Fetch.RobotsTTL)*time.Second),
+	)
+	parser := rss.NewParser(fetcher, rss.WithMaxSize(cfg.Fetch.MaxBodySize))
+	scraper := scrape.NewParser(fetcher, scrape.WithMaxSize(cfg.Fetch.MaxBodySize))
+	crawlerOpts := []crawler.Option{
+		crawler.WithConcurrency(cfg.Worker.Concurrency),
+		crawler.WithSourceTimeout(time.Duration(cfg.Worker.SourceTimeout) * time.Second),
+		crawler.WithParser(domain.SourceTypeSitemap, sitemap.NewParser(fetcher, sitemap.WithMaxSize(cfg.Fetch.MaxBodySize))),
+		crawler.WithParser(domain.SourceTypeScrape, scraper),
+	}
+
+	opts := []service.Option{
+		service.WithSchedulePolicy(schedule.Policy{
+			Min:     time.Duration(cfg.Worker.MinPollInterval) * time.Second,
+			Max:     time.Duration(cfg.Worker.MaxPollInterval) * time.Second,
+			Initial: time.Duration(cfg.Worker.InitialPollInterval) * time.Second,
+		}),
+		service.WithScraper(scraper),
+		service.WithDiscoverer(discover.New(fetcher, parser)),
+	}
+
+	var feedParser crawler.Parser = parser
+	if cfg.Files.Root != "" {
+		reader, err := localfeed.New(parser, cfg.Files.Root, localfeed.WithMaxSize(cfg.Fetch.MaxBodySize))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read local files")
+		}
+		feedParser = reader
+		crawlerOpts = append(crawlerOpts, crawler.WithParser(domain.SourceTypeDirectory, reader))
+		opts = append(opts, service.WithLocalFiles(cfg.Files.Root))
+	}
+	crawler := crawler.New(feedParser, store, crawlerOpts...)
+
+	if cfg.WebSub.CallbackURL != "" {
+		opts = append(opts, service.WithWebSub(websub.NewClient(), cfg.WebSub.CallbackURL,
+			cfg.WebSub.LeaseSeconds, time.Duration(cfg.WebSub.RenewBefore)*time.Second))
+	}
+	if cfg.Archive.Enabled {
+		opt, err := archiveOption(cfg, store)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+
+	extractor := extract.New(fetcher)
+	if cfg.Extraction.Enabled {
+		opts = append(opts, service.WithExtractor(extractor, cfg.Extraction.BatchSize))
+	}
+	if cfg.Canonical.Resolve {
+		opts = append(opts, service.WithCanonicalResolver(extractor))
+	}
+	if cfg.Media.ResolvePage {
+		opts = append(opts, service.WithImageResolver(extractor))
+	}
+	if cfg.Images.Proxy {
+		cache, err := diskcache.New(cfg.Images.CacheDir, cfg.Images.CacheSize<<20)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to open image cache")
+		}
+		opts = append(opts, service.WithImageProxy(imageproxy.New(fetcher, cache, cfg.Images.Widths...)))
+	}
+	if cfg.Stories.Enabled {
+		opts = append(opts, service.WithStoryClustering(cfg.Stories.Similarity,
+			time.Duration(cfg.Stories.Window)*time.Second))
+	}
+
+	svc, err := service.New(store, crawler, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/jeffreyyong/news-feeder/internal/config"
+	"github.com/jeffreyyong/news-feeder/internal/opml"
+)
+
+var opmlCommand = &cli.Command{
+	Name:  "opml",
+	Usage: "Imports or exports the feed sources as OPML.",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "import",
+			Usage:     "Registers the feeds of an OPML file as sources.",
+			ArgsUsage: "<file>",
+			Action:    opmlImportAction,
+		},
+		{
+			Name:      "export",
+			Usage:     "Writes the registered sources as an OPML 2.0 file, to stdout if no file is given.",
+			ArgsUsage: "[file]",
+			Action:    opmlExportAction,
+		},
+	},
+}
+
+func opmlImportAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return cli.Exit("an opml file is required", 1)
+	}
+
+	f, err := os.Open(c.Args().First())
+	if err != nil {
+		return errors.Wrap(err, "opening_opml_file")
+	}
+	defer f.Close()
+
+	doc, err := opml.Parse(f)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return errors.Wrap(err, "loading_config")
+	}
+
+	svc, err := newCLIService(c.Context, cfg)
+	if err != nil {
+		return err
+	}
+
+	result, err := svc.ImportOPML(c.Context, doc)
+	if err != nil {
+		return errors.Wrap(err, "importing_opml")
+	}
+
+	enc := json.NewEncoder(c.App.Writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+func opmlExportAction(c *cli.Context) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return errors.Wrap(err, "loading_config")
+	}
+
+	svc, err := newCLIService(c.Context, cfg)
+	if err != nil {
+		return err
+	}
+
+	doc, err := svc.ExportOPML(c.Context)
+	if err != nil {
+		return errors.Wrap(err, "exporting_opml")
+	}
+
+	var w io.Writer = c.App.Writer
+	if c.NArg() > 0 {
+		f, err := os.Create(c.Args().First())
+		if err != nil {
+			return errors.Wrap(err, "creating_opml_file")
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := opml.Write(w, doc); err != nil {
+		return err
+	}
+	if c.NArg() > 0 {
+		fmt.Fprintf(c.App.ErrWriter, "exported %d sources to %s\n", countOutlines(doc.Body.Outlines), c.Args().First())
+	}
+	return nil
+}
+
+func countOutlines(outlines []*opml.Outline) int {
+	var n int
+	for _, o := range outlines {
+		if o.XMLURL != "" {
+			n++
+		}
+		n += countOutlines(o.Outlines)
+	}
+	return n
+}
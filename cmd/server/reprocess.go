@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	uuid "github.com/kevinburke/go.uuid"
+	"github.com/pkg/errors"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/jeffreyyong/news-feeder/internal/config"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+var reprocessCommand = &cli.Command{
+	Name:  "reprocess",
+	Usage: "Replays archived feed documents through the current parser and stores their articles again.",
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "source",
+			Usage: "id of a source whose documents are replayed, may be repeated",
+		},
+		&cli.TimestampFlag{
+			Name:   "from",
+			Usage:  "replays the documents archived from this time, RFC 3339",
+			Layout: time.RFC3339,
+		},
+		&cli.TimestampFlag{
+			Name:   "to",
+			Usage:  "replays the documents archived before this time, RFC 3339",
+			Layout: time.RFC3339,
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "parses the documents without storing anything",
+		},
+		&cli.BoolFlag{
+			Name:  "overwrite-newer",
+			Usage: "also rewrites the articles revised since a later document was archived",
+		},
+	},
+	Action: reprocessAction,
+}
+
+func reprocessAction(c *cli.Context) error {
+	filters := &domain.SelectPayloadFilters{
+		From: c.Timestamp("from"),
+		To:   c.Timestamp("to"),
+	}
+	for _, s := range c.StringSlice("source") {
+		id, err := uuid.FromString(s)
+		if err != nil {
+			return cli.Exit("bad source id: "+s, 1)
+		}
+		filters.SourceIDs = append(filters.SourceIDs, id)
+	}
+	// replaying the whole archive is rarely meant
+	if len(filters.SourceIDs) == 0 && filters.From == nil && filters.To == nil {
+		return cli.Exit("a source or a time range is required", 1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return errors.Wrap(err, "loading_config")
+	}
+
+	svc, err := newCLIService(c.Context, cfg)
+	if err != nil {
+		return err
+	}
+
+	result, err := svc.ReprocessPayloads(c.Context, filters, c.Bool("dry-run"), c.Bool("overwrite-newer"))
+	if err != nil {
+		return errors.Wrap(err, "reprocessing_payloads")
+	}
+
+	enc := json.NewEncoder(c.App.Writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
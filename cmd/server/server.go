@@ -10,6 +10,7 @@ import (
 	"github.com/jeffreyyong/news-feeder/internal/app"
 	"github.com/jeffreyyong/news-feeder/internal/app/listeners/httplistener"
 	"github.com/jeffreyyong/news-feeder/internal/config"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
 	"github.com/jeffreyyong/news-feeder/internal/logging"
 	"github.com/jeffreyyong/news-feeder/internal/transport/transporthttp"
 )
@@ -70,12 +71,38 @@ func serverSetup(ctx context.Context, s *app.Service) ([]app.Listener, context.C
 		return nil, ctx, errors.Wrap(err, "unable to create feed service")
 	}
 
+	// sources from the config are only registered on first boot, after that
+	// they are managed through the admin API
+	seeds := make([]*domain.Source, len(cfg.Worker.URLSources))
+	for i, seed := range cfg.Worker.URLSources {
+		seeds[i] = &domain.Source{
+			URL:      seed.URL,
+			Category: domain.Category(seed.Category),
+			Provider: domain.Provider(seed.Provider),
+		}
+	}
+	seeded, err := feedService.SeedSources(ctx, seeds)
+	if err != nil {
+		return nil, ctx, errors.Wrap(err, "unable to seed sources")
+	}
+	if seeded > 0 {
+		logging.Print(ctx, "seeded sources from config", zap.Int("count", seeded))
+	}
+
 	socialService, err := newSocialService(ctx, s, cfg, store)
 	if err != nil {
 		return nil, ctx, errors.Wrap(err, "unable to create social service")
 	}
 
-	h, err := transporthttp.NewHTTPHandler(feedService, socialService, transporthttp.WithAuth(cfg.PrivilegedTokens))
+	handlerOpts := []transporthttp.MiddlewareFunc{
+		transporthttp.WithAuth(cfg.PrivilegedTokens),
+		transporthttp.WithAdminAuth(cfg.AdminTokens),
+	}
+	if cfg.Images.Proxy {
+		handlerOpts = append(handlerOpts, transporthttp.WithImageProxy(cfg.Images.BaseURL))
+	}
+
+	h, err := transporthttp.NewHTTPHandler(feedService, socialService, handlerOpts...)
 	if err != nil {
 		logging.Error(ctx, "creating_http_handler", zap.Error(err))
 		return nil, ctx, err
@@ -58,6 +58,9 @@ func workerSetup(ctx context.Context, s *app.Service) ([]app.Listener, context.C
 	}
 
 	return []app.Listener{
-		worker.New(svc, time.Duration(cfg.Worker.Interval)*time.Second),
+		worker.New(svc,
+			time.Duration(cfg.Worker.Interval)*time.Second,
+			time.Duration(cfg.Worker.UpkeepInterval)*time.Second,
+		),
 	}, ctx, nil
 }
@@ -4,7 +4,9 @@ go 1.17
 
 require (
 	github.com/Masterminds/squirrel v1.5.3
+	github.com/PuerkitoBio/goquery v1.5.1
 	github.com/XSAM/otelsql v0.14.1
+	github.com/andybalholm/cascadia v1.1.0
 	github.com/cenkalti/backoff v2.2.1+incompatible
 	github.com/dghubble/go-twitter v0.0.0-20220706021256-cdf1c5ea4e19
 	github.com/dghubble/oauth1 v0.7.1
@@ -21,6 +23,8 @@ require (
 	go.opentelemetry.io/otel/trace v1.7.0
 	go.uber.org/automaxprocs v1.5.1
 	go.uber.org/zap v1.21.0
+	golang.org/x/net v0.0.0-20220225172249-27dd8689420f
+	golang.org/x/time v0.0.0-20220224211638-0e9765cccd65
 	gopkg.in/DataDog/dd-trace-go.v1 v1.39.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -31,8 +35,6 @@ require (
 	github.com/DataDog/datadog-go/v5 v5.0.2 // indirect
 	github.com/DataDog/sketches-go v1.2.1 // indirect
 	github.com/Microsoft/go-winio v0.5.2 // indirect
-	github.com/PuerkitoBio/goquery v1.5.1 // indirect
-	github.com/andybalholm/cascadia v1.1.0 // indirect
 	github.com/cenkalti/backoff/v4 v4.1.3 // indirect
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
@@ -64,10 +66,8 @@ require (
 	go.uber.org/multierr v1.6.0 // indirect
 	go4.org/intern v0.0.0-20211027215823-ae77deb06f29 // indirect
 	go4.org/unsafe/assume-no-moving-gc v0.0.0-20211027215541-db492cf91b37 // indirect
-	golang.org/x/net v0.0.0-20220225172249-27dd8689420f // indirect
 	golang.org/x/sys v0.0.0-20220317061510-51cd9980dadf // indirect
 	golang.org/x/text v0.3.7 // indirect
-	golang.org/x/time v0.0.0-20220224211638-0e9765cccd65 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	google.golang.org/protobuf v1.27.1 // indirect
 	inet.af/netaddr v0.0.0-20211027220019-c74959edd3b6 // indirect
@@ -10,19 +10,33 @@ import (
 
 type Service interface {
 	CrawlFeeds(ctx context.Context) error
+	RenewSubscriptions(ctx context.Context) error
+	ExtractArticles(ctx context.Context) error
+	PruneArchive(ctx context.Context) error
 }
 
+// defaultUpkeepInterval is how often upkeep runs unless told otherwise.
+const defaultUpkeepInterval = 5 * time.Minute
+
 type Worker struct {
-	interval time.Duration
-	service  Service
+	interval       time.Duration
+	upkeepInterval time.Duration
+	service        Service
 
 	ctxCancel func()
 }
 
-func New(service Service, interval time.Duration) *Worker {
+// New creates a worker crawling the sources that are due every interval, and
+// running the upkeep of subscriptions, extraction and the archive every
+// upkeepInterval, defaultUpkeepInterval when 0.
+func New(service Service, interval, upkeepInterval time.Duration) *Worker {
+	if upkeepInterval <= 0 {
+		upkeepInterval = defaultUpkeepInterval
+	}
 	return &Worker{
-		service:  service,
-		interval: interval,
+		service:        service,
+		interval:       interval,
+		upkeepInterval: upkeepInterval,
 	}
 }
 
@@ -34,6 +48,12 @@ func (w *Worker) Serve(ctx context.Context) error {
 
 	ticker := time.NewTicker(w.interval)
 	defer ticker.Stop()
+	upkeepTicker := time.NewTicker(w.upkeepInterval)
+	defer upkeepTicker.Stop()
+
+	// work straight away rather than a full interval after starting
+	w.work(ctx)
+	w.upkeep(ctx)
 
 	for {
 		select {
@@ -41,14 +61,35 @@ func (w *Worker) Serve(ctx context.Context) error {
 			logging.Print(ctx, "stopped worker")
 			return nil
 		case <-ticker.C:
-			logging.Print(ctx, "worker working")
-			if err := w.service.CrawlFeeds(ctx); err != nil {
-				logging.Error(ctx, "failed to fetch articles", zap.Error(err))
-			}
+			w.work(ctx)
+		case <-upkeepTicker.C:
+			w.upkeep(ctx)
 		}
 	}
 }
 
+// work crawls the sources that are due, every source being scheduled on its own.
+func (w *Worker) work(ctx context.Context) {
+	logging.Print(ctx, "worker working")
+	if err := w.service.CrawlFeeds(ctx); err != nil {
+		logging.Error(ctx, "failed to fetch articles", zap.Error(err))
+	}
+}
+
+// upkeep renews websub subscriptions, extracts the content of new articles
+// and prunes the archive, none of which has to keep up with every crawl.
+func (w *Worker) upkeep(ctx context.Context) {
+	if err := w.service.RenewSubscriptions(ctx); err != nil {
+		logging.Error(ctx, "failed to renew websub subscriptions", zap.Error(err))
+	}
+	if err := w.service.ExtractArticles(ctx); err != nil {
+		logging.Error(ctx, "failed to extract articles", zap.Error(err))
+	}
+	if err := w.service.PruneArchive(ctx); err != nil {
+		logging.Error(ctx, "failed to prune archived payloads", zap.Error(err))
+	}
+}
+
 func (w *Worker) Close(ctx context.Context) error {
 	logging.Print(ctx, "stop worker")
 	w.ctxCancel()
@@ -0,0 +1,118 @@
+// Package archive keeps the bodies of archived feed payloads in files of a
+// local directory, one folder per day they were archived on.
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jonboulle/clockwork"
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+// tempPrefix marks the files being written, which are not bodies yet.
+const tempPrefix = ".tmp-"
+
+var ErrInvalidLocation = errors.New("invalid payload location")
+
+// Disk is safe for concurrent use, every body being a file of its own.
+type Disk struct {
+	dir   string
+	clock clockwork.Clock
+}
+
+type Option func(*Disk)
+
+// WithClock functionally configures the archive with a clock.
+func WithClock(clock clockwork.Clock) Option {
+	return func(d *Disk) {
+		d.clock = clock
+	}
+}
+
+// NewDisk opens the archive in dir, creating the directory if needed.
+func NewDisk(dir string, opts ...Option) (*Disk, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive directory: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating archive directory: %w", err)
+	}
+
+	d := &Disk{
+		dir:   abs,
+		clock: clockwork.NewRealClock(),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d, nil
+}
+
+// PutPayloadBody writes the body to a new file, returning its path relative
+// to the archive directory as its location.
+func (d *Disk) PutPayloadBody(ctx context.Context, body []byte) (string, error) {
+	location := filepath.Join(d.clock.Now().UTC().Format("2006/01/02"), uuid.NewV4().String()+".gz")
+	path := filepath.Join(d.dir, location)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("error creating archive folder: %w", err)
+	}
+
+	// written aside and renamed so that a body is never read half written
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempPrefix)
+	if err != nil {
+		return "", fmt.Errorf("error creating payload file: %w", err)
+	}
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("error writing payload file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("error writing payload file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("error writing payload file: %w", err)
+	}
+	return filepath.ToSlash(location), nil
+}
+
+// GetPayloadBody reads the body at the location.
+func (d *Disk) GetPayloadBody(ctx context.Context, location string) ([]byte, error) {
+	path, err := d.path(location)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// DeletePayloadBody removes the body at the location, which may already be
+// gone.
+func (d *Disk) DeletePayloadBody(ctx context.Context, location string) error {
+	path, err := d.path(location)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	// the folder of the day goes once its last body does, failing while not empty
+	_ = os.Remove(filepath.Dir(path))
+	return nil
+}
+
+// path is the file of the location, which must be under the archive directory.
+func (d *Disk) path(location string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(location))
+	if location == "" || filepath.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocation, location)
+	}
+	return filepath.Join(d.dir, rel), nil
+}
@@ -0,0 +1,85 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+func TestDiskPath(t *testing.T) {
+	d, err := NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		location string
+		want     string
+		wantErr  bool
+	}{
+		{name: "body", location: "2021/06/01/a.gz", want: filepath.Join(d.dir, "2021", "06", "01", "a.gz")},
+		{name: "cleaned", location: "2021/06/../06/01/a.gz", want: filepath.Join(d.dir, "2021", "06", "01", "a.gz")},
+		{name: "empty", location: "", wantErr: true},
+		{name: "archive directory", location: ".", wantErr: true},
+		{name: "parent", location: "..", wantErr: true},
+		{name: "traversal", location: "../../etc/passwd", wantErr: true},
+		{name: "traversal after cleaning", location: "2021/../../etc/passwd", wantErr: true},
+		{name: "absolute", location: "/etc/passwd", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.path(tt.location)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidLocation) {
+					t.Errorf("path(%q) error = %v, want %v", tt.location, err, ErrInvalidLocation)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("path(%q) error = %v", tt.location, err)
+			}
+			if got != tt.want {
+				t.Errorf("path(%q) = %q, want %q", tt.location, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiskBodies(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC))
+	d, err := NewDisk(t.TempDir(), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	location, err := d.PutPayloadBody(ctx, []byte("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(location) != "2021/06/01" {
+		t.Errorf("location %q not in the folder of its day", location)
+	}
+
+	body, err := d.GetPayloadBody(ctx, location)
+	if err != nil || string(body) != "body" {
+		t.Errorf("GetPayloadBody = %q, %v, want %q", body, err, "body")
+	}
+
+	if err := d.DeletePayloadBody(ctx, location); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(d.dir, "2021", "06", "01")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty folder of the day left behind: %v", err)
+	}
+	if err := d.DeletePayloadBody(ctx, location); err != nil {
+		t.Errorf("deleting a body already gone: %v", err)
+	}
+}
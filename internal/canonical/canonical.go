@@ -0,0 +1,108 @@
+// Package canonical normalizes article URLs so the same story linked from
+// different feeds, or with different tracking parameters, compares equal.
+package canonical
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// trackingParams are query parameters that only identify where a click came
+// from, on top of the families of trackingPrefixes.
+var trackingParams = map[string]bool{
+	"cmpid":   true,
+	"dclid":   true,
+	"fbclid":  true,
+	"gclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"msclkid": true,
+	"ocid":    true,
+	"smid":    true,
+	"yclid":   true,
+	"_ga":     true,
+}
+
+// trackingPrefixes are prefixes of families of tracking parameters.
+var trackingPrefixes = []string{"utm_", "at_", "ns_", "__"}
+
+// URL normalizes an absolute http(s) URL: the scheme and host are lowercased,
+// default ports, fragments and tracking parameters are dropped and the
+// remaining parameters are sorted. Anything that is not such a URL is
+// returned trimmed but otherwise untouched.
+func URL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return raw
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		// an IPv6 address
+		host = "[" + host + "]"
+	}
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for key := range query {
+		if isTracking(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = encodeSorted(query)
+
+	return u.String()
+}
+
+func isTracking(key string) bool {
+	key = strings.ToLower(key)
+	if trackingParams[key] {
+		return true
+	}
+	for _, prefix := range trackingPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeSorted encodes the query sorted by key, keeping the order of repeated values.
+func encodeSorted(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range query[k] {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(k))
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(v))
+		}
+	}
+	return b.String()
+}
@@ -10,17 +10,73 @@ import (
 
 const (
 	defaultConfigFilePath = "config.yaml"
+
+	// secretsKeyEnv overrides secrets.key, which is better kept out of the file.
+	secretsKeyEnv = "NEWS_FEEDER_SECRETS_KEY"
 )
 
 // Config variables for the application
 type Config struct {
 	PostgresDSN      string            `yaml:"postgres_dsn"`
 	PrivilegedTokens map[string]string `yaml:"privileged_tokens"`
+	AdminTokens      map[string]string `yaml:"admin_tokens"`
 	MigrationPath    string            `yaml:"migration_path"`
 	Worker           struct {
-		URLSources []string `yaml:"url_sources"`
-		Interval   int      `yaml:"interval"`
+		URLSources          []SeedSource `yaml:"url_sources"`
+		Interval            int          `yaml:"interval"`
+		UpkeepInterval      int          `yaml:"upkeep_interval"`
+		Concurrency         int          `yaml:"concurrency"`
+		SourceTimeout       int          `yaml:"source_timeout"`
+		MinPollInterval     int          `yaml:"min_poll_interval"`
+		MaxPollInterval     int          `yaml:"max_poll_interval"`
+		InitialPollInterval int          `yaml:"initial_poll_interval"`
 	} `yaml:"worker"`
+	WebSub struct {
+		CallbackURL  string `yaml:"callback_url"`
+		LeaseSeconds int    `yaml:"lease_seconds"`
+		RenewBefore  int    `yaml:"renew_before"`
+	} `yaml:"websub"`
+	Extraction struct {
+		Enabled   bool `yaml:"enabled"`
+		BatchSize int  `yaml:"batch_size"`
+	} `yaml:"extraction"`
+	Canonical struct {
+		Resolve bool `yaml:"resolve"`
+	} `yaml:"canonical"`
+	Media struct {
+		ResolvePage bool `yaml:"resolve_page"`
+	} `yaml:"media"`
+	Images struct {
+		Proxy     bool   `yaml:"proxy"`
+		BaseURL   string `yaml:"base_url"`
+		Widths    []int  `yaml:"widths"`
+		CacheDir  string `yaml:"cache_dir"`
+		CacheSize int64  `yaml:"cache_size"`
+	} `yaml:"images"`
+	Stories struct {
+		Enabled    bool    `yaml:"enabled"`
+		Similarity float64 `yaml:"similarity"`
+		Window     int     `yaml:"window"`
+	} `yaml:"stories"`
+	Fetch struct {
+		UserAgent         string  `yaml:"user_agent"`
+		RequestsPerSecond float64 `yaml:"requests_per_second"`
+		Burst             int     `yaml:"burst"`
+		RobotsTTL         int     `yaml:"robots_ttl"`
+		MaxBodySize       int64   `yaml:"max_body_size"`
+	} `yaml:"fetch"`
+	Files struct {
+		Root string `yaml:"root"`
+	} `yaml:"files"`
+	Archive struct {
+		Enabled   bool   `yaml:"enabled"`
+		Storage   string `yaml:"storage"`
+		Dir       string `yaml:"dir"`
+		Retention int    `yaml:"retention"`
+	} `yaml:"archive"`
+	Secrets struct {
+		Key string `yaml:"key"`
+	} `yaml:"secrets"`
 	Social struct {
 		Twitter struct {
 			ConsumerKey    string `yaml:"consumer_key"`
@@ -31,6 +87,24 @@ type Config struct {
 	} `yaml:"social"`
 }
 
+// SeedSource is a source seeded on first boot, given either as its url alone
+// or with the category and provider slugs it is assigned to.
+type SeedSource struct {
+	URL      string `yaml:"url"`
+	Category string `yaml:"category"`
+	Provider string `yaml:"provider"`
+}
+
+// UnmarshalYAML reads a seed source given as a plain url or as a mapping.
+func (s *SeedSource) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		s.URL = value.Value
+		return nil
+	}
+	type plain SeedSource
+	return value.Decode((*plain)(s))
+}
+
 // Load loads the configuration for the application.
 func Load() (Config, error) {
 	var config Config
@@ -47,5 +121,9 @@ func Load() (Config, error) {
 		return Config{}, errors.Wrap(err, "failed to decode config")
 	}
 
+	if key := os.Getenv(secretsKeyEnv); key != "" {
+		config.Secrets.Key = key
+	}
+
 	return config, nil
 }
@@ -2,40 +2,241 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"go.uber.org/zap"
 
 	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+)
+
+const (
+	defaultConcurrency   = 4
+	defaultSourceTimeout = 30 * time.Second
 )
 
 type Parser interface {
-	Parse(ctx context.Context, url string) (*domain.Feed, error)
+	Parse(ctx context.Context, source *domain.Source) (*domain.Feed, error)
+}
+
+// BodyParser maps a feed document that was delivered to us, such as a WebSub
+// push, rather than fetched.
+type BodyParser interface {
+	ParseBody(ctx context.Context, source *domain.Source, body []byte) (*domain.Feed, error)
+}
+
+// Store provides the sources to crawl and looks up previously crawled feeds
+// so their cache validators can be sent along with the next fetch.
+type Store interface {
+	SelectSources(ctx context.Context, f *domain.SelectSourceFilters) ([]*domain.Source, error)
+	SelectFeeds(ctx context.Context, f *domain.SelectFeedFilters) ([]*domain.Feed, error)
 }
 
-func New(parser Parser, sources []string) *Crawler {
-	return &Crawler{
-		feedParser: parser,
-		sources:    sources,
-		results:    make(chan *domain.Feed),
+// New creates a crawler parsing feed sources with parser, the parsers of other
+// source types are given with WithParser.
+func New(parser Parser, store Store, opts ...Option) *Crawler {
+	c := &Crawler{
+		parsers:       map[domain.SourceType]Parser{domain.SourceTypeFeed: parser},
+		store:         store,
+		clock:         clockwork.NewRealClock(),
+		concurrency:   defaultConcurrency,
+		sourceTimeout: defaultSourceTimeout,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 type Crawler struct {
-	sources    []string
-	feedParser Parser
-	results    chan *domain.Feed
+	parsers       map[domain.SourceType]Parser
+	store         Store
+	clock         clockwork.Clock
+	concurrency   int
+	sourceTimeout time.Duration
 }
 
-func (c *Crawler) Crawl(ctx context.Context) ([]*domain.Feed, error) {
-	var feeds []*domain.Feed
+// Crawl fetches and parses every enabled source that is due through a pool
+// of at most concurrency workers. The sources are read from the store on
+// every run. Each source is given its own timeout and succeeds or fails on
+// its own; the returned results are in the same order as the sources
+// regardless of which finished first. When ctx is done before the crawl is,
+// the results of the sources that finished are returned along with its error.
+func (c *Crawler) Crawl(ctx context.Context) ([]*domain.CrawlResult, error) {
+	sources, err := c.loadSources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]*domain.CrawlResult, len(sources))
+		jobs    = make(chan int)
+	)
+
+	workers := c.concurrency
+	if workers > len(sources) {
+		workers = len(sources)
+	}
 
-	for _, s := range c.sources {
-		feed, err := c.feedParser.Parse(ctx, s)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing url (%s): %w", s, err)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := c.crawlSource(ctx, sources[i])
+				// a source cut short by the end of the crawl did not fail
+				if result.Err != nil && ctx.Err() != nil {
+					continue
+				}
+				results[i] = result
+			}
+		}()
+	}
+
+dispatch:
+	for i := range sources {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
 		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		var finished []*domain.CrawlResult
+		for _, r := range results {
+			if r != nil {
+				finished = append(finished, r)
+			}
+		}
+		return finished, err
+	}
+
+	return results, nil
+}
+
+// loadSources lists the sources due for a crawl and pairs each with the cache
+// validators of its feed, if it has been crawled before. Failing to look the
+// validators up only costs a full fetch.
+func (c *Crawler) loadSources(ctx context.Context) ([]*domain.Source, error) {
+	enabled := true
+	now := c.clock.Now()
+	sources, err := c.store.SelectSources(ctx, &domain.SelectSourceFilters{
+		Enabled: &enabled,
+		DueAt:   &now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error loading sources: %w", err)
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	urls := make([]string, len(sources))
+	for i, s := range sources {
+		urls[i] = s.URL
+	}
 
-		feeds = append(feeds, feed)
+	feeds, err := c.store.SelectFeeds(ctx, &domain.SelectFeedFilters{FeedLinks: urls})
+	if err != nil {
+		logging.Error(ctx, "failed to load feed validators", zap.Error(err))
 	}
 
-	return feeds, nil
+	validators := make(map[string]*domain.Feed, len(feeds))
+	for _, f := range feeds {
+		validators[f.FeedLink] = f
+	}
+
+	for _, s := range sources {
+		if f, ok := validators[s.URL]; ok {
+			s.ETag = f.ETag
+			s.LastModified = f.LastModified
+		}
+	}
+	return sources, nil
+}
+
+// Ingest parses a feed document pushed for the source into a result, the same
+// way Crawl reports a fetched one.
+func (c *Crawler) Ingest(ctx context.Context, source *domain.Source, body []byte) *domain.CrawlResult {
+	start := time.Now()
+	result := &domain.CrawlResult{SourceID: source.ID, Source: source.URL}
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	bp, ok := c.parsers[sourceType(source)].(BodyParser)
+	if !ok {
+		result.Err = fmt.Errorf("parser does not support pushed content (%s)", source.URL)
+		return result
+	}
+
+	feed, err := bp.ParseBody(ctx, source, body)
+	if err != nil {
+		result.Err = fmt.Errorf("error parsing pushed content (%s): %w", source.URL, err)
+	} else {
+		result.Feed = feed
+	}
+
+	return result
+}
+
+func (c *Crawler) crawlSource(ctx context.Context, source *domain.Source) *domain.CrawlResult {
+	ctx, cancel := context.WithTimeout(ctx, c.sourceTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := &domain.CrawlResult{SourceID: source.ID, Source: source.URL}
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	if source.AuthErr != nil {
+		result.Err = fmt.Errorf("credentials of source (%s) cannot be decrypted: %w", source.URL, source.AuthErr)
+		return result
+	}
+
+	parser, ok := c.parsers[sourceType(source)]
+	if !ok {
+		result.Err = fmt.Errorf("no parser for %s sources (%s)", source.Type, source.URL)
+		return result
+	}
+
+	feed, err := parser.Parse(ctx, source)
+	switch {
+	case errors.Is(err, domain.ErrNotModified):
+		result.NotModified = true
+	case err != nil:
+		// crawl errors are logged and stored, never with the credentials of the source
+		result.Err = source.Auth.RedactError(fmt.Errorf("error parsing url (%s): %w", source.URL, err))
+		result.Gone = errors.Is(err, domain.ErrSourceGone)
+	default:
+		result.Feed = feed
+		result.MovedTo = feed.MovedTo
+	}
+
+	var moved *domain.SourceMovedError
+	if errors.As(err, &moved) {
+		result.MovedTo = moved.URL
+	}
+
+	return result
+}
+
+// sourceType is the type of the source, sources registered before types were
+// introduced being feeds.
+func sourceType(source *domain.Source) domain.SourceType {
+	if source.Type == "" {
+		return domain.SourceTypeFeed
+	}
+	return source.Type
 }
@@ -0,0 +1,173 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/kevinburke/go.uuid"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// stubParser parses every source into an empty feed after its delay, or
+// blocks until ctx is done for the sources in block. It records the most
+// sources it was parsing at once.
+type stubParser struct {
+	delay map[string]time.Duration
+	block map[string]bool
+	done  chan string
+
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (p *stubParser) Parse(ctx context.Context, source *domain.Source) (*domain.Feed, error) {
+	p.mu.Lock()
+	p.running++
+	if p.running > p.peak {
+		p.peak = p.running
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.running--
+		p.mu.Unlock()
+	}()
+
+	if p.block[source.URL] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	select {
+	case <-time.After(p.delay[source.URL]):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if p.done != nil {
+		p.done <- source.URL
+	}
+	return &domain.Feed{FeedLink: source.URL}, nil
+}
+
+type stubStore struct {
+	sources []*domain.Source
+}
+
+func (s stubStore) SelectSources(ctx context.Context, f *domain.SelectSourceFilters) ([]*domain.Source, error) {
+	return s.sources, nil
+}
+
+func (s stubStore) SelectFeeds(ctx context.Context, f *domain.SelectFeedFilters) ([]*domain.Feed, error) {
+	return nil, nil
+}
+
+func sources(urls ...string) []*domain.Source {
+	var sources []*domain.Source
+	for _, u := range urls {
+		sources = append(sources, &domain.Source{ID: uuid.NewV4(), URL: u})
+	}
+	return sources
+}
+
+func resultURLs(results []*domain.CrawlResult) []string {
+	var urls []string
+	for _, r := range results {
+		urls = append(urls, r.Source)
+	}
+	return urls
+}
+
+func TestCrawlOrderAndConcurrency(t *testing.T) {
+	var urls []string
+	delay := make(map[string]time.Duration)
+	for i := 0; i < 10; i++ {
+		u := fmt.Sprintf("https://example.com/%d", i)
+		urls = append(urls, u)
+		// the first sources take the longest
+		delay[u] = time.Duration(10-i) * 3 * time.Millisecond
+	}
+	parser := &stubParser{delay: delay}
+
+	results, err := New(parser, stubStore{sources: sources(urls...)}, WithConcurrency(3)).Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := fmt.Sprint(resultURLs(results)), fmt.Sprint(urls); got != want {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+	for _, r := range results {
+		if r.Err != nil || r.Feed == nil || r.Duration <= 0 {
+			t.Errorf("%s: err %v, feed %v, duration %v", r.Source, r.Err, r.Feed, r.Duration)
+		}
+	}
+	if parser.peak > 3 {
+		t.Errorf("parsed %d sources at once, want at most 3", parser.peak)
+	}
+}
+
+func TestCrawlSourceTimeout(t *testing.T) {
+	parser := &stubParser{block: map[string]bool{"https://example.com/slow": true}}
+	store := stubStore{sources: sources("https://example.com/a", "https://example.com/slow", "https://example.com/b")}
+
+	results, err := New(parser, store, WithSourceTimeout(20*time.Millisecond)).Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+
+	for _, r := range results {
+		slow := r.Source == "https://example.com/slow"
+		if slow != errors.Is(r.Err, context.DeadlineExceeded) {
+			t.Errorf("%s: err = %v", r.Source, r.Err)
+		}
+		if !slow && r.Feed == nil {
+			t.Errorf("%s: no feed", r.Source)
+		}
+	}
+}
+
+func TestCrawlCancelled(t *testing.T) {
+	parser := &stubParser{
+		block: map[string]bool{"https://example.com/blocked": true},
+		done:  make(chan string, 2),
+	}
+	store := stubStore{sources: sources("https://example.com/a", "https://example.com/blocked", "https://example.com/b")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// cancel once every source but the blocked one has finished
+		<-parser.done
+		<-parser.done
+		cancel()
+	}()
+
+	results, err := New(parser, store, WithConcurrency(3)).Crawl(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Crawl error = %v, want %v", err, context.Canceled)
+	}
+	if got, want := fmt.Sprint(resultURLs(results)), "[https://example.com/a https://example.com/b]"; got != want {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestCrawlWithoutParser(t *testing.T) {
+	store := stubStore{sources: []*domain.Source{{ID: uuid.NewV4(), URL: "https://example.com/sitemap.xml", Type: domain.SourceTypeSitemap}}}
+
+	results, err := New(&stubParser{}, store).Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Err == nil || results[0].Duration <= 0 {
+		t.Errorf("results = %+v, want a timed failure", results)
+	}
+}
@@ -0,0 +1,47 @@
+package crawler
+
+import (
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jonboulle/clockwork"
+)
+
+type Option func(*Crawler)
+
+// WithConcurrency functionally configures the maximum number of sources
+// fetched in parallel.
+func WithConcurrency(n int) Option {
+	return func(c *Crawler) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WithSourceTimeout functionally configures how long a single source is
+// given to be fetched and parsed.
+func WithSourceTimeout(d time.Duration) Option {
+	return func(c *Crawler) {
+		if d > 0 {
+			c.sourceTimeout = d
+		}
+	}
+}
+
+// WithParser functionally configures the parser of the sources of the given
+// type, replacing the feed parser given to New for feeds.
+func WithParser(sourceType domain.SourceType, parser Parser) Option {
+	return func(c *Crawler) {
+		if parser != nil {
+			c.parsers[sourceType] = parser
+		}
+	}
+}
+
+// WithClock functionally configures the crawler with a clock.
+func WithClock(clock clockwork.Clock) Option {
+	return func(c *Crawler) {
+		c.clock = clock
+	}
+}
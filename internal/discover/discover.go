@@ -0,0 +1,177 @@
+// Package discover finds the feeds of a website from its homepage, or any of
+// its pages, for the editors who only know the site.
+package discover
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/fetch"
+)
+
+// pageAccept is the Accept header pages are requested with, feeds being
+// welcome as the url given may already be one.
+const pageAccept = "text/html, application/xhtml+xml, application/rss+xml;q=0.9, application/atom+xml;q=0.9, application/feed+json;q=0.9, */*;q=0.5"
+
+// candidates checked per page, a page linking more feeds than this usually
+// links one per category or author
+const maxCandidates = 10
+
+// feedTypes are the types of the <link rel="alternate"> that are feeds.
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+	"application/rdf+xml":   true,
+}
+
+// commonPaths are probed on sites whose page advertises no feed.
+var commonPaths = []string{
+	"/feed",
+	"/rss",
+	"/rss.xml",
+	"/feed.xml",
+	"/atom.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+// Parser fetches and parses feeds, in practice rss.Parser.
+type Parser interface {
+	Parse(ctx context.Context, source *domain.Source) (*domain.Feed, error)
+	ParseBody(ctx context.Context, source *domain.Source, body []byte) (*domain.Feed, error)
+}
+
+type Discoverer struct {
+	client fetch.Doer
+	parser Parser
+}
+
+func New(client fetch.Doer, parser Parser) *Discoverer {
+	return &Discoverer{client: client, parser: parser}
+}
+
+// Discover returns the feeds of the website of the page: the page itself when
+// it is a feed, the feeds it advertises through <link rel="alternate">, or
+// else those found at common paths of the site. Every candidate is fetched
+// and parsed, those that fail are left out.
+func (d *Discoverer) Discover(ctx context.Context, pageURL string) ([]*domain.FeedCandidate, error) {
+	doc, err := fetch.Get(ctx, d.client, fetch.Request{URL: pageURL, Accept: pageAccept})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching page: %w", err)
+	}
+	body, err := doc.UTF8()
+	if err != nil {
+		return nil, fmt.Errorf("error reading page: %w", err)
+	}
+
+	if feed, err := d.parser.ParseBody(ctx, &domain.Source{URL: doc.URL}, body); err == nil {
+		return []*domain.FeedCandidate{candidate(doc.URL, domain.FeedOriginURL, feed, "")}, nil
+	}
+
+	page, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing page: %w", err)
+	}
+	base, err := url.Parse(doc.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing page url: %w", err)
+	}
+	lang := strings.ToLower(strings.TrimSpace(page.Find("html").AttrOr("lang", "")))
+
+	origin := domain.FeedOriginLink
+	urls := alternates(page, base)
+	if len(urls) == 0 {
+		origin = domain.FeedOriginProbe
+		for _, p := range commonPaths {
+			urls = append(urls, (&url.URL{Scheme: base.Scheme, Host: base.Host, Path: p}).String())
+		}
+	}
+	if len(urls) > maxCandidates {
+		urls = urls[:maxCandidates]
+	}
+
+	var candidates []*domain.FeedCandidate
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		feed, err := d.parser.Parse(ctx, &domain.Source{URL: u})
+		if err != nil {
+			continue
+		}
+		// probed paths often redirect to the same feed
+		if feed.MovedTo != "" {
+			u = feed.MovedTo
+		}
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		candidates = append(candidates, candidate(u, origin, feed, lang))
+	}
+	return candidates, nil
+}
+
+// alternates returns the feeds the page links to through
+// <link rel="alternate">, in the order they appear.
+func alternates(page *goquery.Document, base *url.URL) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	page.Find("link[rel][href]").Each(func(_ int, link *goquery.Selection) {
+		if !hasToken(link.AttrOr("rel", ""), "alternate") {
+			return
+		}
+		typ, _, err := mime.ParseMediaType(link.AttrOr("type", ""))
+		if err != nil || !feedTypes[strings.ToLower(typ)] {
+			return
+		}
+
+		u, err := url.Parse(strings.TrimSpace(link.AttrOr("href", "")))
+		if err != nil {
+			return
+		}
+		u = base.ResolveReference(u)
+		if (u.Scheme != "http" && u.Scheme != "https") || seen[u.String()] {
+			return
+		}
+		seen[u.String()] = true
+		urls = append(urls, u.String())
+	})
+	return urls
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidate describes the parsed feed, falling back to the language of the
+// page when the feed declares none.
+func candidate(u string, origin domain.FeedOrigin, feed *domain.Feed, lang string) *domain.FeedCandidate {
+	c := &domain.FeedCandidate{
+		URL:       u,
+		Title:     strings.Join(strings.Fields(feed.Title), " "),
+		ItemCount: len(feed.Articles),
+		Language:  feed.Language,
+		Origin:    origin,
+	}
+	if c.Language == "" {
+		c.Language = lang
+	}
+	return c
+}
@@ -0,0 +1,199 @@
+// Package diskcache keeps blobs in files of a local directory, evicting the
+// least recently used ones once their total size goes over a cap.
+package diskcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tempPrefix marks the files being written, which are not entries yet.
+const tempPrefix = ".tmp-"
+
+type entry struct {
+	name string
+	size int64
+}
+
+// Cache is safe for concurrent use, files being read and written outside its
+// lock. Recency is kept in the modification time of the files so that it
+// survives restarts.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	size    int64
+}
+
+// New opens the cache in dir, creating the directory if needed and picking
+// up the entries left in it, trimmed down to maxBytes.
+func New(dir string, maxBytes int64) (*Cache, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("cache size must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache directory: %w", err)
+	}
+
+	type existing struct {
+		entry
+		modTime time.Time
+	}
+	var found []existing
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(f.Name(), tempPrefix) {
+			// left over by a write that never finished
+			_ = os.Remove(filepath.Join(dir, f.Name()))
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		found = append(found, existing{entry{f.Name(), info.Size()}, info.ModTime()})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].modTime.Before(found[j].modTime) })
+
+	c := &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	for _, f := range found {
+		c.entries[f.name] = c.lru.PushFront(&entry{f.name, f.size})
+		c.size += f.size
+	}
+	c.removeFiles(c.evict())
+
+	return c, nil
+}
+
+// Get returns the blob stored under key, if any.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	name := fileName(key)
+
+	c.mu.Lock()
+	el, ok := c.entries[name]
+	if ok {
+		c.lru.MoveToFront(el)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := filepath.Join(c.dir, name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// removed from under the cache, or evicted meanwhile
+		c.mu.Lock()
+		if c.entries[name] == el {
+			c.remove(el)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return data, true
+}
+
+// Put stores the blob under key, evicting the least recently used entries
+// to make room for it. Blobs larger than the whole cache are not stored.
+func (c *Cache) Put(key string, data []byte) error {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return nil
+	}
+	name := fileName(key)
+
+	tmp, err := os.CreateTemp(c.dir, tempPrefix)
+	if err != nil {
+		return fmt.Errorf("error creating cache file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error storing cache file: %w", err)
+	}
+
+	c.mu.Lock()
+	if el, ok := c.entries[name]; ok {
+		e := el.Value.(*entry)
+		c.size += size - e.size
+		e.size = size
+		c.lru.MoveToFront(el)
+	} else {
+		c.entries[name] = c.lru.PushFront(&entry{name, size})
+		c.size += size
+	}
+	evicted := c.evict()
+	c.mu.Unlock()
+
+	c.removeFiles(evicted)
+	return nil
+}
+
+// evict drops the least recently used entries until the cache fits its cap,
+// returning their names for their files to be removed outside the lock.
+func (c *Cache) evict() []string {
+	var evicted []string
+	for c.size > c.maxBytes {
+		el := c.lru.Back()
+		if el == nil {
+			break
+		}
+		evicted = append(evicted, el.Value.(*entry).name)
+		c.remove(el)
+	}
+	return evicted
+}
+
+func (c *Cache) removeFiles(names []string) {
+	for _, name := range names {
+		_ = os.Remove(filepath.Join(c.dir, name))
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := el.Value.(*entry)
+	c.lru.Remove(el)
+	delete(c.entries, e.name)
+	c.size -= e.size
+}
+
+// fileName turns any key into a name safe to use in the directory.
+func fileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,155 @@
+package diskcache
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func blob(size int, b byte) []byte {
+	return bytes.Repeat([]byte{b}, size)
+}
+
+func mustPut(t *testing.T, c *Cache, key string, data []byte) {
+	t.Helper()
+	if err := c.Put(key, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func files(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestEviction(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustPut(t, c, "a", blob(10, 'a'))
+	mustPut(t, c, "b", blob(10, 'b'))
+	mustPut(t, c, "c", blob(10, 'c'))
+	// a is the most recently used now, b the least
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a missing at the cap")
+	}
+
+	mustPut(t, c, "d", blob(10, 'd'))
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("Get(%q) found %v, want %v", key, ok, want)
+		}
+	}
+	if n := files(t, dir); n != 3 {
+		t.Errorf("%d files left, want 3", n)
+	}
+
+	// replacing an entry accounts for its new size
+	mustPut(t, c, "a", blob(20, 'A'))
+	if data, ok := c.Get("a"); !ok || !bytes.Equal(data, blob(20, 'A')) {
+		t.Errorf("Get(a) = %q, %v", data, ok)
+	}
+	if c.size > c.maxBytes {
+		t.Errorf("size %d over the cap %d", c.size, c.maxBytes)
+	}
+
+	// larger than the whole cache
+	mustPut(t, c, "huge", blob(31, 'h'))
+	if _, ok := c.Get("huge"); ok {
+		t.Errorf("stored a blob larger than the cache")
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	for i, key := range []string{"oldest", "older", "newest"} {
+		mustPut(t, c, key, blob(10, byte('a'+i)))
+		at := old.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(filepath.Join(dir, fileName(key)), at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, tempPrefix+"unfinished"), blob(10, 'x'), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// reopened smaller, keeping the most recently used entries
+	c, err = New(dir, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"oldest": false, "older": true, "newest": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("Get(%q) found %v, want %v", key, ok, want)
+		}
+	}
+	if data, _ := c.Get("newest"); !bytes.Equal(data, blob(10, 'c')) {
+		t.Errorf("Get(newest) = %q", data)
+	}
+	if n := files(t, dir); n != 2 {
+		t.Errorf("%d files left, want the 2 entries", n)
+	}
+}
+
+func TestConcurrentGetPut(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("key-%d", (g+i)%30)
+				want := blob(10, byte('a'+(g+i)%30))
+				if err := c.Put(key, want); err != nil {
+					t.Error(err)
+					return
+				}
+				if data, ok := c.Get(key); ok && !bytes.Equal(data, want) {
+					t.Errorf("Get(%s) = %q, want %q", key, data, want)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size > c.maxBytes {
+		t.Errorf("size %d over the cap %d", c.size, c.maxBytes)
+	}
+	if c.lru.Len() != len(c.entries) {
+		t.Errorf("%d entries in the lru, %d in the map", c.lru.Len(), len(c.entries))
+	}
+	var size int64
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		size += el.Value.(*entry).size
+	}
+	if size != c.size {
+		t.Errorf("entries add up to %d, size is %d", size, c.size)
+	}
+}
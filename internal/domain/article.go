@@ -1,18 +1,33 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"strings"
 	"time"
 
 	uuid "github.com/kevinburke/go.uuid"
+	"github.com/lib/pq"
 )
 
 var (
 	ErrArticleAlreadyExists = errors.New("article already exists")
+	ErrArticleNotFound      = errors.New("article not found")
+	ErrArticleWithoutGUID   = errors.New("article without guid")
 )
 
+// ArticleHashVersion is bumped whenever ingestion changes how the fields of
+// an article are derived from a feed item, e.g. how its text is normalized or
+// its thumbnail picked among the images of the item.
+// A stored article hashed under an older version is then re-baselined on its
+// next crawl instead of being revised, as the publisher did not change it.
+const ArticleHashVersion = 2
+
 type Article struct {
-	ID          uuid.UUID  `db:"id" json:"id"`
+	ID uuid.UUID `db:"id" json:"id"`
+	// FeedID is the feed the article was first stored from, see Categories and
+	// Providers for every feed that carried it.
 	FeedID      uuid.UUID  `db:"feed_id" json:"-"`
 	PublishedAt time.Time  `db:"published_at" json:"published_at"`
 	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
@@ -22,12 +37,126 @@ type Article struct {
 	Description  string `db:"description" json:"description"`
 	Link         string `db:"link" json:"link"`
 	ThumbnailURL string `db:"thumbnail_url" json:"thumbnail_url"`
-	GUID         string `db:"guid" json:"-"`
+	// The size of the thumbnail, 0 when not known.
+	ThumbnailWidth  int    `db:"thumbnail_width" json:"thumbnail_width"`
+	ThumbnailHeight int    `db:"thumbnail_height" json:"thumbnail_height"`
+	GUID            string `db:"guid" json:"-"`
+	// CanonicalURL is the normalized link of the article, which deduplicates
+	// the same story carried under different GUIDs.
+	CanonicalURL string `db:"canonical_url" json:"canonical_url"`
+
+	// The categories and providers of every feed that carried the article.
+	Categories pq.StringArray `db:"categories" json:"categories"`
+	Providers  pq.StringArray `db:"providers" json:"providers"`
+
+	Authors pq.StringArray `db:"authors" json:"authors"`
+	Tags    pq.StringArray `db:"tags" json:"tags"`
+	// Content is the full HTML body of the item, e.g. content:encoded.
+	Content string `db:"content" json:"content"`
+	// ItemUpdatedAt is when the publisher last updated the item.
+	ItemUpdatedAt *time.Time `db:"item_updated_at" json:"item_updated_at"`
+	// ContentHash fingerprints the editable fields to detect when the publisher changes them.
+	ContentHash string `db:"content_hash" json:"-"`
+	// HashVersion is the ArticleHashVersion the ContentHash was computed under.
+	HashVersion int `db:"hash_version" json:"-"`
+
+	// StoryID is the story the article was grouped into with its near-duplicates, if any.
+	StoryID *uuid.UUID `db:"story_id" json:"story_id"`
+	// Shingles fingerprint the title and description to find near-duplicates.
+	Shingles pq.Int64Array `db:"shingles" json:"-"`
+
+	// Images are the candidates the thumbnail was picked from.
+	Images []*ArticleImage `db:"-" json:"images"`
+	// Extracted is the content extracted from the page of a new article when
+	// it was read for its canonical URL or image, stored along with it.
+	Extracted *ArticleContent `db:"-" json:"-"`
 }
 
+// Hash fingerprints the fields of the article a publisher may edit.
+func (a *Article) Hash() string {
+	h := sha256.New()
+	for _, field := range a.revisedFields() {
+		h.Write([]byte(field.value))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SetThumbnail makes the image the thumbnail of the article, or clears the
+// thumbnail when nil.
+func (a *Article) SetThumbnail(img *ArticleImage) {
+	if img == nil {
+		a.ThumbnailURL, a.ThumbnailWidth, a.ThumbnailHeight = "", 0, 0
+		return
+	}
+	a.ThumbnailURL, a.ThumbnailWidth, a.ThumbnailHeight = img.URL, img.Width, img.Height
+}
+
+// ChangedFields lists the names of the editable fields that differ between the two versions.
+func (a *Article) ChangedFields(other *Article) []string {
+	theirs := other.revisedFields()
+
+	var changed []string
+	for i, field := range a.revisedFields() {
+		if field.value != theirs[i].value {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed
+}
+
+type articleField struct {
+	name  string
+	value string
+}
+
+func (a *Article) revisedFields() []articleField {
+	return []articleField{
+		{"title", a.Title},
+		{"description", a.Description},
+		{"link", a.Link},
+		{"thumbnail_url", a.ThumbnailURL},
+		{"authors", strings.Join(a.Authors, "\x00")},
+		{"tags", strings.Join(a.Tags, "\x00")},
+		{"content", a.Content},
+	}
+}
+
+// ArticleRevision is a previous version of an article, kept when the publisher changed it.
+type ArticleRevision struct {
+	ID            uuid.UUID      `db:"id" json:"id"`
+	ArticleID     uuid.UUID      `db:"article_id" json:"article_id"`
+	Title         string         `db:"title" json:"title"`
+	Description   string         `db:"description" json:"description"`
+	Link          string         `db:"link" json:"link"`
+	ThumbnailURL  string         `db:"thumbnail_url" json:"thumbnail_url"`
+	Authors       pq.StringArray `db:"authors" json:"authors"`
+	Tags          pq.StringArray `db:"tags" json:"tags"`
+	Content       string         `db:"content" json:"content"`
+	ItemUpdatedAt *time.Time     `db:"item_updated_at" json:"item_updated_at"`
+	// ChangedFields are the fields the next version changed.
+	ChangedFields pq.StringArray `db:"changed_fields" json:"changed_fields"`
+	// RevisedAt is when this version was replaced.
+	RevisedAt time.Time `db:"revised_at" json:"revised_at"`
+}
+
+// ArticleWriteStatus describes what storing an article did to the persisted row.
+type ArticleWriteStatus string
+
+const (
+	ArticleWriteCreated ArticleWriteStatus = "created"
+	ArticleWriteUpdated ArticleWriteStatus = "updated"
+	ArticleWriteSkipped ArticleWriteStatus = "skipped"
+)
+
 type SelectArticleFilters struct {
 	Limit      *uint64
 	Offset     *uint64
 	Categories []Category
 	Providers  []Provider
+	Authors    []string
+	Tags       []string
+	StoryIDs   []uuid.UUID
+	// CollapseStories lists a single article, the most recent, per story.
+	CollapseStories bool
 }
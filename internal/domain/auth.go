@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// AuthType is how the credentials of a source are presented.
+type AuthType string
+
+const (
+	// AuthTypeBasic is HTTP Basic authentication with Username and Secret.
+	AuthTypeBasic AuthType = "basic"
+	// AuthTypeBearer sends Secret as a bearer token.
+	AuthTypeBearer AuthType = "bearer"
+	// AuthTypeHeader sends Secret in the header Name.
+	AuthTypeHeader AuthType = "header"
+	// AuthTypeQuery adds Secret to the url as the query parameter Name.
+	AuthTypeQuery AuthType = "query"
+)
+
+// Redacted replaces secrets in what is logged or stored in the clear.
+const Redacted = "REDACTED"
+
+// SourceAuth are the credentials a private source is fetched with. They are
+// stored encrypted, and Secret is left out of their JSON so it never makes it
+// back out of the API.
+type SourceAuth struct {
+	Type     AuthType `json:"type"`
+	Username string   `json:"username,omitempty"`
+	// Name is the header or query parameter the secret is sent as.
+	Name string `json:"name,omitempty"`
+	// Secret is the password, token, or value of the header or query parameter.
+	Secret string `json:"secret,omitempty"`
+}
+
+// MarshalJSON writes the credentials without their secret.
+func (a SourceAuth) MarshalJSON() ([]byte, error) {
+	type redacted SourceAuth
+	r := redacted(a)
+	r.Secret = ""
+	return json.Marshal(r)
+}
+
+// Validate checks that the credentials have what their type needs.
+func (a *SourceAuth) Validate() error {
+	switch a.Type {
+	case AuthTypeBasic:
+		if a.Username == "" {
+			return errors.New("basic credentials need a username")
+		}
+		// a password may be empty
+		return nil
+	case AuthTypeBearer:
+	case AuthTypeHeader:
+		if a.Name == "" || strings.ContainsAny(a.Name, " \t\r\n:") {
+			return fmt.Errorf("invalid header name %q", a.Name)
+		}
+		switch strings.ToLower(a.Name) {
+		case "host", "content-length", "transfer-encoding", "connection", "accept-encoding":
+			return fmt.Errorf("header %s cannot carry credentials", a.Name)
+		}
+	case AuthTypeQuery:
+		if a.Name == "" {
+			return errors.New("query credentials need a parameter name")
+		}
+	default:
+		return fmt.Errorf("unknown credentials type %q", a.Type)
+	}
+	if a.Secret == "" {
+		return fmt.Errorf("%s credentials need a secret", a.Type)
+	}
+	return nil
+}
+
+// Redact replaces the secret, as is or url encoded, wherever it appears in s.
+func (a *SourceAuth) Redact(s string) string {
+	if a == nil || a.Secret == "" {
+		return s
+	}
+	s = strings.ReplaceAll(s, a.Secret, Redacted)
+	if escaped := url.QueryEscape(a.Secret); escaped != a.Secret {
+		s = strings.ReplaceAll(s, escaped, Redacted)
+	}
+	return s
+}
+
+// RedactError returns the error with the secret redacted from its message,
+// still wrapping it for errors.Is and errors.As.
+func (a *SourceAuth) RedactError(err error) error {
+	if err == nil || a == nil || a.Secret == "" {
+		return err
+	}
+	msg := a.Redact(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+var (
+	ErrArticleContentNotFound = errors.New("article content not found")
+)
+
+type ExtractionStatus string
+
+const (
+	ExtractionStatusPending   ExtractionStatus = "pending"
+	ExtractionStatusSucceeded ExtractionStatus = "succeeded"
+	ExtractionStatusFailed    ExtractionStatus = "failed"
+)
+
+// ArticleContent is the full text of an article extracted from its page.
+type ArticleContent struct {
+	ArticleID   uuid.UUID        `db:"article_id" json:"article_id"`
+	Status      ExtractionStatus `db:"status" json:"status"`
+	HTML        string           `db:"html" json:"html"`
+	Text        string           `db:"text" json:"text"`
+	WordCount   int              `db:"word_count" json:"word_count"`
+	Error       string           `db:"error" json:"error,omitempty"`
+	Attempts    int              `db:"attempts" json:"attempts"`
+	ExtractedAt *time.Time       `db:"extracted_at" json:"extracted_at"`
+	CreatedAt   time.Time        `db:"created_at" json:"created_at"`
+	UpdatedAt   *time.Time       `db:"updated_at" json:"updated_at"`
+
+	// Link is the page the content is extracted from.
+	Link string `db:"link" json:"-"`
+}
+
+type SelectArticleContentFilters struct {
+	Statuses []ExtractionStatus
+	// MaxAttempts only selects content that has been attempted fewer times.
+	MaxAttempts *int
+	Limit       *uint64
+}
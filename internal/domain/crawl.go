@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	uuid "github.com/kevinburke/go.uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCrawlRunNotFound = errors.New("crawl run not found")
+)
+
+// CrawlResult is the outcome of fetching and parsing a single source.
+// NotModified is set when the source reported no changes since the last
+// fetch, in which case Feed is nil.
+type CrawlResult struct {
+	SourceID    uuid.UUID
+	Source      string
+	Feed        *Feed
+	NotModified bool
+	Err         error
+	Duration    time.Duration
+	// MovedTo is the url the source permanently redirected to, if it did.
+	MovedTo string
+	// Gone reports that the source answered 410 Gone.
+	Gone bool
+}
+
+type CrawlStatus string
+
+const (
+	CrawlStatusRunning   CrawlStatus = "running"
+	CrawlStatusSucceeded CrawlStatus = "succeeded"
+	CrawlStatusUnchanged CrawlStatus = "not_modified"
+	CrawlStatusPartial   CrawlStatus = "partial"
+	CrawlStatusFailed    CrawlStatus = "failed"
+)
+
+// ItemCounts tallies what happened to the items of a feed when stored.
+type ItemCounts struct {
+	New     int `db:"items_new" json:"items_new"`
+	Updated int `db:"items_updated" json:"items_updated"`
+	Skipped int `db:"items_skipped" json:"items_skipped"`
+}
+
+func (c *ItemCounts) Add(o ItemCounts) {
+	c.New += o.New
+	c.Updated += o.Updated
+	c.Skipped += o.Skipped
+}
+
+// CrawlRun records a single crawl tick across all sources.
+type CrawlRun struct {
+	ID          uuid.UUID   `db:"id" json:"id"`
+	Status      CrawlStatus `db:"status" json:"status"`
+	SourceCount int         `db:"source_count" json:"source_count"`
+	FailedCount int         `db:"failed_count" json:"failed_count"`
+	StartedAt   time.Time   `db:"started_at" json:"started_at"`
+	FinishedAt  *time.Time  `db:"finished_at" json:"finished_at"`
+	DurationMS  *int64      `db:"duration_ms" json:"duration_ms"`
+	ItemCounts
+
+	Results []*CrawlSourceResult `db:"-" json:"results,omitempty"`
+}
+
+// CrawlSourceResult records how a single source fared within a crawl run.
+type CrawlSourceResult struct {
+	ID         uuid.UUID   `db:"id" json:"id"`
+	CrawlRunID uuid.UUID   `db:"crawl_run_id" json:"crawl_run_id"`
+	SourceID   *uuid.UUID  `db:"source_id" json:"source_id"`
+	Source     string      `db:"source" json:"source"`
+	Status     CrawlStatus `db:"status" json:"status"`
+	Error      string      `db:"error" json:"error,omitempty"`
+	DurationMS int64       `db:"duration_ms" json:"duration_ms"`
+	CreatedAt  time.Time   `db:"created_at" json:"created_at"`
+	// Warnings are about the quality of what the source gave, see Feed.Warnings.
+	Warnings pq.StringArray `db:"warnings" json:"warnings,omitempty"`
+	ItemCounts
+}
+
+type SelectCrawlRunFilters struct {
+	Statuses []CrawlStatus
+	Limit    *uint64
+	Offset   *uint64
+}
+
+type SelectCrawlSourceResultFilters struct {
+	CrawlRunIDs []uuid.UUID
+	SourceIDs   []uuid.UUID
+	Sources     []string
+	Statuses    []CrawlStatus
+	Limit       *uint64
+	Offset      *uint64
+}
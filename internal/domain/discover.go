@@ -0,0 +1,26 @@
+package domain
+
+import uuid "github.com/kevinburke/go.uuid"
+
+// FeedOrigin is how a feed was discovered from a website.
+type FeedOrigin string
+
+const (
+	// FeedOriginURL is a feed given directly rather than a website.
+	FeedOriginURL FeedOrigin = "url"
+	// FeedOriginLink is a feed the page advertises through <link rel="alternate">.
+	FeedOriginLink FeedOrigin = "link"
+	// FeedOriginProbe is a feed found at a common path of the website.
+	FeedOriginProbe FeedOrigin = "probe"
+)
+
+// FeedCandidate is a feed discovered from a website, checked to parse.
+type FeedCandidate struct {
+	URL       string     `json:"url"`
+	Title     string     `json:"title"`
+	ItemCount int        `json:"item_count"`
+	Language  string     `json:"language"`
+	Origin    FeedOrigin `json:"origin"`
+	// SourceID is the source the feed is already registered as, if it is.
+	SourceID *uuid.UUID `json:"source_id,omitempty"`
+}
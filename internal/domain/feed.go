@@ -24,38 +24,45 @@ type Feed struct {
 	Language    string   `db:"language"`
 	Provider    Provider `db:"provider"`
 
+	// HTTP cache validators used for conditional fetching.
+	ETag         string `db:"etag"`
+	LastModified string `db:"last_modified"`
+
+	// MovedTo is the url the feed permanently redirected to, if it did.
+	MovedTo string `db:"-"`
+	// Warnings are about the quality of what could be read from the source,
+	// such as the selectors of a scrape source no longer matching.
+	Warnings []string `db:"-"`
+
+	// WebSub hubs the feed advertises and the topic url it is published under.
+	Hubs    []string `db:"-"`
+	SelfURL string   `db:"-"`
+
+	// RSS scheduling hints: how long the feed may be cached for and the
+	// hours (0-23, GMT) and days it is not updated in.
+	TTL       time.Duration  `db:"-"`
+	SkipHours []int          `db:"-"`
+	SkipDays  []time.Weekday `db:"-"`
+
+	// Payload is the document the feed was parsed from, to be archived.
+	Payload *Payload `db:"-"`
+	// Files are the files of a watched directory the feed was read from,
+	// to be moved out of it once the feed is stored.
+	Files []string `db:"-"`
+
 	Articles []*Article
 }
 
 type SelectFeedFilters struct {
 	Categories []string
 	Providers  []string
+	FeedLinks  []string
 	Limit      *uint64
 	Offset     *uint64
 }
 
+// Category is the slug of a category in the taxonomy.
 type Category string
 
-const (
-	CategoryTechnology Category = "technology"
-	CategoryUK         Category = "uk"
-	CategoryUnknown    Category = "unknown"
-)
-
-var SupportedCategory = map[Category]bool{
-	CategoryTechnology: true,
-	CategoryUK:         true,
-}
-
+// Provider is the slug of a provider in the taxonomy.
 type Provider string
-
-const (
-	ProviderBBC     Provider = "bbc"
-	ProviderSky     Provider = "sky"
-	ProviderUnknown Provider = "unknown"
-)
-
-var SupportedProvider = map[Provider]bool{
-	ProviderBBC: true,
-	ProviderSky: true,
-}
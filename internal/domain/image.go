@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+var (
+	ErrImageNotFound = errors.New("image not found")
+	ErrInvalidImage  = errors.New("invalid image")
+)
+
+// ImageOrigin is where in the feed or page an image of an article was found.
+type ImageOrigin string
+
+const (
+	// ImageOriginMedia is a Media RSS media:content or media:thumbnail.
+	ImageOriginMedia ImageOrigin = "media"
+	// ImageOriginEnclosure is an image enclosure of the item.
+	ImageOriginEnclosure ImageOrigin = "enclosure"
+	// ImageOriginFeed is the image the feed format itself gives the item,
+	// e.g. itunes:image or a JSON Feed image.
+	ImageOriginFeed ImageOrigin = "feed"
+	// ImageOriginHTML is an <img> of the item's description or content.
+	ImageOriginHTML ImageOrigin = "html"
+	// ImageOriginPage is the og:image of the article's page.
+	ImageOriginPage ImageOrigin = "page"
+	// ImageOriginSitemap is an image:image of the article's sitemap entry.
+	ImageOriginSitemap ImageOrigin = "sitemap"
+)
+
+// ArticleImage is one of the candidate images of an article, which its
+// thumbnail is picked from. Width and height are 0 when not known.
+type ArticleImage struct {
+	ArticleID uuid.UUID   `db:"article_id" json:"-"`
+	URL       string      `db:"url" json:"url"`
+	Width     int         `db:"width" json:"width"`
+	Height    int         `db:"height" json:"height"`
+	Type      string      `db:"type" json:"type,omitempty"`
+	Origin    ImageOrigin `db:"origin" json:"origin"`
+}
+
+// Image is an encoded image served on behalf of a publisher.
+type Image struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+}
+
+// ImageVersion identifies an image of an article by its url. Proxied image
+// urls carry it so that a url keeps serving the same image when the thumbnail
+// of the article changes.
+func ImageVersion(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MappingRules override which fields of the items of a feed source fill the
+// fields of its articles, for publishers putting them in odd places. Fields
+// without a rule are mapped as usual.
+type MappingRules struct {
+	Title       *FieldRule `json:"title,omitempty"`
+	Description *FieldRule `json:"description,omitempty"`
+	Content     *FieldRule `json:"content,omitempty"`
+	Link        *FieldRule `json:"link,omitempty"`
+	GUID        *FieldRule `json:"guid,omitempty"`
+	PublishedAt *FieldRule `json:"published_at,omitempty"`
+	Image       *FieldRule `json:"image,omitempty"`
+	Authors     *FieldRule `json:"authors,omitempty"`
+	Tags        *FieldRule `json:"tags,omitempty"`
+}
+
+// FieldRule fills an article field from the first of the paths From that
+// has a value, such as "content" or "ext/dc/creator", the default mapping
+// being the last resort. The value is cleaned up with the replacements in
+// order, and dates are read with DateLayouts before the common layouts.
+type FieldRule struct {
+	From        []string      `json:"from"`
+	DateLayouts []string      `json:"date_layouts,omitempty"`
+	Replace     []Replacement `json:"replace,omitempty"`
+}
+
+// Replacement replaces the matches of the regular expression Pattern with
+// With, which may refer to the groups of the match as $1.
+type Replacement struct {
+	Pattern string `json:"pattern"`
+	With    string `json:"with"`
+}
+
+// Value stores the rules as JSON.
+func (r MappingRules) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// Scan reads rules stored as JSON.
+func (r *MappingRules) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	}
+	return fmt.Errorf("cannot scan %T into mapping rules", src)
+}
+
+// Empty reports whether the rules override no field.
+func (r MappingRules) Empty() bool {
+	return r == MappingRules{}
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"time"
+
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+// PayloadOrigin is how the body of an archived payload came to us.
+type PayloadOrigin string
+
+const (
+	PayloadOriginFetch PayloadOrigin = "fetch"
+	PayloadOriginPush  PayloadOrigin = "push"
+)
+
+// PayloadStorage is where the bodies of archived payloads are kept.
+type PayloadStorage string
+
+const (
+	// PayloadStorageDisk keeps bodies as files of the archive directory.
+	PayloadStorageDisk PayloadStorage = "disk"
+	// PayloadStoragePostgres keeps bodies as Postgres large objects.
+	PayloadStoragePostgres PayloadStorage = "postgres"
+)
+
+// Valid reports whether the storage is a known one.
+func (s PayloadStorage) Valid() bool {
+	switch s {
+	case PayloadStorageDisk, PayloadStoragePostgres:
+		return true
+	}
+	return false
+}
+
+// Payload is a feed document as it was fetched or pushed, archived so that
+// its articles can be derived again once the parser changes.
+type Payload struct {
+	ID       uuid.UUID     `db:"id" json:"id"`
+	SourceID uuid.UUID     `db:"source_id" json:"source_id"`
+	Origin   PayloadOrigin `db:"origin" json:"origin"`
+	// FeedLink is the link of the feed the payload was stored as.
+	FeedLink string `db:"feed_link" json:"feed_link"`
+	// URL is the one the payload was finally served from.
+	URL          string `db:"url" json:"url"`
+	ContentType  string `db:"content_type" json:"content_type"`
+	ETag         string `db:"etag" json:"etag"`
+	LastModified string `db:"last_modified" json:"last_modified"`
+	// Size is the size of the body, StoredSize its size once compressed.
+	Size       int64          `db:"size" json:"size"`
+	StoredSize int64          `db:"stored_size" json:"stored_size"`
+	Storage    PayloadStorage `db:"storage" json:"storage"`
+	// Location is the path of the body under the archive directory, or the
+	// oid of its large object.
+	Location  string    `db:"location" json:"-"`
+	FetchedAt time.Time `db:"fetched_at" json:"fetched_at"`
+
+	// Body is the document, UTF-8 encoded as it was parsed.
+	Body []byte `db:"-" json:"-"`
+}
+
+// SelectPayloadFilters select archived payloads, From inclusive and To
+// exclusive.
+type SelectPayloadFilters struct {
+	SourceIDs []uuid.UUID
+	From      *time.Time
+	To        *time.Time
+	Limit     *uint64
+	Offset    *uint64
+}
+
+// ReprocessResult reports how the replay of archived payloads went.
+type ReprocessResult struct {
+	Payloads int `json:"payloads"`
+	// Skipped are the payloads of sources that no longer exist.
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+	// Articles is the number of articles parsed, which are only stored when
+	// not a dry run.
+	Articles int `json:"articles"`
+	// Newer are the articles left as they are, as they were revised since
+	// a later payload of their source was archived.
+	Newer int `json:"newer"`
+	ItemCounts
+	Errors []string `json:"errors,omitempty"`
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// ScrapeRules tell how the items of the listing page of a scrape source are
+// extracted, with CSS selectors relative to each item. Only Item is required:
+// without a Link selector the item's own href or its first link is used, and
+// without a Title selector the text of the link.
+type ScrapeRules struct {
+	Item    string `json:"item"`
+	Title   string `json:"title,omitempty"`
+	Link    string `json:"link,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Image   string `json:"image,omitempty"`
+	Date    string `json:"date,omitempty"`
+	// DateLayout is the Go time layout the date is written in, common
+	// layouts are tried when it is empty.
+	DateLayout string `json:"date_layout,omitempty"`
+}
+
+// Value stores the rules as JSON.
+func (r ScrapeRules) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+// Scan reads rules stored as JSON.
+func (r *ScrapeRules) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	}
+	return fmt.Errorf("cannot scan %T into scrape rules", src)
+}
+
+// ScrapePreview is what scraping a page with some rules would give, without
+// anything being stored.
+type ScrapePreview struct {
+	Title    string     `json:"title"`
+	Articles []*Article `json:"articles"`
+	Warnings []string   `json:"warnings"`
+}
+
+// ScrapePreviewRequest is a page to try scrape rules on.
+type ScrapePreviewRequest struct {
+	URL         string       `json:"url"`
+	ScrapeRules *ScrapeRules `json:"scrape_rules"`
+}
@@ -0,0 +1,188 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	uuid "github.com/kevinburke/go.uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrNotModified         = errors.New("source not modified")
+	ErrSourceNotFound      = errors.New("source not found")
+	ErrSourceAlreadyExists = errors.New("source already exists")
+	ErrInvalidSource       = errors.New("invalid source")
+	// ErrSourceGone is returned for a source that answered 410 Gone.
+	ErrSourceGone = errors.New("source gone")
+)
+
+// SourceMovedError is returned for a source that permanently redirected to
+// URL and then failed with Err.
+type SourceMovedError struct {
+	URL string
+	Err error
+}
+
+func (e *SourceMovedError) Error() string {
+	return fmt.Sprintf("moved to %s: %v", e.URL, e.Err)
+}
+
+func (e *SourceMovedError) Unwrap() error {
+	return e.Err
+}
+
+// SourceType is the kind of document a source is, which decides how it is parsed.
+type SourceType string
+
+const (
+	// SourceTypeFeed is an RSS, Atom or JSON feed.
+	SourceTypeFeed SourceType = "feed"
+	// SourceTypeSitemap is a sitemap, a Google News sitemap or a sitemap index.
+	SourceTypeSitemap SourceType = "sitemap"
+	// SourceTypeScrape is a listing page scraped with the source's ScrapeRules.
+	SourceTypeScrape SourceType = "scrape"
+	// SourceTypeDirectory is a local directory, given as a file:// url, the
+	// feed files dropped into are ingested.
+	SourceTypeDirectory SourceType = "directory"
+)
+
+// Valid reports whether the type is one sources can be registered with.
+func (t SourceType) Valid() bool {
+	switch t {
+	case SourceTypeFeed, SourceTypeSitemap, SourceTypeScrape, SourceTypeDirectory:
+		return true
+	}
+	return false
+}
+
+// Source is a feed location registered to be crawled.
+type Source struct {
+	ID       uuid.UUID  `db:"id" json:"id"`
+	URL      string     `db:"url" json:"url"`
+	Type     SourceType `db:"type" json:"type"`
+	Enabled  bool       `db:"enabled" json:"enabled"`
+	Category Category   `db:"category" json:"category"`
+	Provider Provider   `db:"provider" json:"provider"`
+	// PollInterval fixes the number of seconds between two crawls of the
+	// source, zero meaning the interval adapts to how often it publishes.
+	PollInterval  int        `db:"poll_interval" json:"poll_interval"`
+	Notes         string     `db:"notes" json:"notes"`
+	LastCrawledAt *time.Time `db:"last_crawled_at" json:"last_crawled_at"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     *time.Time `db:"updated_at" json:"updated_at"`
+
+	// NextPollAt is when the source is next due, nil meaning right away.
+	NextPollAt *time.Time `db:"next_poll_at" json:"next_poll_at"`
+	// ScheduledInterval is the interval in seconds the source was last scheduled at.
+	ScheduledInterval int `db:"scheduled_interval" json:"scheduled_interval"`
+	// The scheduling hints last read from the feed, the TTL in seconds.
+	TTL       int            `db:"ttl" json:"ttl"`
+	SkipHours pq.Int64Array  `db:"skip_hours" json:"skip_hours"`
+	SkipDays  pq.StringArray `db:"skip_days" json:"skip_days"`
+
+	// ScrapeRules are how the items of a scrape source are extracted, nil
+	// for other types.
+	ScrapeRules *ScrapeRules `db:"scrape_rules" json:"scrape_rules,omitempty"`
+	// MappingRules override how the items of a feed or directory source are
+	// mapped into articles, nil for the default mapping.
+	MappingRules *MappingRules `db:"mapping_rules" json:"mapping_rules,omitempty"`
+	// Auth are the credentials of a private source, nil for public ones.
+	// They are kept encrypted in SealedAuth.
+	Auth       *SourceAuth `db:"-" json:"auth,omitempty"`
+	SealedAuth []byte      `db:"auth" json:"-"`
+	// AuthErr is why the stored credentials could not be decrypted, in
+	// which case Auth is nil and the source cannot be crawled.
+	AuthErr error `db:"-" json:"-"`
+
+	// DeadAt is when the source answered 410 Gone and was disabled, nil
+	// while it is alive.
+	DeadAt *time.Time `db:"dead_at" json:"dead_at"`
+
+	// HTTP cache validators returned the last time the source was fetched.
+	ETag         string `db:"-" json:"-"`
+	LastModified string `db:"-" json:"-"`
+}
+
+// SourceUpdate holds the fields of a source to be changed, nil fields are left as they are.
+type SourceUpdate struct {
+	URL         *string      `json:"url"`
+	Type        *SourceType  `json:"type"`
+	ScrapeRules *ScrapeRules `json:"scrape_rules"`
+	// MappingRules replace the mapping rules of the source, empty rules
+	// removing them.
+	MappingRules *MappingRules `json:"mapping_rules"`
+	Enabled      *bool         `json:"enabled"`
+	Category     *Category     `json:"category"`
+	Provider     *Provider     `json:"provider"`
+	PollInterval *int          `json:"poll_interval"`
+	Notes        *string       `json:"notes"`
+	// Auth replaces the credentials of the source, credentials without a
+	// type removing them.
+	Auth *SourceAuth `json:"auth"`
+}
+
+type SelectSourceFilters struct {
+	IDs     []uuid.UUID
+	URLs    []string
+	Enabled *bool
+	// DueAt only selects sources whose next poll is due by the given time.
+	DueAt  *time.Time
+	Limit  *uint64
+	Offset *uint64
+}
+
+// SourceSchedule records a crawl of a source and when it is next due.
+type SourceSchedule struct {
+	CrawledAt  time.Time
+	NextPollAt time.Time
+	// Interval is in seconds.
+	Interval int
+	// Hints are only replaced when the feed was fetched, nil keeps the previous ones.
+	Hints *SourceHints
+}
+
+// SourceHints are the scheduling hints a feed gives.
+type SourceHints struct {
+	// TTL is in seconds.
+	TTL       int
+	SkipHours []int64
+	SkipDays  []string
+}
+
+// SourceEventKind is what happened to a source on its own.
+type SourceEventKind string
+
+const (
+	// SourceEventMoved is a source that permanently redirected and had its url updated.
+	SourceEventMoved SourceEventKind = "moved"
+	// SourceEventGone is a source that answered 410 Gone and was disabled.
+	SourceEventGone SourceEventKind = "gone"
+)
+
+// SourceEvent records a change made to a source because of how it answered.
+type SourceEvent struct {
+	ID        uuid.UUID       `db:"id" json:"id"`
+	SourceID  uuid.UUID       `db:"source_id" json:"source_id"`
+	Kind      SourceEventKind `db:"kind" json:"kind"`
+	OldURL    string          `db:"old_url" json:"old_url"`
+	NewURL    string          `db:"new_url" json:"new_url,omitempty"`
+	Detail    string          `db:"detail" json:"detail,omitempty"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// SourceImportResult reports what importing a list of sources did.
+type SourceImportResult struct {
+	Created  int                   `json:"created"`
+	Existing int                   `json:"existing"`
+	Failed   []*SourceImportFailed `json:"failed"`
+	// UnmappedCategories are the categories of the import that did not match
+	// any category of the taxonomy, the sources filed under them are left unassigned.
+	UnmappedCategories []string `json:"unmapped_categories"`
+}
+
+type SourceImportFailed struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
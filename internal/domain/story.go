@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+
+	uuid "github.com/kevinburke/go.uuid"
+	"github.com/lib/pq"
+)
+
+// Story groups the near-duplicate articles different feeds ran about the
+// same event, e.g. BBC and Sky covering it under headlines of their own.
+type Story struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	// Title is the headline of the first article of the story.
+	Title string `db:"title" json:"title"`
+	// LastArticleAt is when the most recent article of the story was published.
+	LastArticleAt time.Time `db:"last_article_at" json:"last_article_at"`
+
+	Articles []*Article `db:"-" json:"articles"`
+}
+
+type SelectStoryFilters struct {
+	Limit  *uint64
+	Offset *uint64
+}
+
+type SelectStoryCandidateFilters struct {
+	PublishedFrom time.Time
+	PublishedTo   time.Time
+	// Shingles only selects articles sharing at least one of them.
+	Shingles pq.Int64Array
+	// ExcludeFeedID leaves out the articles first stored from the feed.
+	ExcludeFeedID *uuid.UUID
+	Limit         *uint64
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTaxonomy = errors.New("invalid taxonomy")
+
+	slugPattern    = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	nonSlugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// CategoryInfo is a category of the taxonomy that sources are assigned to.
+type CategoryInfo struct {
+	Slug        Category   `db:"slug" json:"slug"`
+	DisplayName string     `db:"display_name" json:"display_name"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ProviderInfo is a news outlet of the taxonomy that sources are assigned to.
+type ProviderInfo struct {
+	Slug        Provider   `db:"slug" json:"slug"`
+	DisplayName string     `db:"display_name" json:"display_name"`
+	HomepageURL string     `db:"homepage_url" json:"homepage_url"`
+	LogoURL     string     `db:"logo_url" json:"logo_url"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   *time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// ValidSlug reports whether s is a lowercase, hyphen separated taxonomy slug.
+func ValidSlug(s string) bool {
+	return slugPattern.MatchString(s)
+}
+
+// Slugify turns a display name such as "Science & Environment" into a slug
+// such as "science-environment".
+func Slugify(s string) string {
+	return strings.Trim(nonSlugPattern.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("websub subscription not found")
+	ErrTopicMismatch        = errors.New("websub topic mismatch")
+	ErrInvalidSignature     = errors.New("invalid websub signature")
+)
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionStatusPending      SubscriptionStatus = "pending"
+	SubscriptionStatusActive       SubscriptionStatus = "active"
+	SubscriptionStatusDenied       SubscriptionStatus = "denied"
+	SubscriptionStatusUnsubscribed SubscriptionStatus = "unsubscribed"
+	SubscriptionStatusFailed       SubscriptionStatus = "failed"
+)
+
+// WebSubSubscription is a subscription to a hub pushing the updates of a source's feed.
+type WebSubSubscription struct {
+	ID             uuid.UUID          `db:"id" json:"id"`
+	SourceID       uuid.UUID          `db:"source_id" json:"source_id"`
+	Hub            string             `db:"hub" json:"hub"`
+	Topic          string             `db:"topic" json:"topic"`
+	Secret         string             `db:"secret" json:"-"`
+	Status         SubscriptionStatus `db:"status" json:"status"`
+	LeaseSeconds   int                `db:"lease_seconds" json:"lease_seconds"`
+	LeaseExpiresAt *time.Time         `db:"lease_expires_at" json:"lease_expires_at"`
+	LastError      string             `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt      time.Time          `db:"created_at" json:"created_at"`
+	UpdatedAt      *time.Time         `db:"updated_at" json:"updated_at"`
+
+	// PreviousSecret is the secret a renewal replaced, which the hub may
+	// still sign pushes with until it verifies the renewal.
+	PreviousSecret string `db:"previous_secret" json:"-"`
+	// Attempts counts the subscription requests that failed in a row, the
+	// next one being sent at NextAttemptAt, or never once nil.
+	Attempts      int        `db:"attempts" json:"attempts"`
+	NextAttemptAt *time.Time `db:"next_attempt_at" json:"next_attempt_at"`
+}
+
+type SelectWebSubSubscriptionFilters struct {
+	SourceIDs []uuid.UUID
+	Statuses  []SubscriptionStatus
+	// ExpiringBefore only selects subscriptions whose lease runs out before the given time.
+	ExpiringBefore *time.Time
+}
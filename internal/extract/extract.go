@@ -0,0 +1,352 @@
+// Package extract pulls the main content out of article pages with a
+// readability style scoring of the page's paragraphs.
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/sanitize"
+)
+
+const (
+	maxPageSize = 5 << 20
+
+	// paragraphs shorter than this are too short to be scored
+	minParagraphLength = 25
+)
+
+var (
+	ErrNoContent = errors.New("no content found")
+
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text`)
+	negativeNames = regexp.MustCompile(`(?i)ad-|banner|comment|contact|footer|header|menu|meta|nav|outbrain|promo|related|share|sidebar|social|sponsor|subscribe|tags|taboola|widget`)
+
+	whitespace = regexp.MustCompile(`[ \t\r\f\v]+`)
+	blankLines = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// junkSelector matches page furniture removed before scoring.
+const junkSelector = "script, style, noscript, iframe, form, nav, header, footer, aside, button, svg"
+
+// Result is the main content of a page.
+type Result struct {
+	HTML      string
+	Text      string
+	WordCount int
+}
+
+// HTTPClient fetches the pages, in practice the shared fetch.Client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Extractor struct {
+	client HTTPClient
+}
+
+func New(client HTTPClient) *Extractor {
+	return &Extractor{client: client}
+}
+
+// Extract fetches the page and returns its main content as sanitized HTML
+// and plain text.
+func (e *Extractor) Extract(ctx context.Context, pageURL string) (*Result, error) {
+	doc, base, err := e.fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	return FromDocument(doc, base)
+}
+
+// Page is what is read from an article's page in a single fetch.
+type Page struct {
+	// Canonical is the URL the page declares canonical through
+	// rel="canonical" or og:url, or the URL it was finally served from after
+	// redirects when it declares none.
+	Canonical string
+	// Image is the image the page is shared with, its og:image or
+	// twitter:image, nil when it declares none.
+	Image *domain.ArticleImage
+	// Content is the main content of the page, nil when ContentErr tells why
+	// it could not be extracted.
+	Content    *Result
+	ContentErr error
+}
+
+// ReadPage fetches the page once and reads its canonical URL, the image it
+// is shared with and its main content.
+func (e *Extractor) ReadPage(ctx context.Context, pageURL string) (*Page, error) {
+	doc, base, err := e.fetch(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		Canonical: canonicalURL(doc, base),
+		Image:     sharedImage(doc, base),
+	}
+	// last, as extracting strips the page of its furniture
+	page.Content, page.ContentErr = FromDocument(doc, base)
+	return page, nil
+}
+
+func canonicalURL(doc *goquery.Document, base *url.URL) string {
+	for _, sel := range []struct{ selector, attr string }{
+		{`link[rel~="canonical"]`, "href"},
+		{`meta[property="og:url"]`, "content"},
+	} {
+		href, ok := doc.Find(sel.selector).First().Attr(sel.attr)
+		if !ok || strings.TrimSpace(href) == "" {
+			continue
+		}
+		u, err := base.Parse(strings.TrimSpace(href))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		return u.String()
+	}
+	return base.String()
+}
+
+func sharedImage(doc *goquery.Document, base *url.URL) *domain.ArticleImage {
+	for _, name := range []string{"og:image:secure_url", "og:image:url", "og:image", "twitter:image"} {
+		src := strings.TrimSpace(metaContent(doc, name))
+		if src == "" {
+			continue
+		}
+		u, err := base.Parse(src)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+
+		img := &domain.ArticleImage{
+			URL:    u.String(),
+			Type:   metaContent(doc, "og:image:type"),
+			Origin: domain.ImageOriginPage,
+		}
+		img.Width, _ = strconv.Atoi(metaContent(doc, "og:image:width"))
+		img.Height, _ = strconv.Atoi(metaContent(doc, "og:image:height"))
+		return img
+	}
+	return nil
+}
+
+// metaContent returns the content of the first <meta> with the property or
+// name, Open Graph using the former and Twitter cards the latter.
+func metaContent(doc *goquery.Document, name string) string {
+	sel := doc.Find(fmt.Sprintf(`meta[property=%q], meta[name=%q]`, name, name)).First()
+	return strings.TrimSpace(sel.AttrOr("content", ""))
+}
+
+// fetch gets and parses the page, returning it along with the URL it was
+// served from, which relative links resolve against.
+func (e *Extractor) fetch(ctx context.Context, pageURL string) (*goquery.Document, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("error fetching page: %s", resp.Status)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing page: %w", err)
+	}
+	return doc, resp.Request.URL, nil
+}
+
+// FromDocument extracts the main content of an already parsed page.
+func FromDocument(doc *goquery.Document, base *url.URL) (*Result, error) {
+	doc.Find(junkSelector).Remove()
+
+	top := topCandidate(doc)
+	if top == nil {
+		return nil, ErrNoContent
+	}
+
+	var b strings.Builder
+	for _, s := range contentNodes(top) {
+		h, err := goquery.OuterHtml(s)
+		if err != nil {
+			return nil, fmt.Errorf("error rendering content: %w", err)
+		}
+		b.WriteString(h)
+	}
+
+	clean := sanitize.HTML(b.String(), base)
+	text := plainText(clean)
+	if text == "" {
+		return nil, ErrNoContent
+	}
+
+	return &Result{
+		HTML:      clean,
+		Text:      text,
+		WordCount: len(strings.Fields(text)),
+	}, nil
+}
+
+// topCandidate scores the parents and grandparents of every paragraph by the
+// amount of text they hold and returns the highest scoring one.
+func topCandidate(doc *goquery.Document) *goquery.Selection {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = nameWeight(n)
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	doc.Find("p, pre, td").Each(func(_ int, p *goquery.Selection) {
+		text := strings.TrimSpace(p.Text())
+		if len(text) < minParagraphLength {
+			return
+		}
+
+		// one point per paragraph, one per comma and one per 100 characters up to three
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text)/100), 3)
+
+		parent := p.Nodes[0].Parent
+		addScore(parent, score)
+		if parent != nil {
+			addScore(parent.Parent, score/2)
+		}
+	})
+
+	var (
+		best      *html.Node
+		bestScore float64
+	)
+	for _, n := range order {
+		// content swamped by links is navigation rather than prose
+		score := scores[n] * (1 - linkDensity(goquery.NewDocumentFromNode(n).Selection))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	return goquery.NewDocumentFromNode(best).Selection
+}
+
+// contentNodes returns the candidate along with the siblings that look like
+// part of the same article, such as paragraphs split across containers.
+func contentNodes(top *goquery.Selection) []*goquery.Selection {
+	nodes := []*goquery.Selection{top}
+	if top.Nodes[0].Parent == nil {
+		return nodes
+	}
+
+	nodes = nodes[:0]
+	topClass, _ := top.Attr("class")
+	goquery.NewDocumentFromNode(top.Nodes[0].Parent).Children().Each(func(_ int, s *goquery.Selection) {
+		if s.Nodes[0] == top.Nodes[0] {
+			nodes = append(nodes, s)
+			return
+		}
+
+		if class, _ := s.Attr("class"); topClass != "" && class == topClass {
+			nodes = append(nodes, s)
+			return
+		}
+
+		if goquery.NodeName(s) == "p" {
+			text := strings.TrimSpace(s.Text())
+			if len(text) > 80 && linkDensity(s) < 0.25 {
+				nodes = append(nodes, s)
+			}
+		}
+	})
+	return nodes
+}
+
+// nameWeight favours elements whose class or id suggest content and
+// penalises those suggesting page furniture.
+func nameWeight(n *html.Node) float64 {
+	var weight float64
+	for _, a := range n.Attr {
+		if a.Key != "class" && a.Key != "id" {
+			continue
+		}
+		if negativeNames.MatchString(a.Val) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(a.Val) {
+			weight += 25
+		}
+	}
+
+	switch n.Data {
+	case "article", "main":
+		weight += 10
+	case "div":
+		weight += 5
+	case "ul", "ol", "dl", "form", "li":
+		weight -= 3
+	}
+	return weight
+}
+
+// linkDensity is the share of the selection's text that sits within links.
+func linkDensity(s *goquery.Selection) float64 {
+	length := len(s.Text())
+	if length == 0 {
+		return 0
+	}
+
+	var linkLength int
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += len(a.Text())
+	})
+	return float64(linkLength) / float64(length)
+}
+
+// plainText renders sanitized HTML as text, one block element per paragraph.
+func plainText(fragment string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return ""
+	}
+
+	doc.Find("br").ReplaceWithHtml("\n")
+	doc.Find("p, h1, h2, h3, h4, h5, h6, li, blockquote, pre, figcaption, tr").Each(func(_ int, s *goquery.Selection) {
+		s.AppendHtml("\n\n")
+	})
+
+	text := whitespace.ReplaceAllString(doc.Text(), " ")
+	lines := strings.Split(text, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,45 @@
+package fetch
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// applyAuth presents the credentials of a private source on the request.
+// Go drops the Authorization header of redirects to other hosts, the custom
+// header is dropped by Client.checkRedirect.
+func applyAuth(req *http.Request, auth *domain.SourceAuth) {
+	switch auth.Type {
+	case domain.AuthTypeBasic:
+		req.SetBasicAuth(auth.Username, auth.Secret)
+	case domain.AuthTypeBearer:
+		req.Header.Set("Authorization", "Bearer "+auth.Secret)
+	case domain.AuthTypeHeader:
+		req.Header.Set(auth.Name, auth.Secret)
+	case domain.AuthTypeQuery:
+		q := req.URL.Query()
+		q.Set(auth.Name, auth.Secret)
+		req.URL.RawQuery = q.Encode()
+	}
+}
+
+// stripAuth removes the query parameter credentials were sent as from a url
+// the document was served from, so that it can be stored.
+func stripAuth(rawURL string, auth *domain.SourceAuth) string {
+	if auth == nil || auth.Type != domain.AuthTypeQuery || rawURL == "" {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return auth.Redact(rawURL)
+	}
+	q := u.Query()
+	if _, ok := q[auth.Name]; !ok {
+		return rawURL
+	}
+	q.Del(auth.Name)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,296 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// DefaultMaxDocumentSize is the largest document, once decompressed, read
+// unless told otherwise.
+const DefaultMaxDocumentSize = 10 << 20
+
+var (
+	ErrNotModified = errors.New("not modified")
+	ErrGone        = errors.New("gone")
+	ErrTooLarge    = errors.New("document too large")
+)
+
+// StatusError is returned for responses that are neither successful nor
+// one of the statuses reported through the errors above.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %s", e.Status)
+}
+
+// Doer sends requests, in practice a Client.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Request describes a document to get.
+type Request struct {
+	URL string
+	// Validators of the previous fetch, sent along to get ErrNotModified
+	// back when nothing changed since.
+	ETag         string
+	LastModified string
+	// Accept is sent as the Accept header when not empty.
+	Accept string
+	// MaxSize caps the decompressed size of the body, DefaultMaxDocumentSize when 0.
+	MaxSize int64
+	// Auth are the credentials of a private source, if any. The secret is
+	// redacted from the errors returned.
+	Auth *domain.SourceAuth
+}
+
+// Document is a fetched document.
+type Document struct {
+	// URL is the one the document was finally served from.
+	URL string
+	// MovedTo is the URL the document moved to for good, through 301 or
+	// 308 redirects, empty when it did not. It may differ from URL when a
+	// temporary redirect followed the permanent ones.
+	MovedTo string
+	Header  http.Header
+	Body    []byte
+}
+
+// Get fetches the document, capping its size and decompressing gzip and
+// deflate encoded bodies. Redirects are followed; which of them were
+// permanent is only known when the doer is a Client. When the document
+// moved, errors are returned wrapped in a *MovedError.
+func Get(ctx context.Context, doer Doer, r Request) (*Document, error) {
+	doc, err := get(ctx, doer, r)
+	return doc, r.Auth.RedactError(err)
+}
+
+func get(ctx context.Context, doer Doer, r Request) (*Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	if r.ETag != "" {
+		req.Header.Set("If-None-Match", r.ETag)
+	}
+	if r.LastModified != "" {
+		req.Header.Set("If-Modified-Since", r.LastModified)
+	}
+	if r.Accept != "" {
+		req.Header.Set("Accept", r.Accept)
+	}
+	// asking for an encoding ourselves turns off the transport's own gzip
+	// handling, deflate is decoded below as well
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	rec := &redirects{}
+	if r.Auth != nil {
+		applyAuth(req, r.Auth)
+		if r.Auth.Type == domain.AuthTypeHeader {
+			rec.authHeader = r.Auth.Name
+		}
+	}
+	req = req.WithContext(context.WithValue(ctx, redirectsKey{}, rec))
+
+	resp, err := doer.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	doc := &Document{
+		URL:     stripAuth(resp.Request.URL.String(), r.Auth),
+		MovedTo: stripAuth(rec.movedTo(), r.Auth),
+		Header:  resp.Header,
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil, doc.wrap(ErrNotModified)
+	case resp.StatusCode == http.StatusGone:
+		return nil, doc.wrap(ErrGone)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, doc.wrap(&StatusError{StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+
+	maxSize := r.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDocumentSize
+	}
+	if resp.ContentLength > maxSize && resp.Header.Get("Content-Encoding") == "" {
+		return nil, doc.wrap(fmt.Errorf("%w: %d bytes", ErrTooLarge, resp.ContentLength))
+	}
+
+	body, err := decode(resp)
+	if err != nil {
+		return nil, doc.wrap(err)
+	}
+	doc.Body, err = io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, doc.wrap(fmt.Errorf("error reading document: %w", err))
+	}
+	if int64(len(doc.Body)) > maxSize {
+		return nil, doc.wrap(fmt.Errorf("%w: over %d bytes", ErrTooLarge, maxSize))
+	}
+
+	return doc, nil
+}
+
+func (d *Document) wrap(err error) error {
+	if d.MovedTo == "" {
+		return err
+	}
+	return &MovedError{URL: d.MovedTo, Err: err}
+}
+
+// MovedError carries the URL a document moved to for good along with the
+// error fetching it.
+type MovedError struct {
+	URL string
+	Err error
+}
+
+func (e *MovedError) Error() string {
+	return fmt.Sprintf("moved to %s: %v", e.URL, e.Err)
+}
+
+func (e *MovedError) Unwrap() error {
+	return e.Err
+}
+
+// decode undoes the Content-Encoding of the body.
+func decode(resp *http.Response) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding gzip body: %w", err)
+		}
+		return r, nil
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but some servers send it raw
+		buffered := &peekReader{r: resp.Body}
+		header, err := buffered.peek(2)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding deflate body: %w", err)
+		}
+		if len(header) == 2 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			r, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding deflate body: %w", err)
+			}
+			return r, nil
+		}
+		return flate.NewReader(buffered), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+// peekReader allows looking at the first bytes of a reader without losing them.
+type peekReader struct {
+	r      io.Reader
+	peeked []byte
+}
+
+func (p *peekReader) peek(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	read, err := io.ReadFull(p.r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	p.peeked = buf[:read]
+	return p.peeked, nil
+}
+
+func (p *peekReader) Read(b []byte) (int, error) {
+	if len(p.peeked) > 0 {
+		n := copy(b, p.peeked)
+		p.peeked = p.peeked[n:]
+		return n, nil
+	}
+	return p.r.Read(b)
+}
+
+// xmlEncoding matches the encoding of an XML declaration.
+var xmlEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?encoding\s*=\s*)["'][^"']*["']`)
+
+// UTF8 returns the body of the document converted to UTF-8. The charset of
+// the Content-Type header wins; HTML pages without one are sniffed for a
+// <meta charset>. Other documents without one are returned as they are,
+// leaving XML to the encoding of its declaration. Once converted, the
+// declaration of an XML document is changed to UTF-8 to match.
+func (d *Document) UTF8() ([]byte, error) {
+	contentType := d.Header.Get("Content-Type")
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		r, err := charset.NewReader(bytes.NewReader(d.Body), contentType)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding charset: %w", err)
+		}
+		return io.ReadAll(r)
+	}
+
+	label := strings.ToLower(strings.TrimSpace(params["charset"]))
+	if label == "" || label == "utf-8" || label == "utf8" {
+		return d.Body, nil
+	}
+
+	r, err := charset.NewReaderLabel(label, bytes.NewReader(d.Body))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding charset %s: %w", label, err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding charset %s: %w", label, err)
+	}
+	return xmlEncoding.ReplaceAll(body, []byte(`${1}"UTF-8"`)), nil
+}
+
+// redirects records the redirects a request went through, see Client.checkRedirect.
+type redirects struct {
+	hops []redirect
+	// authHeader is the header credentials were sent in, not to be sent
+	// along to other hosts.
+	authHeader string
+}
+
+type redirect struct {
+	status int
+	to     *url.URL
+}
+
+type redirectsKey struct{}
+
+// movedTo is where the permanent redirects the request started with led.
+func (r *redirects) movedTo() string {
+	var to string
+	for _, hop := range r.hops {
+		if hop.status != http.StatusMovedPermanently && hop.status != http.StatusPermanentRedirect {
+			break
+		}
+		to = hop.to.String()
+	}
+	return to
+}
@@ -0,0 +1,265 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+func compress(t *testing.T, encoding string, body string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "raw":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = fw
+	}
+	if _, err := io.WriteString(w, body); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newServer serves the routes of the test, robots.txt being missing.
+func newServer(t *testing.T, routes map[string]http.HandlerFunc) (*httptest.Server, *Client) {
+	mux := http.NewServeMux()
+	for path, h := range routes {
+		mux.HandleFunc(path, h)
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, New(WithRateLimit(1000, 100))
+}
+
+func TestGetConditional(t *testing.T) {
+	const etag, lastModified = `"v1"`, "Tue, 01 Jun 2021 10:00:00 GMT"
+
+	srv, client := newServer(t, map[string]http.HandlerFunc{
+		"/feed": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", lastModified)
+			io.WriteString(w, "<rss/>")
+		},
+	})
+
+	doc, err := Get(context.Background(), client, Request{URL: srv.URL + "/feed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc.Body) != "<rss/>" || doc.Header.Get("ETag") != etag || doc.Header.Get("Last-Modified") != lastModified {
+		t.Errorf("document = %q %v", doc.Body, doc.Header)
+	}
+
+	_, err = Get(context.Background(), client, Request{URL: srv.URL + "/feed", ETag: etag, LastModified: lastModified})
+	if !errors.Is(err, ErrNotModified) {
+		t.Fatalf("Get error = %v, want %v", err, ErrNotModified)
+	}
+	if err := SourceError(err); !errors.Is(err, domain.ErrNotModified) {
+		t.Errorf("SourceError = %v, want %v", err, domain.ErrNotModified)
+	}
+}
+
+func TestGetBody(t *testing.T) {
+	const body = "<rss><channel><title>news</title></channel></rss>"
+
+	tests := []struct {
+		name     string
+		encoding string
+		data     []byte
+		chunked  bool
+		maxSize  int64
+		want     string
+		wantErr  error
+		wantFail bool
+	}{
+		{name: "plain", data: []byte(body), want: body},
+		{name: "gzip", encoding: "gzip", data: compress(t, "gzip", body), want: body},
+		{name: "deflate", encoding: "deflate", data: compress(t, "zlib", body), want: body},
+		{name: "raw deflate", encoding: "deflate", data: compress(t, "raw", body), want: body},
+		{name: "at the cap", data: []byte(body), maxSize: int64(len(body)), want: body},
+		{name: "declared over the cap", data: []byte(body), maxSize: 10, wantErr: ErrTooLarge},
+		{name: "chunked over the cap", data: []byte(body), chunked: true, maxSize: 10, wantErr: ErrTooLarge},
+		{name: "decompressed over the cap", encoding: "gzip", data: compress(t, "gzip", body+strings.Repeat(" ", 1000)), maxSize: 100, wantErr: ErrTooLarge},
+		{name: "unsupported encoding", encoding: "br", data: []byte(body), wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, client := newServer(t, map[string]http.HandlerFunc{
+				"/feed": func(w http.ResponseWriter, r *http.Request) {
+					if tt.encoding != "" {
+						w.Header().Set("Content-Encoding", tt.encoding)
+					}
+					w.Write(tt.data[:1])
+					if tt.chunked {
+						w.(http.Flusher).Flush()
+					}
+					w.Write(tt.data[1:])
+				},
+			})
+
+			doc, err := Get(context.Background(), client, Request{URL: srv.URL + "/feed", MaxSize: tt.maxSize})
+			if wantFail := tt.wantFail || tt.wantErr != nil; (err != nil) != wantFail {
+				t.Fatalf("Get error = %v, want an error %v", err, wantFail)
+			}
+			if !errors.Is(err, tt.wantErr) && tt.wantErr != nil {
+				t.Fatalf("Get error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(doc.Body) != tt.want {
+				t.Errorf("body = %q, want %q", doc.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRedirects(t *testing.T) {
+	redirect := func(to string, status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, to, status)
+		}
+	}
+	feed := func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "<rss/>") }
+
+	srv, client := newServer(t, map[string]http.HandlerFunc{
+		"/301":          redirect("/feed", http.StatusMovedPermanently),
+		"/308":          redirect("/feed", http.StatusPermanentRedirect),
+		"/302":          redirect("/feed", http.StatusFound),
+		"/301-then-307": redirect("/307", http.StatusMovedPermanently),
+		"/307":          redirect("/feed", http.StatusTemporaryRedirect),
+		"/302-then-301": redirect("/301", http.StatusFound),
+		"/301-to-gone":  redirect("/gone", http.StatusMovedPermanently),
+		"/feed":         feed,
+		"/gone": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		},
+	})
+
+	tests := []struct {
+		path    string
+		movedTo string
+	}{
+		{path: "/feed"},
+		{path: "/301", movedTo: "/feed"},
+		{path: "/308", movedTo: "/feed"},
+		{path: "/302"},
+		{path: "/301-then-307", movedTo: "/307"},
+		{path: "/302-then-301"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			doc, err := Get(context.Background(), client, Request{URL: srv.URL + tt.path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := ""
+			if tt.movedTo != "" {
+				want = srv.URL + tt.movedTo
+			}
+			if doc.MovedTo != want {
+				t.Errorf("moved to = %q, want %q", doc.MovedTo, want)
+			}
+			if doc.URL != srv.URL+"/feed" {
+				t.Errorf("url = %q", doc.URL)
+			}
+		})
+	}
+
+	t.Run("gone", func(t *testing.T) {
+		_, err := Get(context.Background(), client, Request{URL: srv.URL + "/gone"})
+		if !errors.Is(err, ErrGone) {
+			t.Fatalf("Get error = %v, want %v", err, ErrGone)
+		}
+		if err := SourceError(err); !errors.Is(err, domain.ErrSourceGone) {
+			t.Errorf("SourceError = %v, want %v", err, domain.ErrSourceGone)
+		}
+	})
+
+	t.Run("moved then gone", func(t *testing.T) {
+		_, err := Get(context.Background(), client, Request{URL: srv.URL + "/301-to-gone"})
+		var moved *domain.SourceMovedError
+		if err := SourceError(err); !errors.As(err, &moved) || moved.URL != srv.URL+"/gone" || !errors.Is(err, domain.ErrSourceGone) {
+			t.Errorf("SourceError = %v, want gone after moving to %s/gone", err, srv.URL)
+		}
+	})
+}
+
+func TestDocumentUTF8(t *testing.T) {
+	latin1 := func(s string) string {
+		var b []byte
+		for _, r := range s {
+			b = append(b, byte(r))
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{name: "utf-8", contentType: "application/rss+xml; charset=utf-8", body: "<title>café</title>", want: "<title>café</title>"},
+		{name: "no charset", contentType: "application/rss+xml", body: "<title>café</title>", want: "<title>café</title>"},
+		{
+			name:        "latin-1 xml",
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			body:        latin1(`<?xml version="1.0" encoding="ISO-8859-1"?><title>café</title>`),
+			want:        `<?xml version="1.0" encoding="UTF-8"?><title>café</title>`,
+		},
+		{
+			name:        "windows-1252",
+			contentType: "text/xml; charset=windows-1252",
+			body:        "<title>\x93quoted\x94</title>",
+			want:        "<title>“quoted”</title>",
+		},
+		{
+			name:        "html meta charset",
+			contentType: "text/html",
+			body:        latin1(`<html><head><meta charset="iso-8859-1"></head><body>café</body></html>`),
+			want:        `<html><head><meta charset="iso-8859-1"></head><body>café</body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := &Document{Header: http.Header{"Content-Type": {tt.contentType}}, Body: []byte(tt.body)}
+			got, err := doc.UTF8()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("UTF8() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	doc := &Document{Header: http.Header{"Content-Type": {"text/xml; charset=x-unknown"}}, Body: []byte("<rss/>")}
+	if _, err := doc.UTF8(); err == nil {
+		t.Errorf("UTF8() of an unknown charset succeeded")
+	}
+}
@@ -0,0 +1,301 @@
+// Package fetch is the HTTP client shared by everything fetching from
+// publishers. It identifies itself with a User-Agent, obeys robots.txt,
+// limits the rate of requests per host and backs off from hosts that ask
+// it to with Retry-After.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"golang.org/x/time/rate"
+)
+
+const (
+	DefaultUserAgent = "news-feeder/1.0"
+
+	defaultTimeout         = 30 * time.Second
+	defaultRequestsPerHost = 1.0
+	defaultBurst           = 2
+	defaultRobotsTTL       = 24 * time.Hour
+	defaultRobotsErrorTTL  = 15 * time.Minute
+	defaultRetryAfter      = time.Minute
+	maxRetryAfter          = 24 * time.Hour
+	maxRobotsSize          = 512 << 10
+	maxRedirects           = 10
+	robotsPath             = "/robots.txt"
+)
+
+var (
+	ErrDisallowed = errors.New("disallowed by robots.txt")
+)
+
+// RetryAfterError is returned for requests to a host that asked us, through
+// the Retry-After header of a 429 or 503, to come back later.
+type RetryAfterError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s asked to retry after %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+type Client struct {
+	client    *http.Client
+	clock     clockwork.Clock
+	userAgent string
+
+	requestsPerHost float64
+	burst           int
+	robotsTTL       time.Duration
+
+	mu      sync.Mutex
+	hosts   map[string]*host
+	robotMu sync.Map // host -> *sync.Mutex, one robots.txt fetch per host at a time
+}
+
+// host is what is known about a single scheme and host.
+type host struct {
+	limiter     *rate.Limiter
+	robots      *robots
+	robotsUntil time.Time
+	retryAfter  time.Time
+}
+
+func New(opts ...Option) *Client {
+	c := &Client{
+		clock:           clockwork.NewRealClock(),
+		userAgent:       DefaultUserAgent,
+		requestsPerHost: defaultRequestsPerHost,
+		burst:           defaultBurst,
+		robotsTTL:       defaultRobotsTTL,
+		hosts:           make(map[string]*host),
+	}
+
+	c.client = &http.Client{
+		Timeout:       defaultTimeout,
+		CheckRedirect: c.checkRedirect,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// UserAgent is the identity the client fetches with.
+func (c *Client) UserAgent() string {
+	return c.userAgent
+}
+
+// Do sends the request once robots.txt allows it, the host is not backing us
+// off and its rate limit has a token available.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	key := hostKey(req.URL)
+	h := c.host(key)
+
+	rules, err := c.robots(ctx, req.URL, h)
+	if err != nil {
+		return nil, err
+	}
+	if !rules.allowed(requestPath(req.URL)) {
+		return nil, fmt.Errorf("%w: %s", ErrDisallowed, req.URL)
+	}
+
+	// checked after robots.txt, whose fetch may have been told to back off too
+	if until := c.retryAfter(h); !until.IsZero() {
+		return nil, &RetryAfterError{Host: req.URL.Host, Until: until}
+	}
+
+	return c.send(req, h)
+}
+
+// send waits for the host's rate limit, sends the request and records any
+// Retry-After the host answers with.
+func (c *Client) send(req *http.Request, h *host) (*http.Response, error) {
+	if err := h.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		c.mu.Lock()
+		h.retryAfter = c.clock.Now().Add(parseRetryAfter(resp.Header.Get("Retry-After"), c.clock.Now()))
+		c.mu.Unlock()
+	}
+	return resp, nil
+}
+
+// checkRedirect holds the requests redirects lead to to the same rules as
+// the first, and records the redirects for Get.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	if rec, ok := req.Context().Value(redirectsKey{}).(*redirects); ok && rec != nil {
+		if req.Response != nil {
+			rec.hops = append(rec.hops, redirect{status: req.Response.StatusCode, to: req.URL})
+		}
+		if rec.authHeader != "" && req.URL.Host != via[0].URL.Host {
+			req.Header.Del(rec.authHeader)
+		}
+	}
+
+	ctx := req.Context()
+	h := c.host(hostKey(req.URL))
+	rules, err := c.robots(ctx, req.URL, h)
+	if err != nil {
+		return err
+	}
+	if !rules.allowed(requestPath(req.URL)) {
+		return fmt.Errorf("%w: %s", ErrDisallowed, req.URL)
+	}
+	if until := c.retryAfter(h); !until.IsZero() {
+		return &RetryAfterError{Host: req.URL.Host, Until: until}
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return h.limiter.Wait(ctx)
+}
+
+func (c *Client) host(key string) *host {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.hosts[key]
+	if !ok {
+		h = &host{limiter: rate.NewLimiter(rate.Limit(c.requestsPerHost), c.burst)}
+		c.hosts[key] = h
+	}
+	return h
+}
+
+func (c *Client) retryAfter(h *host) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.clock.Now().Before(h.retryAfter) {
+		return h.retryAfter
+	}
+	return time.Time{}
+}
+
+// robots returns the cached robots.txt rules of the host, fetching them when
+// they are missing or stale. A robots.txt that cannot be fetched, or answers
+// with a server error, disallows everything until it is tried again, sooner
+// than a fetched one (RFC 9309, section 2.3.1.4).
+func (c *Client) robots(ctx context.Context, u *url.URL, h *host) (*robots, error) {
+	lock, _ := c.robotMu.LoadOrStore(hostKey(u), &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	c.mu.Lock()
+	rules, fresh := h.robots, c.clock.Now().Before(h.robotsUntil)
+	c.mu.Unlock()
+	if fresh {
+		return rules, nil
+	}
+
+	rules, ttl, err := c.fetchRobots(ctx, u, h)
+	if err != nil {
+		// the request itself was cancelled, nothing was learnt about the host
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		rules, ttl = disallowAll, defaultRobotsErrorTTL
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h.robots = rules
+	h.robotsUntil = c.clock.Now().Add(ttl)
+	if rules != nil && rules.crawlDelay > 0 {
+		// slow down to the pace the host asks for, never speed up
+		if limit := rate.Every(rules.crawlDelay); limit < h.limiter.Limit() {
+			h.limiter.SetLimit(limit)
+			h.limiter.SetBurst(1)
+		}
+	}
+	return rules, nil
+}
+
+func (c *Client) fetchRobots(ctx context.Context, u *url.URL, h *host) (*robots, time.Duration, error) {
+	robotsURL := url.URL{Scheme: u.Scheme, Host: u.Host, Path: robotsPath}
+	// the redirects of robots.txt are not those of the request it is fetched for
+	ctx = context.WithValue(ctx, redirectsKey{}, (*redirects)(nil))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := c.send(req, h)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return parseRobots(io.LimitReader(resp.Body, maxRobotsSize), c.userAgent), c.robotsTTL, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// no robots.txt, everything is allowed
+		return nil, c.robotsTTL, nil
+	default:
+		return nil, 0, fmt.Errorf("error fetching robots.txt: %s", resp.Status)
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as
+// an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	d := defaultRetryAfter
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		d = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		d = at.Sub(now)
+	}
+
+	if d < 0 {
+		return 0
+	}
+	if d > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return d
+}
+
+func hostKey(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+func requestPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
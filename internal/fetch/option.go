@@ -0,0 +1,56 @@
+package fetch
+
+import (
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+type Option func(*Client)
+
+// WithUserAgent sets the User-Agent sent with every request and matched
+// against the groups of robots.txt.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		if userAgent != "" {
+			c.userAgent = userAgent
+		}
+	}
+}
+
+// WithRateLimit sets how many requests per second are sent to a single host,
+// allowing bursts of up to burst requests.
+func WithRateLimit(requestsPerHost float64, burst int) Option {
+	return func(c *Client) {
+		if requestsPerHost > 0 {
+			c.requestsPerHost = requestsPerHost
+		}
+		if burst > 0 {
+			c.burst = burst
+		}
+	}
+}
+
+// WithRobotsTTL sets how long a fetched robots.txt is cached for.
+func WithRobotsTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		if ttl > 0 {
+			c.robotsTTL = ttl
+		}
+	}
+}
+
+// WithTimeout sets the timeout of a single request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		if timeout > 0 {
+			c.client.Timeout = timeout
+		}
+	}
+}
+
+func WithClock(clock clockwork.Clock) Option {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
@@ -0,0 +1,151 @@
+package fetch
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robots holds the rules of a robots.txt that apply to our user agent, see
+// https://www.rfc-editor.org/rfc/rfc9309.
+type robots struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// disallowAll are the rules of a host whose robots.txt is unreachable or
+// answers with a server error, which must be taken as disallowing everything.
+var disallowAll = &robots{rules: []robotsRule{{length: 1, pattern: regexp.MustCompile("^/")}}}
+
+// allowed reports whether the path may be fetched. The longest matching rule
+// wins, allow winning ties.
+func (r *robots) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+
+	var match *robotsRule
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if match == nil || rule.length > match.length || (rule.length == match.length && rule.allow) {
+			match = rule
+		}
+	}
+	return match == nil || match.allow
+}
+
+// parseRobots keeps the group of rules whose user-agent is the product token
+// of the user agent, compared case-insensitively, falling back to the "*"
+// group.
+func parseRobots(body io.Reader, userAgent string) *robots {
+	token := strings.ToLower(productToken(userAgent))
+
+	var (
+		specific, wildcard *robots
+		// the groups the current lines apply to
+		current []*robots
+		// whether the previous line was a user-agent line, consecutive ones share a group
+		inAgents bool
+	)
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		if key == "user-agent" {
+			if !inAgents {
+				current = nil
+			}
+			inAgents = true
+
+			agent := strings.ToLower(value)
+			switch {
+			case agent == "*":
+				if wildcard == nil {
+					wildcard = &robots{}
+				}
+				current = append(current, wildcard)
+			case token != "" && productToken(agent) == token:
+				if specific == nil {
+					specific = &robots{}
+				}
+				current = append(current, specific)
+			}
+			continue
+		}
+		inAgents = false
+
+		for _, group := range current {
+			switch key {
+			case "allow", "disallow":
+				if value == "" {
+					// an empty disallow allows everything
+					continue
+				}
+				group.rules = append(group.rules, robotsRule{
+					allow:   key == "allow",
+					length:  len(value),
+					pattern: robotsPattern(value),
+				})
+			case "crawl-delay":
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					group.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if specific != nil {
+		return specific
+	}
+	return wildcard
+}
+
+// robotsPattern turns a robots.txt path, which may use "*" wildcards and a
+// "$" end anchor, into a prefix matching regexp.
+func robotsPattern(path string) *regexp.Regexp {
+	anchored := strings.HasSuffix(path, "$")
+	path = strings.TrimSuffix(path, "$")
+
+	parts := strings.Split(path, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// productToken is the name of the user agent without its version or
+// comments, e.g. "news-feeder" for "news-feeder/1.0 (+https://...)".
+func productToken(userAgent string) string {
+	fields := strings.Fields(userAgent)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.SplitN(fields[0], "/", 2)[0]
+}
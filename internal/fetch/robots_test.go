@@ -0,0 +1,177 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	const userAgent = "news-feeder/1.0 (+https://github.com/jeffreyyong/news-feeder)"
+
+	tests := []struct {
+		name      string
+		robots    string
+		userAgent string
+		allowed   map[string]bool
+		delay     time.Duration
+	}{
+		{
+			name:    "no rules",
+			robots:  "",
+			allowed: map[string]bool{"/": true, "/feed": true},
+		},
+		{
+			name: "wildcard group",
+			robots: `User-agent: *
+Disallow: /private`,
+			allowed: map[string]bool{"/feed": true, "/private": false, "/private/x": false},
+		},
+		{
+			name: "own group wins over wildcard",
+			robots: `User-agent: *
+Disallow: /
+
+User-agent: news-feeder
+Disallow: /private`,
+			allowed: map[string]bool{"/feed": true, "/private": false},
+		},
+		{
+			name: "agent compared case-insensitively",
+			robots: `User-agent: *
+Disallow: /
+
+User-agent: News-Feeder
+Allow: /`,
+			allowed: map[string]bool{"/feed": true},
+		},
+		{
+			name: "agent with a version",
+			robots: `User-agent: *
+Disallow: /
+
+User-agent: news-feeder/2.0
+Allow: /`,
+			allowed: map[string]bool{"/feed": true},
+		},
+		{
+			name: "token containing ours is another bot",
+			robots: `User-agent: news
+Disallow: /
+
+User-agent: feeder
+Disallow: /
+
+User-agent: *
+Allow: /`,
+			allowed: map[string]bool{"/feed": true},
+		},
+		{
+			name: "bot whose token contains ours",
+			robots: `User-agent: news-feeder-pro
+Disallow: /`,
+			allowed: map[string]bool{"/feed": true},
+		},
+		{
+			name: "empty user agent only gets the wildcard group",
+			robots: `User-agent: news-feeder
+Disallow: /
+
+User-agent: *
+Disallow: /private`,
+			userAgent: " ",
+			allowed:   map[string]bool{"/feed": true, "/private": false},
+		},
+		{
+			name: "consecutive agents share a group",
+			robots: `User-agent: otherbot
+User-agent: news-feeder
+Disallow: /a`,
+			allowed: map[string]bool{"/a": false, "/b": true},
+		},
+		{
+			name: "longest match wins, allow winning ties",
+			robots: `User-agent: *
+Disallow: /news
+Allow: /news/feed
+Disallow: /x
+Allow: /x`,
+			allowed: map[string]bool{"/news/page": false, "/news/feed.xml": true, "/x": true},
+		},
+		{
+			name: "wildcards and end anchor",
+			robots: `User-agent: *
+Disallow: /*.php$
+Disallow: /tmp*/cache`,
+			allowed: map[string]bool{"/index.php": false, "/index.php?x=1": true, "/tmp1/cache": false, "/tmp": true},
+		},
+		{
+			name: "empty disallow allows everything",
+			robots: `User-agent: *
+Disallow:`,
+			allowed: map[string]bool{"/": true},
+		},
+		{
+			name: "comments and crawl delay",
+			robots: `# our rules
+User-agent: * # everyone
+Crawl-delay: 2.5
+Disallow: /private # keep out`,
+			allowed: map[string]bool{"/private": false, "/feed": true},
+			delay:   2500 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ua := userAgent
+			if tt.userAgent != "" {
+				ua = tt.userAgent
+			}
+			rules := parseRobots(strings.NewReader(tt.robots), ua)
+			for path, want := range tt.allowed {
+				if got := rules.allowed(path); got != want {
+					t.Errorf("allowed(%q) = %v, want %v", path, got, want)
+				}
+			}
+			if rules != nil && rules.crawlDelay != tt.delay {
+				t.Errorf("crawl delay = %v, want %v", rules.crawlDelay, tt.delay)
+			}
+		})
+	}
+}
+
+func TestDisallowAll(t *testing.T) {
+	for _, path := range []string{"/", "/feed", "/robots.txt?x=1"} {
+		if disallowAll.allowed(path) {
+			t.Errorf("allowed(%q) = true, want false", path)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "seconds", value: "120", want: 2 * time.Minute},
+		{name: "zero", value: "0", want: 0},
+		{name: "http date", value: "Tue, 01 Jun 2021 10:05:00 GMT", want: 5 * time.Minute},
+		{name: "date in the past", value: "Tue, 01 Jun 2021 09:00:00 GMT", want: 0},
+		{name: "capped", value: "604800", want: maxRetryAfter},
+		{name: "negative", value: "-5", want: defaultRetryAfter},
+		{name: "malformed", value: "soon", want: defaultRetryAfter},
+		{name: "empty", value: "", want: defaultRetryAfter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package fetch
+
+import (
+	"errors"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// SourceError translates an error of fetching a source with Get into the
+// domain's: ErrNotModified and ErrGone become domain.ErrNotModified and
+// domain.ErrSourceGone, and a *MovedError a *domain.SourceMovedError.
+func SourceError(err error) error {
+	var movedTo string
+	var moved *MovedError
+	if errors.As(err, &moved) {
+		movedTo, err = moved.URL, moved.Err
+	}
+
+	switch {
+	case errors.Is(err, ErrNotModified):
+		err = domain.ErrNotModified
+	case errors.Is(err, ErrGone):
+		err = domain.ErrSourceGone
+	}
+	return sourceMovedError(movedTo, err)
+}
+
+// SourceError wraps an error met processing the document of a source in a
+// *domain.SourceMovedError when the source permanently redirected.
+func (d *Document) SourceError(err error) error {
+	return sourceMovedError(d.MovedTo, err)
+}
+
+func sourceMovedError(movedTo string, err error) error {
+	if movedTo == "" {
+		return err
+	}
+	return &domain.SourceMovedError{URL: movedTo, Err: err}
+}
@@ -0,0 +1,79 @@
+// Package fingerprint reduces short texts, such as a headline and its
+// summary, to the hashed word shingles they are made of, so that the same
+// story worded differently by two publishers can be recognised by how many
+// shingles the two share.
+package fingerprint
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// stopwords carry no meaning of the story and are left out of fingerprints.
+var stopwords = map[string]bool{
+	"a": true, "about": true, "after": true, "amid": true, "an": true, "and": true,
+	"are": true, "as": true, "at": true, "be": true, "been": true, "but": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "he": true,
+	"her": true, "his": true, "in": true, "into": true, "is": true, "it": true,
+	"its": true, "new": true, "of": true, "on": true, "or": true, "over": true,
+	"says": true, "she": true, "that": true, "the": true, "their": true,
+	"they": true, "this": true, "to": true, "was": true, "were": true,
+	"will": true, "with": true, "would": true,
+}
+
+// Shingles returns the sorted, distinct hashes of the words of the text,
+// leaving out stopwords and punctuation and crudely stemming plurals.
+// Single words rather than longer shingles are used as headlines are too
+// short for word order to survive rewording.
+func Shingles(text string) []int64 {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	seen := make(map[int64]bool, len(fields))
+	var shingles []int64
+	for _, f := range fields {
+		if stopwords[f] {
+			continue
+		}
+		if len(f) > 3 && strings.HasSuffix(f, "s") && !strings.HasSuffix(f, "ss") {
+			f = strings.TrimSuffix(f, "s")
+		}
+
+		h := fnv.New64a()
+		h.Write([]byte(f))
+		sum := int64(h.Sum64())
+		if !seen[sum] {
+			seen[sum] = true
+			shingles = append(shingles, sum)
+		}
+	}
+
+	sort.Slice(shingles, func(i, j int) bool { return shingles[i] < shingles[j] })
+	return shingles
+}
+
+// Similarity is the Jaccard index of two sorted shingle sets, from 0 for
+// nothing in common to 1 for the same words.
+func Similarity(a, b []int64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	var shared, i, j int
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,91 @@
+package fingerprint
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestShingles(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{name: "case and punctuation", a: "Storm hits coast!", b: "storm, HITS coast", same: true},
+		{name: "stopwords", a: "The storm hits the coast", b: "storm hits coast", same: true},
+		{name: "plurals", a: "Storms hit coasts", b: "storm hit coast", same: true},
+		{name: "double s kept", a: "Boss", b: "Bos", same: false},
+		{name: "short words kept", a: "bus", b: "bu", same: false},
+		{name: "word order", a: "coast hits storm", b: "storm hits coast", same: true},
+		{name: "repeated words", a: "storm storm storm", b: "storm", same: true},
+		{name: "numbers", a: "Budget 2021", b: "Budget 2022", same: false},
+		{name: "other scripts", a: "Привет мир", b: "привет, мир", same: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := Shingles(tt.a), Shingles(tt.b)
+			if same := equal(a, b); same != tt.same {
+				t.Errorf("Shingles(%q) = %v, Shingles(%q) = %v, same %v, want %v", tt.a, a, tt.b, b, same, tt.same)
+			}
+		})
+	}
+}
+
+func TestShinglesSortedAndDistinct(t *testing.T) {
+	shingles := Shingles("zebra apple mango apple kiwi zebra")
+	if len(shingles) != 4 {
+		t.Fatalf("got %d shingles, want 4", len(shingles))
+	}
+	if !sort.SliceIsSorted(shingles, func(i, j int) bool { return shingles[i] < shingles[j] }) {
+		t.Errorf("shingles not sorted: %v", shingles)
+	}
+}
+
+func TestShinglesEmpty(t *testing.T) {
+	for _, text := range []string{"", "   ", "the of and", "!!! ..."} {
+		if shingles := Shingles(text); len(shingles) != 0 {
+			t.Errorf("Shingles(%q) = %v, want none", text, shingles)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{name: "identical", a: "storm hits coast", b: "storm hits coast", want: 1},
+		{name: "disjoint", a: "storm hits coast", b: "budget vote delayed", want: 0},
+		{name: "half shared", a: "storm hits coast", b: "storm hits city", want: 0.5},
+		{name: "subset", a: "storm hits coast town", b: "storm hits", want: 0.5},
+		{name: "empty", a: "", b: "storm", want: 0},
+		{name: "both empty", a: "", b: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := Shingles(tt.a), Shingles(tt.b)
+			if got := Similarity(a, b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Similarity = %v, want %v", got, tt.want)
+			}
+			if got, rev := Similarity(a, b), Similarity(b, a); got != rev {
+				t.Errorf("Similarity not symmetric: %v and %v", got, rev)
+			}
+		})
+	}
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package imageproxy
+
+import (
+	"context"
+	"sync"
+)
+
+// call is an image being produced for the callers waiting on it.
+type call struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// flight lets concurrent misses of the same key share the one fetch and
+// encoding rather than each doing it.
+type flight struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// do runs fn for the key unless it is already running, in which case it
+// waits for its result, or for ctx to be done. fn runs under the context of
+// the caller that started it.
+func (f *flight) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]*call)
+	}
+	if c, ok := f.calls[key]; ok {
+		f.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.data, c.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c := &call{done: make(chan struct{})}
+	f.calls[key] = c
+	f.mu.Unlock()
+
+	c.data, c.err = fn()
+
+	f.mu.Lock()
+	delete(f.calls, key)
+	f.mu.Unlock()
+	close(c.done)
+
+	return c.data, c.err
+}
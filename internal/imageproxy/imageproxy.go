@@ -0,0 +1,211 @@
+// Package imageproxy serves publisher images from our own origin, scaled
+// down to one of a few widths, re-encoded and cached on local disk.
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	// decoders of the formats publishers serve that the standard library reads
+	_ "image/gif"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+const (
+	// maxSourceSize is the largest original image fetched.
+	maxSourceSize = 20 << 20
+	// maxSourcePixels guards against images that are small to download but
+	// huge to decode.
+	maxSourcePixels = 50_000_000
+
+	jpegQuality = 82
+)
+
+// DefaultWidths are the widths images are served in unless configured otherwise.
+var DefaultWidths = []int{160, 320, 640, 1024}
+
+// HTTPClient fetches the original images, in practice the shared fetch.Client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Cache keeps the images already served.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Put(key string, data []byte) error
+}
+
+type Proxy struct {
+	client HTTPClient
+	cache  Cache
+	widths []int
+	misses flight
+}
+
+// New creates a proxy serving images in the given widths, DefaultWidths when
+// none are given.
+func New(client HTTPClient, cache Cache, widths ...int) *Proxy {
+	var sorted []int
+	for _, w := range widths {
+		if w > 0 {
+			sorted = append(sorted, w)
+		}
+	}
+	if len(sorted) == 0 {
+		sorted = DefaultWidths
+	}
+	sort.Ints(sorted)
+
+	return &Proxy{
+		client: client,
+		cache:  cache,
+		widths: sorted,
+	}
+}
+
+// Image returns the image at sourceURL scaled down to the narrowest of the
+// proxy's widths that is at least width, the widest one when width is 0 or
+// larger than any. Images are never scaled up. JPEG, PNG and GIF images are
+// re-encoded, as JPEG unless they have transparency. Other formats are served
+// as they were fetched when their content is recognised as an image, SVG
+// images, which may carry scripts, never being served. Concurrent requests
+// for an image not cached yet share a single fetch.
+func (p *Proxy) Image(ctx context.Context, sourceURL string, width int) (*domain.Image, error) {
+	width = p.snap(width)
+	key := sourceURL + "\x00" + strconv.Itoa(width)
+
+	if data, ok := p.cache.Get(key); ok && servable(data) {
+		return newImage(data), nil
+	}
+
+	data, err := p.misses.do(ctx, key, func() ([]byte, error) {
+		return p.produce(ctx, key, sourceURL, width)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newImage(data), nil
+}
+
+// produce fetches the original image, encodes it at the width and caches it.
+func (p *Proxy) produce(ctx context.Context, key, sourceURL string, width int) ([]byte, error) {
+	original, contentType, err := p.fetch(ctx, sourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encode(original, width)
+	if errors.Is(err, image.ErrFormat) && strings.HasPrefix(contentType, "image/") && contentType != "image/svg+xml" && servable(original) {
+		data = original
+	} else if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidImage, err)
+	}
+
+	if err := p.cache.Put(key, data); err != nil {
+		return nil, fmt.Errorf("error caching image: %w", err)
+	}
+	return data, nil
+}
+
+func (p *Proxy) snap(width int) int {
+	for _, w := range p.widths {
+		if width > 0 && width <= w {
+			return w
+		}
+	}
+	return p.widths[len(p.widths)-1]
+}
+
+func (p *Proxy) fetch(ctx context.Context, sourceURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "image/jpeg,image/png,image/gif,image/*;q=0.8")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("error fetching image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("error fetching image: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSourceSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading image: %w", err)
+	}
+	if len(data) > maxSourceSize {
+		return nil, "", fmt.Errorf("%w: larger than %d bytes", domain.ErrInvalidImage, maxSourceSize)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return data, contentType, nil
+}
+
+// encode decodes the original image and encodes it again at the width.
+func encode(original []byte, width int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(original))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > maxSourcePixels {
+		return nil, fmt.Errorf("%dx%d image is too large", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		return nil, err
+	}
+	img = resize(img, width)
+
+	var buf bytes.Buffer
+	if opaque(img) {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func opaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
+
+// servable reports whether the content of the image is recognised as a raster
+// image format, which SVG, sniffed as XML, is not.
+func servable(data []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(data), "image/")
+}
+
+// newImage describes the encoded image, its ETag changing with its content.
+func newImage(data []byte) *domain.Image {
+	sum := sha256.Sum256(data)
+	return &domain.Image{
+		Data:        data,
+		ContentType: http.DetectContentType(data),
+		ETag:        `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+}
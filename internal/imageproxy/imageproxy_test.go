@@ -0,0 +1,197 @@
+package imageproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// stubClient serves body with the given Content-Type for every request.
+type stubClient struct {
+	body        []byte
+	contentType string
+}
+
+func (c stubClient) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {c.contentType}},
+		Body:       io.NopCloser(bytes.NewReader(c.body)),
+		Request:    req,
+	}, nil
+}
+
+// gatedClient counts the requests and holds them until release is closed.
+type gatedClient struct {
+	stubClient
+	requests int32
+	release  chan struct{}
+}
+
+func (c *gatedClient) Do(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.requests, 1)
+	<-c.release
+	return c.stubClient.Do(req)
+}
+
+type memoryCache struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{blobs: make(map[string][]byte)}
+}
+
+func (c *memoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.blobs[key]
+	return data, ok
+}
+
+func (c *memoryCache) Put(key string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.blobs[key] = data
+	return nil
+}
+
+func pngImage(t *testing.T, width, height int, alpha uint8) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 30, B: 30, A: alpha})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProxyImage(t *testing.T) {
+	webp := append([]byte("RIFF\x24\x00\x00\x00WEBPVP8 "), make([]byte, 32)...)
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		width       int
+		wantType    string
+		wantWidth   int
+		wantErr     error
+	}{
+		{name: "opaque image as jpeg", body: pngImage(t, 400, 200, 255), contentType: "image/png", width: 100, wantType: "image/jpeg", wantWidth: 160},
+		{name: "transparent image as png", body: pngImage(t, 400, 200, 100), contentType: "image/png", width: 300, wantType: "image/png", wantWidth: 320},
+		{name: "never scaled up", body: pngImage(t, 100, 50, 255), contentType: "image/png", width: 640, wantType: "image/jpeg", wantWidth: 100},
+		{name: "other image format as fetched", body: webp, contentType: "image/webp", wantType: "image/webp"},
+		{name: "svg", body: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), contentType: "image/svg+xml", wantErr: domain.ErrInvalidImage},
+		{name: "svg labelled as another format", body: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), contentType: "image/png", wantErr: domain.ErrInvalidImage},
+		{name: "html labelled as an image", body: []byte(`<!DOCTYPE html><html><script>alert(1)</script></html>`), contentType: "image/jpeg", wantErr: domain.ErrInvalidImage},
+		{name: "image under another type", body: webp, contentType: "text/html", wantErr: domain.ErrInvalidImage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(stubClient{body: tt.body, contentType: tt.contentType}, newMemoryCache())
+			img, err := p.Image(context.Background(), "https://cdn.example.com/a", tt.width)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Image error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if img.ContentType != tt.wantType {
+				t.Errorf("content type = %q, want %q", img.ContentType, tt.wantType)
+			}
+			if !strings.HasPrefix(img.ETag, `"`) {
+				t.Errorf("etag = %q", img.ETag)
+			}
+			if tt.wantWidth > 0 {
+				cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if cfg.Width != tt.wantWidth {
+					t.Errorf("width = %d, want %d", cfg.Width, tt.wantWidth)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyIgnoresUnservableCacheEntries(t *testing.T) {
+	cache := newMemoryCache()
+	cache.blobs["https://cdn.example.com/a\x001024"] = []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`)
+	p := New(stubClient{body: pngImage(t, 10, 10, 255), contentType: "image/png"}, cache)
+
+	img, err := p.Image(context.Background(), "https://cdn.example.com/a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.ContentType != "image/jpeg" {
+		t.Errorf("content type = %q, want the image fetched again", img.ContentType)
+	}
+}
+
+func TestSnap(t *testing.T) {
+	p := New(nil, nil, 640, 0, 160, 320)
+	for width, want := range map[int]int{0: 640, -5: 640, 1: 160, 160: 160, 161: 320, 640: 640, 5000: 640} {
+		if got := p.snap(width); got != want {
+			t.Errorf("snap(%d) = %d, want %d", width, got, want)
+		}
+	}
+}
+
+func TestProxySharesConcurrentMisses(t *testing.T) {
+	client := &gatedClient{
+		stubClient: stubClient{body: pngImage(t, 400, 200, 255), contentType: "image/png"},
+		release:    make(chan struct{}),
+	}
+	proxy := New(client, newMemoryCache())
+
+	const callers = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := proxy.Image(context.Background(), "https://example.com/a.png", 320)
+			errs <- err
+		}()
+	}
+
+	// let every caller miss the cache before the fetch completes
+	for atomic.LoadInt32(&client.requests) == 0 {
+		runtime.Gosched()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(client.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&client.requests); n != 1 {
+		t.Errorf("fetched the image %d times, want once", n)
+	}
+}
@@ -0,0 +1,57 @@
+package imageproxy
+
+import (
+	"image"
+	"image/draw"
+)
+
+// resize scales the image down to width, keeping its aspect ratio, by
+// averaging the source pixels every destination pixel covers. Images that
+// are not wider than width are returned as they are.
+func resize(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if width <= 0 || sw <= width {
+		return src
+	}
+	height := sh * width / sw
+	if height < 1 {
+		height = 1
+	}
+
+	in := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(in, in.Bounds(), src, b.Min, draw.Src)
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for dy := 0; dy < height; dy++ {
+		y0, y1 := dy*sh/height, (dy+1)*sh/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for dx := 0; dx < width; dx++ {
+			x0, x1 := dx*sw/width, (dx+1)*sw/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n uint32
+			for y := y0; y < y1; y++ {
+				row := in.Pix[y*in.Stride+x0*4 : y*in.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint32(row[i])
+					g += uint32(row[i+1])
+					bl += uint32(row[i+2])
+					a += uint32(row[i+3])
+					n++
+				}
+			}
+
+			o := dy*out.Stride + dx*4
+			out.Pix[o] = uint8(r / n)
+			out.Pix[o+1] = uint8(g / n)
+			out.Pix[o+2] = uint8(bl / n)
+			out.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return out
+}
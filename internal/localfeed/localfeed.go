@@ -0,0 +1,338 @@
+// Package localfeed reads feeds from the local disk rather than the network:
+// single files of file:// sources, and the files dropped into the watched
+// directories of directory sources.
+package localfeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/fetch"
+)
+
+const (
+	DoneDir   = "done"
+	FailedDir = "failed"
+
+	// files modified more recently than this may still be being written
+	settleTime = 2 * time.Second
+	// files read from a directory per crawl, the rest wait for the next one
+	defaultMaxFiles = 100
+)
+
+var ErrOutsideRoot = errors.New("path outside of the files root")
+
+// feedExtensions are the files of a watched directory that are read as feeds.
+var feedExtensions = map[string]bool{
+	".xml":  true,
+	".rss":  true,
+	".atom": true,
+	".json": true,
+}
+
+// Parser is both a crawler.Parser and a crawler.BodyParser.
+type Parser interface {
+	Parse(ctx context.Context, source *domain.Source) (*domain.Feed, error)
+	ParseBody(ctx context.Context, source *domain.Source, body []byte) (*domain.Feed, error)
+}
+
+// Reader parses the feeds of file:// and directory sources with the feed
+// parser it wraps, and hands every other source to it.
+type Reader struct {
+	next     Parser
+	root     string
+	maxSize  int64
+	maxFiles int
+	clock    clockwork.Clock
+}
+
+type Option func(*Reader)
+
+// WithMaxSize caps the size of the feed files read.
+func WithMaxSize(maxSize int64) Option {
+	return func(r *Reader) {
+		if maxSize > 0 {
+			r.maxSize = maxSize
+		}
+	}
+}
+
+// WithClock functionally configures the reader with a clock.
+func WithClock(clock clockwork.Clock) Option {
+	return func(r *Reader) {
+		r.clock = clock
+	}
+}
+
+// New creates a reader of the files under root, parsed with next.
+func New(next Parser, root string, opts ...Option) (*Reader, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid files root: %w", err)
+	}
+
+	r := &Reader{
+		next:     next,
+		root:     abs,
+		maxSize:  fetch.DefaultMaxDocumentSize,
+		maxFiles: defaultMaxFiles,
+		clock:    clockwork.NewRealClock(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Path returns the local path of a file:// URL, which must be absolute and
+// under root.
+func Path(root, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("not a file url: %s", rawURL)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file url with a remote host: %s", rawURL)
+	}
+	if !filepath.IsAbs(u.Path) {
+		return "", fmt.Errorf("file url with a relative path: %s", rawURL)
+	}
+
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Clean(u.Path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, path)
+	}
+	return path, nil
+}
+
+// IsFileURL reports whether the url is a file:// one.
+func IsFileURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(rawURL), "file:")
+}
+
+// Parse reads the feed of a file:// source or the files of a directory
+// source, and hands any other source to the wrapped parser.
+func (r *Reader) Parse(ctx context.Context, source *domain.Source) (*domain.Feed, error) {
+	switch {
+	case source.Type == domain.SourceTypeDirectory:
+		return r.readDirectory(ctx, source)
+	case IsFileURL(source.URL):
+		return r.readFile(ctx, source)
+	}
+	return r.next.Parse(ctx, source)
+}
+
+// ParseBody hands pushed content to the wrapped parser.
+func (r *Reader) ParseBody(ctx context.Context, source *domain.Source, body []byte) (*domain.Feed, error) {
+	return r.next.ParseBody(ctx, source, body)
+}
+
+// readFile parses the file of the source, domain.ErrNotModified being
+// returned while its modification time is the one last read. A missing file
+// is domain.ErrSourceGone.
+func (r *Reader) readFile(ctx context.Context, source *domain.Source) (*domain.Feed, error) {
+	path, err := Path(r.root, source.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSource, err)
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, domain.ErrSourceGone
+	}
+	if err != nil {
+		return nil, err
+	}
+	lastModified := info.ModTime().UTC().Format(http.TimeFormat)
+	if lastModified == source.LastModified {
+		return nil, domain.ErrNotModified
+	}
+
+	feed, err := r.parseFile(ctx, source, path)
+	if err != nil {
+		return nil, err
+	}
+	feed.LastModified = lastModified
+	return feed, nil
+}
+
+// readDirectory parses the feed files dropped into the directory of the
+// source, oldest first, into a single feed. Files that could not be parsed
+// are moved to the failed folder, which is reported as a warning, while the
+// others are listed in the Files of the feed, to be moved to the done folder
+// with Done once its articles are stored. It returns domain.ErrNotModified
+// when no file is waiting, and an error when none of them could be parsed.
+func (r *Reader) readDirectory(ctx context.Context, source *domain.Source) (*domain.Feed, error) {
+	dir, err := Path(r.root, source.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSource, err)
+	}
+
+	files, err := r.pendingFiles(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, domain.ErrSourceGone
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, domain.ErrNotModified
+	}
+
+	var (
+		merged   *domain.Feed
+		warnings []string
+		firstErr error
+	)
+	for _, name := range files {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+
+		path := filepath.Join(dir, name)
+		feed, err := r.parseFile(ctx, source, path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error parsing %s: %w", name, err)
+			}
+			warnings = append(warnings, fmt.Sprintf("%s moved to %s: %v", name, FailedDir, err))
+			if err := move(path, FailedDir, r.clock.Now()); err != nil {
+				warnings = append(warnings, err.Error())
+			}
+			continue
+		}
+
+		if merged == nil {
+			merged = feed
+			merged.Files = []string{path}
+			continue
+		}
+		merged.Files = append(merged.Files, path)
+		merged.Articles = append(merged.Articles, feed.Articles...)
+		if feed.UpdatedAt.After(merged.UpdatedAt) {
+			merged.UpdatedAt = feed.UpdatedAt
+		}
+	}
+
+	if merged == nil {
+		if firstErr == nil {
+			firstErr = ctx.Err()
+		}
+		return nil, firstErr
+	}
+	merged.Warnings = append(merged.Warnings, warnings...)
+	return merged, nil
+}
+
+// pendingFiles lists the feed files of the directory that are done being
+// written, oldest first.
+func (r *Reader) pendingFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type file struct {
+		name    string
+		modTime time.Time
+	}
+	var files []file
+	settled := r.clock.Now().Add(-settleTime)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !feedExtensions[strings.ToLower(filepath.Ext(name))] {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || info.ModTime().After(settled) {
+			continue
+		}
+		files = append(files, file{name: name, modTime: info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].modTime.Equal(files[j].modTime) {
+			return files[i].modTime.Before(files[j].modTime)
+		}
+		return files[i].name < files[j].name
+	})
+	if len(files) > r.maxFiles {
+		files = files[:r.maxFiles]
+	}
+
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.name
+	}
+	return names, nil
+}
+
+// parseFile maps the feed of a local file. Feeds read from disk are not
+// subscribed to through WebSub, whatever hubs they advertise.
+func (r *Reader) parseFile(ctx context.Context, source *domain.Source, path string) (*domain.Feed, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	body, err := io.ReadAll(io.LimitReader(f, r.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > r.maxSize {
+		return nil, fmt.Errorf("%w: over %d bytes", fetch.ErrTooLarge, r.maxSize)
+	}
+
+	feed, err := r.next.ParseBody(ctx, source, body)
+	if err != nil {
+		return nil, err
+	}
+	feed.Hubs, feed.SelfURL = nil, ""
+	return feed, nil
+}
+
+// Done moves a file read from a watched directory into its done folder.
+func Done(path string, now time.Time) error {
+	return move(path, DoneDir, now)
+}
+
+// move moves the file into the given folder of its directory, suffixing its
+// name when a file of the same name was already moved there.
+func move(path, folder string, now time.Time) error {
+	dir, name := filepath.Split(path)
+	target := filepath.Join(dir, folder)
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return fmt.Errorf("error creating %s folder: %w", folder, err)
+	}
+
+	dest := filepath.Join(target, name)
+	if _, err := os.Stat(dest); err == nil {
+		ext := filepath.Ext(name)
+		dest = filepath.Join(target, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), now.UnixNano(), ext))
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return fmt.Errorf("error moving %s to %s: %w", name, folder, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package localfeed
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+func TestPath(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{name: "file under root", url: "file://" + root + "/vendor/feed.xml", want: filepath.Join(root, "vendor", "feed.xml")},
+		{name: "root itself", url: "file://" + root, want: root},
+		{name: "localhost", url: "file://localhost" + root + "/feed.xml", want: filepath.Join(root, "feed.xml")},
+		{name: "cleaned", url: "file://" + root + "/vendor/../feed.xml", want: filepath.Join(root, "feed.xml")},
+		{name: "traversal", url: "file://" + root + "/../etc/passwd", wantErr: true},
+		{name: "escaped traversal", url: "file://" + root + "/%2e%2e/etc/passwd", wantErr: true},
+		{name: "sibling with root as prefix", url: "file://" + root + "-other/feed.xml", wantErr: true},
+		{name: "outside root", url: "file:///etc/passwd", wantErr: true},
+		{name: "remote host", url: "file://example.com" + root + "/feed.xml", wantErr: true},
+		{name: "relative path", url: "file:feed.xml", wantErr: true},
+		{name: "not a file url", url: "https://example.com/feed.xml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Path(root, tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Path(%q) error = %v, want an error %v", tt.url, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Path(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+// stubParser parses every body into a feed of one article titled after it,
+// and fails on bodies reading "invalid".
+type stubParser struct{}
+
+func (stubParser) Parse(ctx context.Context, source *domain.Source) (*domain.Feed, error) {
+	return nil, errors.New("not a local source")
+}
+
+func (stubParser) ParseBody(ctx context.Context, source *domain.Source, body []byte) (*domain.Feed, error) {
+	if string(body) == "invalid" {
+		return nil, errors.New("invalid feed")
+	}
+	return &domain.Feed{Articles: []*domain.Article{{Title: string(body)}}}, nil
+}
+
+func TestReadDirectory(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "vendor")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range map[string]string{
+		"a.xml":   "first",
+		"b.xml":   "invalid",
+		"c.xml":   "second",
+		"d.txt":   "ignored",
+		".e.xml":  "hidden",
+		"f.json":  "third",
+		"g.atom~": "backup",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clock := clockwork.NewFakeClockAt(time.Now().Add(time.Minute))
+	r, err := New(stubParser{}, root, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &domain.Source{Type: domain.SourceTypeDirectory, URL: "file://" + dir}
+
+	feed, err := r.Parse(context.Background(), source)
+	if err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+	if len(feed.Articles) != 3 {
+		t.Errorf("got %d articles, want 3", len(feed.Articles))
+	}
+	if len(feed.Warnings) != 1 {
+		t.Errorf("got warnings %v, want one", feed.Warnings)
+	}
+
+	// parsed files stay until the feed is stored, failed ones are moved
+	for _, name := range []string{"a.xml", "c.xml", "f.json", filepath.Join(FailedDir, "b.xml")} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if len(feed.Files) != 3 {
+		t.Fatalf("got files %v, want 3", feed.Files)
+	}
+
+	for _, path := range feed.Files {
+		if err := Done(path, clock.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a.xml", "c.xml", "f.json"} {
+		if _, err := os.Stat(filepath.Join(dir, DoneDir, name)); err != nil {
+			t.Errorf("%s not done: %v", name, err)
+		}
+	}
+
+	if _, err := r.Parse(context.Background(), source); !errors.Is(err, domain.ErrNotModified) {
+		t.Errorf("Parse of an empty directory error = %v, want %v", err, domain.ErrNotModified)
+	}
+}
@@ -0,0 +1,216 @@
+// Package media finds the images of feed items, wherever the publisher put
+// them, and picks the one to use as the thumbnail.
+package media
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// images at most this wide or high are tracking pixels, spacers or icons
+// rather than pictures of the story
+const minSize = 100
+
+// FromItem returns the images of the item in the order they were found in:
+// Media RSS, enclosures, the item's own image and the <img> of its
+// description and content. Relative URLs are resolved against the item's
+// link and images found more than once are only returned the first time.
+func FromItem(item *gofeed.Item) []*domain.ArticleImage {
+	base, _ := url.Parse(item.Link)
+	c := &collector{base: base, seen: make(map[string]bool)}
+
+	media := item.Extensions["media"]
+	for _, group := range media["group"] {
+		c.addMedia(group.Children)
+	}
+	c.addMedia(media)
+
+	for _, e := range item.Enclosures {
+		if e != nil {
+			c.add(e.URL, 0, 0, e.Type, domain.ImageOriginEnclosure)
+		}
+	}
+
+	if item.Image != nil {
+		c.add(item.Image.URL, 0, 0, "", domain.ImageOriginFeed)
+	}
+	if item.ITunesExt != nil {
+		c.add(item.ITunesExt.Image, 0, 0, "", domain.ImageOriginFeed)
+	}
+
+	c.addHTML(item.Description)
+	c.addHTML(item.Content)
+
+	return c.images
+}
+
+// FromURLs returns the images at the given URLs the way FromItem does,
+// resolved against base, such as the image:image of a sitemap entry.
+func FromURLs(base string, origin domain.ImageOrigin, urls ...string) []*domain.ArticleImage {
+	b, _ := url.Parse(base)
+	c := &collector{base: b, seen: make(map[string]bool)}
+	for _, u := range urls {
+		c.add(u, 0, 0, "", origin)
+	}
+	return c.images
+}
+
+// Best picks the image to use as the thumbnail: the largest of the images of
+// known size that are not too small, then the first of the ones of unknown
+// size. It returns nil when none is suitable.
+func Best(images []*domain.ArticleImage) *domain.ArticleImage {
+	var suitable []*domain.ArticleImage
+	for _, img := range images {
+		if img.Type == "image/svg+xml" {
+			continue
+		}
+		if (img.Width > 0 && img.Width <= minSize) || (img.Height > 0 && img.Height <= minSize) {
+			continue
+		}
+		suitable = append(suitable, img)
+	}
+	if len(suitable) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(suitable, func(i, j int) bool {
+		return area(suitable[i]) > area(suitable[j])
+	})
+	return suitable[0]
+}
+
+// area ranks images, those missing a dimension are assumed as wide as high.
+func area(img *domain.ArticleImage) int {
+	w, h := img.Width, img.Height
+	if w == 0 {
+		w = h
+	}
+	if h == 0 {
+		h = w
+	}
+	return w * h
+}
+
+type collector struct {
+	base   *url.URL
+	seen   map[string]bool
+	images []*domain.ArticleImage
+}
+
+// add keeps the image when its URL is a web image not seen yet. The type is
+// guessed from the extension when not given, and anything typed other than
+// an image is left out.
+func (c *collector) add(raw string, width, height int, typ string, origin domain.ImageOrigin) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return
+	}
+	if c.base != nil {
+		u = c.base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+
+	if typ == "" {
+		typ = mime.TypeByExtension(strings.ToLower(path.Ext(u.Path)))
+	}
+	if mediaType, _, err := mime.ParseMediaType(typ); err == nil {
+		typ = mediaType
+	}
+	if typ != "" && !strings.HasPrefix(typ, "image/") {
+		return
+	}
+
+	key := u.String()
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+
+	c.images = append(c.images, &domain.ArticleImage{
+		URL:    key,
+		Width:  width,
+		Height: height,
+		Type:   typ,
+		Origin: origin,
+	})
+}
+
+// addMedia adds the media:content images and media:thumbnail of a Media RSS
+// item or group.
+func (c *collector) addMedia(media map[string][]ext.Extension) {
+	for _, content := range media["content"] {
+		medium := content.Attrs["medium"]
+		typ := content.Attrs["type"]
+		if medium != "" && medium != "image" {
+			// a video or audio may still come with a thumbnail of its own
+			c.addThumbnails(content.Children["thumbnail"])
+			continue
+		}
+		c.add(content.Attrs["url"], atoi(content.Attrs["width"]), atoi(content.Attrs["height"]), typ, domain.ImageOriginMedia)
+		c.addThumbnails(content.Children["thumbnail"])
+	}
+	c.addThumbnails(media["thumbnail"])
+}
+
+func (c *collector) addThumbnails(thumbnails []ext.Extension) {
+	for _, t := range thumbnails {
+		c.add(t.Attrs["url"], atoi(t.Attrs["width"]), atoi(t.Attrs["height"]), "", domain.ImageOriginMedia)
+	}
+}
+
+// addHTML adds the <img> of an HTML fragment, taking the widest of a srcset.
+func (c *collector) addHTML(fragment string) {
+	if !strings.Contains(fragment, "<img") {
+		return
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return
+	}
+
+	doc.Find("img").Each(func(_ int, img *goquery.Selection) {
+		src := img.AttrOr("src", "")
+		width, height := atoi(img.AttrOr("width", "")), atoi(img.AttrOr("height", ""))
+
+		if srcset, widest := img.AttrOr("srcset", ""), 0; srcset != "" {
+			for _, candidate := range strings.Split(srcset, ",") {
+				fields := strings.Fields(candidate)
+				if len(fields) != 2 || !strings.HasSuffix(fields[1], "w") {
+					continue
+				}
+				if w := atoi(strings.TrimSuffix(fields[1], "w")); w > widest && w > width {
+					widest, src = w, fields[0]
+					// the height of a srcset candidate is not known
+					width, height = w, 0
+				}
+			}
+		}
+
+		c.add(src, width, height, "", domain.ImageOriginHTML)
+	})
+}
+
+func atoi(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(s, "px")))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,129 @@
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	Version = "2.0"
+
+	OutlineTypeRSS = "rss"
+)
+
+// Document is an OPML document, see http://opml.org/spec2.opml.
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []*Outline `xml:"outline"`
+}
+
+type Outline struct {
+	Text     string     `xml:"text,attr"`
+	Title    string     `xml:"title,attr,omitempty"`
+	Type     string     `xml:"type,attr,omitempty"`
+	XMLURL   string     `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string     `xml:"htmlUrl,attr,omitempty"`
+	Category string     `xml:"category,attr,omitempty"`
+	Outlines []*Outline `xml:"outline,omitempty"`
+}
+
+// Feed is a subscription found in a document along with the categories it
+// was filed under, either through its category attribute or the outlines
+// it is nested in.
+type Feed struct {
+	URL        string
+	Title      string
+	HTMLURL    string
+	Categories []string
+}
+
+// Parse decodes an OPML document.
+func Parse(r io.Reader) (*Document, error) {
+	var doc Document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("error decoding opml: %w", err)
+	}
+	return &doc, nil
+}
+
+// Write encodes the document, including the XML header.
+func Write(w io.Writer, doc *Document) error {
+	if doc.Version == "" {
+		doc.Version = Version
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("error encoding opml: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Feeds flattens the outline tree into the feeds it subscribes to, in document order.
+func (d *Document) Feeds() []*Feed {
+	var feeds []*Feed
+	walk(d.Body.Outlines, nil, &feeds)
+	return feeds
+}
+
+func walk(outlines []*Outline, parents []string, feeds *[]*Feed) {
+	for _, o := range outlines {
+		if o.XMLURL == "" {
+			// a folder, its children are filed under it
+			name := o.Text
+			if name == "" {
+				name = o.Title
+			}
+			walk(o.Outlines, appendCategory(parents, name), feeds)
+			continue
+		}
+
+		title := o.Title
+		if title == "" {
+			title = o.Text
+		}
+
+		categories := parents
+		for _, c := range strings.Split(o.Category, ",") {
+			// categories are slash delimited paths, the leaf being the most specific
+			path := strings.Split(strings.Trim(strings.TrimSpace(c), "/"), "/")
+			categories = appendCategory(categories, path[len(path)-1])
+		}
+
+		*feeds = append(*feeds, &Feed{
+			URL:        strings.TrimSpace(o.XMLURL),
+			Title:      title,
+			HTMLURL:    o.HTMLURL,
+			Categories: categories,
+		})
+	}
+}
+
+func appendCategory(categories []string, c string) []string {
+	c = strings.TrimSpace(c)
+	if c == "" {
+		return categories
+	}
+	out := make([]string, len(categories), len(categories)+1)
+	copy(out, categories)
+	return append(out, c)
+}
@@ -0,0 +1,348 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+var ErrInvalidMapping = errors.New("invalid mapping rules")
+
+// prefixes of the paths reading the extensions and the custom fields of items
+const (
+	extPrefix    = "ext/"
+	customPrefix = "custom/"
+)
+
+// itemFields are the fields of gofeed items mapping paths may name.
+var itemFields = map[string]func(i *gofeed.Item) []string{
+	"title":       func(i *gofeed.Item) []string { return []string{i.Title} },
+	"description": func(i *gofeed.Item) []string { return []string{i.Description} },
+	"content":     func(i *gofeed.Item) []string { return []string{i.Content} },
+	"link":        func(i *gofeed.Item) []string { return []string{i.Link} },
+	"links":       func(i *gofeed.Item) []string { return i.Links },
+	"guid":        func(i *gofeed.Item) []string { return []string{i.GUID} },
+	"published":   func(i *gofeed.Item) []string { return []string{i.Published} },
+	"updated":     func(i *gofeed.Item) []string { return []string{i.Updated} },
+	"authors":     mapAuthors,
+	"categories":  func(i *gofeed.Item) []string { return i.Categories },
+	"image": func(i *gofeed.Item) []string {
+		if i.Image == nil {
+			return nil
+		}
+		return []string{i.Image.URL}
+	},
+	"enclosures": func(i *gofeed.Item) []string {
+		var urls []string
+		for _, e := range i.Enclosures {
+			if e != nil {
+				urls = append(urls, e.URL)
+			}
+		}
+		return urls
+	},
+}
+
+// mappingDateLayouts are tried on mapped dates after the layouts of the rule.
+var mappingDateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// mapping is the compiled form of the mapping rules of a source.
+type mapping struct {
+	fields []*fieldMapping
+}
+
+type fieldMapping struct {
+	name    string
+	paths   []itemPath
+	layouts []string
+	replace []replacement
+}
+
+type replacement struct {
+	re   *regexp.Regexp
+	with string
+}
+
+// itemPath is where in an item a value is read: one of the itemFields, an
+// extension element or attribute, or a custom field.
+type itemPath struct {
+	field  string
+	ext    []string
+	attr   string
+	custom string
+}
+
+// mappedItem holds the values the rules found in an item, nil for the fields
+// whose rules found nothing.
+type mappedItem struct {
+	title, description, content, link, guid *string
+	publishedAt                             *time.Time
+	images, authors, tags                   []string
+}
+
+// ValidateMapping checks the paths, the date layouts and the regular
+// expressions of the rules.
+func ValidateMapping(rules *domain.MappingRules) error {
+	_, err := compileMapping(rules)
+	return err
+}
+
+// compileMapping returns nil when the rules override nothing.
+func compileMapping(rules *domain.MappingRules) (*mapping, error) {
+	if rules == nil || rules.Empty() {
+		return nil, nil
+	}
+
+	m := &mapping{}
+	for _, f := range []struct {
+		name string
+		rule *domain.FieldRule
+	}{
+		{"title", rules.Title},
+		{"description", rules.Description},
+		{"content", rules.Content},
+		{"link", rules.Link},
+		{"guid", rules.GUID},
+		{"published_at", rules.PublishedAt},
+		{"image", rules.Image},
+		{"authors", rules.Authors},
+		{"tags", rules.Tags},
+	} {
+		if f.rule == nil {
+			continue
+		}
+		fm, err := compileField(f.name, f.rule)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidMapping, f.name, err)
+		}
+		m.fields = append(m.fields, fm)
+	}
+	return m, nil
+}
+
+func compileField(name string, rule *domain.FieldRule) (*fieldMapping, error) {
+	if len(rule.From) == 0 {
+		return nil, errors.New("no path to map from")
+	}
+	if len(rule.DateLayouts) > 0 && name != "published_at" {
+		return nil, errors.New("date layouts are only for published_at")
+	}
+
+	fm := &fieldMapping{name: name, layouts: rule.DateLayouts}
+	for _, from := range rule.From {
+		p, err := parsePath(from)
+		if err != nil {
+			return nil, err
+		}
+		fm.paths = append(fm.paths, p)
+	}
+	for _, r := range rule.Replace {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern %q: %v", r.Pattern, err)
+		}
+		fm.replace = append(fm.replace, replacement{re: re, with: r.With})
+	}
+	return fm, nil
+}
+
+// parsePath reads paths such as "content", "ext/media/thumbnail@url",
+// "ext/media/group/content@url" or "custom/summary".
+func parsePath(s string) (itemPath, error) {
+	switch {
+	case strings.HasPrefix(s, extPrefix):
+		var p itemPath
+		rest := strings.TrimPrefix(s, extPrefix)
+		if i := strings.LastIndex(rest, "@"); i >= 0 {
+			rest, p.attr = rest[:i], rest[i+1:]
+			if p.attr == "" {
+				return itemPath{}, fmt.Errorf("no attribute in path %q", s)
+			}
+		}
+		p.ext = strings.Split(rest, "/")
+		if len(p.ext) < 2 {
+			return itemPath{}, fmt.Errorf("path %q needs a namespace prefix and an element", s)
+		}
+		for _, e := range p.ext {
+			if e == "" {
+				return itemPath{}, fmt.Errorf("empty element in path %q", s)
+			}
+		}
+		return p, nil
+	case strings.HasPrefix(s, customPrefix):
+		key := strings.TrimPrefix(s, customPrefix)
+		if key == "" {
+			return itemPath{}, fmt.Errorf("no key in path %q", s)
+		}
+		return itemPath{custom: key}, nil
+	}
+	if _, ok := itemFields[s]; !ok {
+		return itemPath{}, fmt.Errorf("unknown path %q", s)
+	}
+	return itemPath{field: s}, nil
+}
+
+// values reads the values at the path, which are empty when the item has none.
+func (p itemPath) values(i *gofeed.Item) []string {
+	switch {
+	case p.field != "":
+		return itemFields[p.field](i)
+	case p.custom != "":
+		return []string{i.Custom[p.custom]}
+	}
+
+	exts := i.Extensions[p.ext[0]][p.ext[1]]
+	for _, child := range p.ext[2:] {
+		var children []ext.Extension
+		for _, e := range exts {
+			children = append(children, e.Children[child]...)
+		}
+		exts = children
+	}
+
+	var values []string
+	for _, e := range exts {
+		if p.attr != "" {
+			values = append(values, e.Attrs[p.attr])
+		} else {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+// find returns the cleaned up values of the first path that has any.
+func (fm *fieldMapping) find(i *gofeed.Item) []string {
+	for _, p := range fm.paths {
+		var values []string
+		for _, v := range p.values(i) {
+			for _, r := range fm.replace {
+				v = r.re.ReplaceAllString(v, r.with)
+			}
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			return values
+		}
+	}
+	return nil
+}
+
+// parseDate reads the first of the values in one of the layouts of the rule
+// or a common one.
+func (fm *fieldMapping) parseDate(values []string) *time.Time {
+	layouts := append(append([]string{}, fm.layouts...), mappingDateLayouts...)
+	for _, v := range values {
+		for _, l := range layouts {
+			if t, err := time.Parse(l, v); err == nil {
+				return &t
+			}
+		}
+	}
+	return nil
+}
+
+// apply reads the fields the rules are for from the item, counting in misses
+// the fields nothing was found for.
+func (m *mapping) apply(i *gofeed.Item, misses map[string]int) *mappedItem {
+	mapped := &mappedItem{}
+	for _, fm := range m.fields {
+		values := fm.find(i)
+		first := func() *string {
+			if len(values) == 0 {
+				return nil
+			}
+			return &values[0]
+		}
+
+		found := len(values) > 0
+		switch fm.name {
+		case "title":
+			mapped.title = first()
+		case "description":
+			mapped.description = first()
+		case "content":
+			mapped.content = first()
+		case "link":
+			mapped.link = first()
+		case "guid":
+			mapped.guid = first()
+		case "published_at":
+			mapped.publishedAt = fm.parseDate(values)
+			found = mapped.publishedAt != nil
+		case "image":
+			mapped.images = values
+		case "authors":
+			mapped.authors = values
+		case "tags":
+			mapped.tags = values
+		}
+		if !found {
+			misses[fm.name]++
+		}
+	}
+	return mapped
+}
+
+// warnings are about the rules that found nothing in any of the items.
+func (m *mapping) warnings(misses map[string]int, items int) []string {
+	var warnings []string
+	for _, fm := range m.fields {
+		if items > 0 && misses[fm.name] == items {
+			warnings = append(warnings, fmt.Sprintf("mapping for %s found nothing in any of the %d items", fm.name, items))
+		}
+	}
+	return warnings
+}
+
+// overwrite replaces the fields of the item the rules found values for, once
+// all of them were read, so that the rules all see the item as it was parsed.
+func (mi *mappedItem) overwrite(i *gofeed.Item) {
+	if mi.title != nil {
+		i.Title = *mi.title
+	}
+	if mi.description != nil {
+		i.Description = *mi.description
+	}
+	if mi.content != nil {
+		i.Content = *mi.content
+	}
+	if mi.link != nil {
+		i.Link = *mi.link
+	}
+	if mi.guid != nil {
+		i.GUID = *mi.guid
+	}
+	if mi.publishedAt != nil {
+		i.PublishedParsed = mi.publishedAt
+	}
+	if len(mi.authors) > 0 {
+		i.Author = nil
+		i.Authors = nil
+		for _, name := range mi.authors {
+			i.Authors = append(i.Authors, &gofeed.Person{Name: name})
+		}
+	}
+	if len(mi.tags) > 0 {
+		i.Categories = mi.tags
+	}
+}
@@ -0,0 +1,165 @@
+package rss
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+func TestCompileMapping(t *testing.T) {
+	from := func(paths ...string) *domain.FieldRule { return &domain.FieldRule{From: paths} }
+
+	tests := []struct {
+		name    string
+		rules   *domain.MappingRules
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "no rules", rules: nil, wantNil: true},
+		{name: "empty rules", rules: &domain.MappingRules{}, wantNil: true},
+		{name: "item fields", rules: &domain.MappingRules{Title: from("title"), Image: from("image", "enclosures")}},
+		{name: "extension element", rules: &domain.MappingRules{Authors: from("ext/dc/creator")}},
+		{name: "nested extension attribute", rules: &domain.MappingRules{Image: from("ext/media/group/content@url")}},
+		{name: "custom field", rules: &domain.MappingRules{Description: from("custom/summary")}},
+		{name: "no path", rules: &domain.MappingRules{Title: from()}, wantErr: true},
+		{name: "unknown field", rules: &domain.MappingRules{Title: from("headline")}, wantErr: true},
+		{name: "extension without an element", rules: &domain.MappingRules{Title: from("ext/dc")}, wantErr: true},
+		{name: "extension with an empty element", rules: &domain.MappingRules{Image: from("ext/media//content@url")}, wantErr: true},
+		{name: "extension with an empty attribute", rules: &domain.MappingRules{Image: from("ext/media/thumbnail@")}, wantErr: true},
+		{name: "custom field without a key", rules: &domain.MappingRules{Title: from("custom/")}, wantErr: true},
+		{
+			name:    "date layouts on another field",
+			rules:   &domain.MappingRules{Title: &domain.FieldRule{From: []string{"title"}, DateLayouts: []string{"2006"}}},
+			wantErr: true,
+		},
+		{
+			name:    "bad pattern",
+			rules:   &domain.MappingRules{Title: &domain.FieldRule{From: []string{"title"}, Replace: []domain.Replacement{{Pattern: "("}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := compileMapping(tt.rules)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidMapping) {
+					t.Errorf("compileMapping error = %v, want %v", err, ErrInvalidMapping)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("compileMapping error = %v", err)
+			}
+			if (m == nil) != tt.wantNil {
+				t.Errorf("compileMapping = %v, want nil %v", m, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestMappingApply(t *testing.T) {
+	m, err := compileMapping(&domain.MappingRules{
+		Title: &domain.FieldRule{
+			From:    []string{"custom/headline", "title"},
+			Replace: []domain.Replacement{{Pattern: ` - Example News$`}},
+		},
+		Description: &domain.FieldRule{From: []string{"custom/summary"}},
+		Link: &domain.FieldRule{
+			From:    []string{"guid"},
+			Replace: []domain.Replacement{{Pattern: `^urn:example:(\d+)$`, With: "https://example.com/news/$1"}},
+		},
+		PublishedAt: &domain.FieldRule{From: []string{"ext/dc/date"}, DateLayouts: []string{"02/01/2006 15:04"}},
+		Image:       &domain.FieldRule{From: []string{"ext/media/group/content@url", "ext/media/thumbnail@url"}},
+		Authors:     &domain.FieldRule{From: []string{"ext/dc/creator"}},
+		Tags:        &domain.FieldRule{From: []string{"custom/keywords"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := &gofeed.Item{
+		Title:  "Storm hits coast - Example News",
+		GUID:   "urn:example:42",
+		Custom: map[string]string{"summary": "  High winds overnight  "},
+		Extensions: ext.Extensions{
+			"dc": {
+				"date":    {{Value: "01/06/2021 10:30"}},
+				"creator": {{Value: "Jane Doe"}, {Value: " "}, {Value: "John Roe"}},
+			},
+			"media": {
+				"thumbnail": {{Attrs: map[string]string{"url": "https://cdn.example.com/thumb.jpg"}}},
+				"group": {{Children: map[string][]ext.Extension{
+					"content": {{Attrs: map[string]string{"url": ""}}},
+				}}},
+			},
+		},
+	}
+
+	misses := make(map[string]int)
+	mapped := m.apply(item, misses)
+
+	str := func(p *string) string {
+		if p == nil {
+			return "<nil>"
+		}
+		return *p
+	}
+	if got := str(mapped.title); got != "Storm hits coast" {
+		t.Errorf("title = %q", got)
+	}
+	if got := str(mapped.description); got != "High winds overnight" {
+		t.Errorf("description = %q", got)
+	}
+	if got := str(mapped.link); got != "https://example.com/news/42" {
+		t.Errorf("link = %q", got)
+	}
+	if want := time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC); mapped.publishedAt == nil || !mapped.publishedAt.Equal(want) {
+		t.Errorf("published at = %v, want %v", mapped.publishedAt, want)
+	}
+	// the group has no url, the thumbnail is the next path
+	if want := []string{"https://cdn.example.com/thumb.jpg"}; !reflect.DeepEqual(mapped.images, want) {
+		t.Errorf("images = %v, want %v", mapped.images, want)
+	}
+	if want := []string{"Jane Doe", "John Roe"}; !reflect.DeepEqual(mapped.authors, want) {
+		t.Errorf("authors = %v, want %v", mapped.authors, want)
+	}
+	if want := map[string]int{"tags": 1}; !reflect.DeepEqual(misses, want) {
+		t.Errorf("misses = %v, want %v", misses, want)
+	}
+
+	mapped.overwrite(item)
+	if item.Title != "Storm hits coast" || item.Link != "https://example.com/news/42" || item.GUID != "urn:example:42" {
+		t.Errorf("item not overwritten: %q %q %q", item.Title, item.Link, item.GUID)
+	}
+	if len(item.Authors) != 2 || item.Authors[0].Name != "Jane Doe" {
+		t.Errorf("authors not overwritten: %v", item.Authors)
+	}
+}
+
+func TestMappingWarnings(t *testing.T) {
+	m, err := compileMapping(&domain.MappingRules{
+		Title: &domain.FieldRule{From: []string{"title"}},
+		Tags:  &domain.FieldRule{From: []string{"custom/keywords"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	misses := make(map[string]int)
+	for _, item := range []*gofeed.Item{{Title: "a"}, {}} {
+		m.apply(item, misses)
+	}
+	want := []string{"mapping for tags found nothing in any of the 2 items"}
+	if got := m.warnings(misses, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %v, want %v", got, want)
+	}
+	if got := m.warnings(map[string]int{}, 0); got != nil {
+		t.Errorf("warnings without items = %v", got)
+	}
+}
@@ -1,44 +1,133 @@
 package rss
 
 import (
+	"bytes"
 	"context"
-	"errors"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/fetch"
+	"github.com/jeffreyyong/news-feeder/internal/media"
+	"github.com/jeffreyyong/news-feeder/internal/websub"
 	"github.com/mmcdole/gofeed"
 )
 
-const (
-	EnclosureTypeImage = "image/jpeg"
-)
+// HTTPClient sends the feed requests, in practice the shared fetch.Client.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// feedAccept is the Accept header feeds are requested with.
+const feedAccept = "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, text/xml;q=0.9, application/json;q=0.8, */*;q=0.5"
 
 type Parser struct {
 	*gofeed.Parser
+	client  HTTPClient
+	maxSize int64
 }
 
-func NewParser() *Parser {
-	return &Parser{
-		Parser: gofeed.NewParser(),
+type Option func(*Parser)
+
+// WithMaxSize caps the size of the feeds fetched, once decompressed.
+func WithMaxSize(maxSize int64) Option {
+	return func(p *Parser) {
+		if maxSize > 0 {
+			p.maxSize = maxSize
+		}
 	}
 }
 
-func (p *Parser) Parse(ctx context.Context, url string) (*domain.Feed, error) {
-	f, err := p.ParseURL(url)
+func NewParser(client HTTPClient, opts ...Option) *Parser {
+	parser := gofeed.NewParser()
+	parser.RSSTranslator = &rssTranslator{}
+
+	p := &Parser{
+		Parser:  parser,
+		client:  client,
+		maxSize: fetch.DefaultMaxDocumentSize,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Parse fetches the source and maps it into a feed. The validators from the
+// previous fetch are sent along, and domain.ErrNotModified is returned when
+// the server reports that nothing has changed since. A source that is gone
+// for good returns domain.ErrSourceGone. When the source permanently
+// redirected, the feed's MovedTo is set, or the error is returned as a
+// *domain.SourceMovedError.
+func (p *Parser) Parse(ctx context.Context, source *domain.Source) (*domain.Feed, error) {
+	doc, err := fetch.Get(ctx, p.client, fetch.Request{
+		URL:          source.URL,
+		ETag:         source.ETag,
+		LastModified: source.LastModified,
+		Auth:         source.Auth,
+		Accept:       feedAccept,
+		MaxSize:      p.maxSize,
+	})
+	if err != nil {
+		return nil, fetch.SourceError(err)
+	}
+
+	body, err := doc.UTF8()
 	if err != nil {
-		return nil, errors.New("error parsing feed")
+		return nil, doc.SourceError(err)
 	}
 
+	feed, err := p.parse(source, doc.Header, body)
+	if err != nil {
+		return nil, doc.SourceError(err)
+	}
+	feed.ETag = doc.Header.Get("ETag")
+	feed.LastModified = doc.Header.Get("Last-Modified")
+	if doc.MovedTo != "" {
+		feed.MovedTo = doc.MovedTo
+		feed.FeedLink = doc.MovedTo
+	}
+	feed.Payload = &domain.Payload{
+		Origin:       domain.PayloadOriginFetch,
+		URL:          doc.URL,
+		ContentType:  doc.Header.Get("Content-Type"),
+		ETag:         feed.ETag,
+		LastModified: feed.LastModified,
+		Body:         body,
+	}
+
+	return feed, nil
+}
+
+// ParseBody maps a feed document that was delivered rather than fetched, such
+// as the content a WebSub hub pushes, into a feed of the source.
+func (p *Parser) ParseBody(ctx context.Context, source *domain.Source, body []byte) (*domain.Feed, error) {
+	return p.parse(source, nil, body)
+}
+
+func (p *Parser) parse(source *domain.Source, header http.Header, body []byte) (*domain.Feed, error) {
+	f, err := p.Parser.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing feed: %w", err)
+	}
+
+	m, err := compileMapping(source.MappingRules)
+	if err != nil {
+		return nil, err
+	}
+	misses := make(map[string]int)
+
 	var articles []*domain.Article
 
 	for _, i := range f.Items {
-
-		var thumbnailURL string
-		for _, enclosure := range i.Enclosures {
-			if enclosure.Type == EnclosureTypeImage {
-				thumbnailURL = enclosure.URL
-			}
+		var mapped *mappedItem
+		if m != nil {
+			mapped = m.apply(i, misses)
+			mapped.overwrite(i)
 		}
 
 		var publishedAt time.Time
@@ -47,12 +136,20 @@ func (p *Parser) Parse(ctx context.Context, url string) (*domain.Feed, error) {
 		}
 
 		article := &domain.Article{
-			PublishedAt:  publishedAt,
-			Title:        i.Title,
-			Description:  i.Description,
-			Link:         i.Link,
-			ThumbnailURL: thumbnailURL,
-			GUID:         i.GUID,
+			PublishedAt:   publishedAt,
+			Title:         i.Title,
+			Description:   i.Description,
+			Link:          i.Link,
+			GUID:          itemGUID(source, i),
+			Authors:       mapAuthors(i),
+			Tags:          mapTags(i.Categories),
+			Content:       i.Content,
+			ItemUpdatedAt: i.UpdatedParsed,
+			Images:        media.FromItem(i),
+		}
+		article.SetThumbnail(media.Best(article.Images))
+		if mapped != nil && len(mapped.images) > 0 {
+			mapImages(article, mapped.images)
 		}
 		articles = append(articles, article)
 	}
@@ -66,37 +163,98 @@ func (p *Parser) Parse(ctx context.Context, url string) (*domain.Feed, error) {
 		Title:       f.Title,
 		Description: f.Description,
 		Link:        f.Link,
-		FeedLink:    url,
-		Category:    mapCategory(f.FeedLink),
+		FeedLink:    source.URL,
+		Category:    source.Category,
 		Language:    strings.ToLower(f.Language),
 		UpdatedAt:   updatedAt,
 		Articles:    articles,
-		Provider:    mapProvider(f.FeedLink),
+		Provider:    source.Provider,
+	}
+	feed.Hubs, feed.SelfURL = websub.DiscoverLinks(header, body)
+	feed.TTL, feed.SkipHours, feed.SkipDays = mapHints(f)
+	if m != nil {
+		feed.Warnings = m.warnings(misses, len(f.Items))
 	}
 
 	return feed, nil
 }
 
-func mapCategory(title string) domain.Category {
-	t := strings.ToLower(title)
+// itemGUID identifies an item for as long as it is carried. Items without a
+// <guid> fall back to their link, or to a hash of their title within the
+// source when they have neither, so that they are not all taken for the same
+// article. It is empty for an item with nothing to tell it apart.
+func itemGUID(source *domain.Source, i *gofeed.Item) string {
 	switch {
-	case strings.Contains(t, "uk"):
-		return domain.CategoryUK
-	case strings.Contains(t, "technology"):
-		return domain.CategoryTechnology
+	case strings.TrimSpace(i.GUID) != "":
+		return i.GUID
+	case strings.TrimSpace(i.Link) != "":
+		return i.Link
+	case strings.TrimSpace(i.Title) != "":
+		sum := sha256.Sum256([]byte(source.URL + "\x00" + strings.TrimSpace(i.Title)))
+		return "sha256:" + hex.EncodeToString(sum[:])
 	default:
-		return domain.CategoryUnknown
+		return ""
 	}
 }
 
-func mapProvider(feedLink string) domain.Provider {
-	l := strings.ToLower(feedLink)
-	switch {
-	case strings.Contains(l, "sky"):
-		return domain.ProviderSky
-	case strings.Contains(l, "bbc"):
-		return domain.ProviderBBC
-	default:
-		return domain.ProviderUnknown
+// mapImages puts the images the mapping rules found first, the first of them
+// being the thumbnail whatever the size of the others.
+func mapImages(article *domain.Article, urls []string) {
+	images := media.FromURLs(article.Link, domain.ImageOriginFeed, urls...)
+	if len(images) == 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, img := range images {
+		seen[img.URL] = true
+	}
+	for _, img := range article.Images {
+		if !seen[img.URL] {
+			images = append(images, img)
+		}
+	}
+	article.Images = images
+	article.SetThumbnail(images[0])
+}
+
+// mapAuthors returns the names of the item's authors, falling back to their
+// email when no name is given.
+func mapAuthors(i *gofeed.Item) []string {
+	people := i.Authors
+	if len(people) == 0 && i.Author != nil {
+		people = []*gofeed.Person{i.Author}
+	}
+
+	var authors []string
+	seen := make(map[string]bool)
+	for _, p := range people {
+		if p == nil {
+			continue
+		}
+		name := strings.TrimSpace(p.Name)
+		if name == "" {
+			name = strings.TrimSpace(p.Email)
+		}
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		authors = append(authors, name)
+	}
+	return authors
+}
+
+// mapTags trims and de-duplicates the item level categories.
+func mapTags(categories []string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, c := range categories {
+		tag := strings.TrimSpace(c)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
 	}
+	return tags
 }
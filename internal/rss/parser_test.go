@@ -0,0 +1,63 @@
+package rss
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+func TestParseBodyGUIDs(t *testing.T) {
+	const body = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+  <title>News</title>
+  <item><guid>urn:news:1</guid><title>With a guid</title><link>https://example.com/1</link></item>
+  <item><title>Without a guid</title><link>https://example.com/2</link></item>
+  <item><title>Without a guid either</title><link>https://example.com/3</link></item>
+  <item><title>Without a link</title><description>first</description></item>
+  <item><title>Without a link</title><description>edited</description></item>
+  <item><title>Another without a link</title></item>
+  <item><description>Nothing to tell it apart</description></item>
+</channel></rss>`
+
+	source := &domain.Source{URL: "https://example.com/feed.xml"}
+	feed, err := NewParser(nil).ParseBody(context.Background(), source, []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Articles) != 7 {
+		t.Fatalf("got %d articles, want 7", len(feed.Articles))
+	}
+
+	guids := make([]string, len(feed.Articles))
+	for i, a := range feed.Articles {
+		guids[i] = a.GUID
+	}
+
+	for i, want := range []string{"urn:news:1", "https://example.com/2", "https://example.com/3"} {
+		if guids[i] != want {
+			t.Errorf("article %d guid = %q, want %q", i, guids[i], want)
+		}
+	}
+	if !strings.HasPrefix(guids[3], "sha256:") {
+		t.Errorf("guid of an item without a link = %q, want a hash of its title", guids[3])
+	}
+	if guids[3] != guids[4] {
+		t.Errorf("guid changed along with the description: %q, %q", guids[3], guids[4])
+	}
+	if guids[5] == guids[3] || !strings.HasPrefix(guids[5], "sha256:") {
+		t.Errorf("guid of another item without a link = %q", guids[5])
+	}
+	if guids[6] != "" {
+		t.Errorf("guid of an item with nothing to tell it apart = %q, want none", guids[6])
+	}
+
+	other, err := NewParser(nil).ParseBody(context.Background(), &domain.Source{URL: "https://example.org/feed.xml"}, []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Articles[3].GUID == guids[3] {
+		t.Errorf("items without a link of different sources share the guid %q", guids[3])
+	}
+}
@@ -0,0 +1,96 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+// keys of gofeed.Feed.Custom the RSS scheduling hints are carried in, which
+// the universal feed otherwise drops
+const (
+	customTTL       = "ttl"
+	customSkipHours = "skipHours"
+	customSkipDays  = "skipDays"
+)
+
+// rssTranslator is gofeed's RSS translator, additionally keeping the ttl,
+// skipHours and skipDays of the channel.
+type rssTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+func (t *rssTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	f, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+
+	r, ok := feed.(*rss.Feed)
+	if !ok {
+		return f, nil
+	}
+
+	if f.Custom == nil {
+		f.Custom = make(map[string]string)
+	}
+	if ttl := strings.TrimSpace(r.TTL); ttl != "" {
+		f.Custom[customTTL] = ttl
+	}
+	if len(r.SkipHours) > 0 {
+		f.Custom[customSkipHours] = strings.Join(r.SkipHours, ",")
+	}
+	if len(r.SkipDays) > 0 {
+		f.Custom[customSkipDays] = strings.Join(r.SkipDays, ",")
+	}
+	return f, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// mapHints reads the scheduling hints kept by rssTranslator. Malformed
+// values are ignored.
+func mapHints(f *gofeed.Feed) (ttl time.Duration, skipHours []int, skipDays []time.Weekday) {
+	if minutes, err := strconv.Atoi(f.Custom[customTTL]); err == nil && minutes > 0 {
+		ttl = time.Duration(minutes) * time.Minute
+	}
+
+	for _, h := range splitList(f.Custom[customSkipHours]) {
+		hour, err := strconv.Atoi(h)
+		// some feeds count the hours 1-24
+		if err == nil && hour == 24 {
+			hour = 0
+		}
+		if err == nil && hour >= 0 && hour < 24 {
+			skipHours = append(skipHours, hour)
+		}
+	}
+
+	for _, d := range splitList(f.Custom[customSkipDays]) {
+		if day, ok := weekdays[strings.ToLower(d)]; ok {
+			skipDays = append(skipDays, day)
+		}
+	}
+	return ttl, skipHours, skipDays
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,207 @@
+// Package sanitize cleans untrusted HTML from feeds and publisher pages before
+// it is stored or served.
+package sanitize
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedTags maps the elements that are kept to the attributes they may carry.
+var allowedTags = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: {"cite"},
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Li:         nil,
+	atom.Ol:         nil,
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          {"cite"},
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// droppedTags are removed along with everything inside them, rather than
+// unwrapped like other disallowed elements.
+var droppedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Math:     true,
+}
+
+// urlAttrs are the attributes holding links, which must be http(s).
+var urlAttrs = map[string]bool{
+	"href": true,
+	"src":  true,
+	"cite": true,
+}
+
+// HTML keeps only an allowlist of formatting elements and attributes of the
+// fragment. Other elements are unwrapped, scripts and the like are dropped
+// with their content, and links are resolved against base when it is given.
+func HTML(fragment string, base *url.URL) string {
+	var buf bytes.Buffer
+	z := html.NewTokenizer(strings.NewReader(fragment))
+
+	// depth of nested dropped elements, nothing is written while inside one
+	dropped := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return ""
+			}
+			return strings.TrimSpace(buf.String())
+		}
+
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[tok.DataAtom] {
+				if tt == html.StartTagToken && !isVoid(tok.DataAtom) {
+					dropped++
+				}
+				continue
+			}
+			if dropped > 0 {
+				continue
+			}
+			attrs, ok := allowedTags[tok.DataAtom]
+			if !ok {
+				continue
+			}
+			tok.Attr = filterAttrs(tok.Attr, attrs, base)
+			if tok.DataAtom == atom.Img && !hasAttr(tok.Attr, "src") {
+				continue
+			}
+			if tok.DataAtom == atom.A {
+				tok.Attr = append(tok.Attr, html.Attribute{Key: "rel", Val: "nofollow noopener"})
+			}
+			buf.WriteString(tok.String())
+		case html.EndTagToken:
+			if droppedTags[tok.DataAtom] {
+				if dropped > 0 {
+					dropped--
+				}
+				continue
+			}
+			if dropped > 0 {
+				continue
+			}
+			if _, ok := allowedTags[tok.DataAtom]; ok && !isVoid(tok.DataAtom) {
+				buf.WriteString(tok.String())
+			}
+		case html.TextToken:
+			if dropped > 0 {
+				continue
+			}
+			buf.WriteString(html.EscapeString(tok.Data))
+		}
+	}
+}
+
+func filterAttrs(attrs []html.Attribute, allowed []string, base *url.URL) []html.Attribute {
+	var out []html.Attribute
+	for _, a := range attrs {
+		if a.Namespace != "" || !contains(allowed, a.Key) {
+			continue
+		}
+		if urlAttrs[a.Key] {
+			u, ok := safeURL(a.Val, base)
+			if !ok {
+				continue
+			}
+			a.Val = u
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// safeURL resolves the link against base and only accepts http(s) results.
+func safeURL(raw string, base *url.URL) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func isVoid(a atom.Atom) bool {
+	switch a {
+	case atom.Br, atom.Hr, atom.Img, atom.Embed:
+		return true
+	}
+	return false
+}
+
+func hasAttr(attrs []html.Attribute, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
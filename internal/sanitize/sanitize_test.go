@@ -0,0 +1,102 @@
+package sanitize
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/news/")
+
+	tests := []struct {
+		name     string
+		fragment string
+		base     *url.URL
+		want     string
+	}{
+		{
+			name:     "allowed elements kept",
+			fragment: "<p>Storm <b>hits</b> <em>coast</em></p>",
+			want:     "<p>Storm <b>hits</b> <em>coast</em></p>",
+		},
+		{
+			name:     "script dropped with its content",
+			fragment: "<p>a</p><script>alert(1)</script><p>b</p>",
+			want:     "<p>a</p><p>b</p>",
+		},
+		{
+			name:     "nested dropped elements",
+			fragment: "<svg><script>alert(1)</script><text>y</text></svg>z",
+			want:     "z",
+		},
+		{
+			name:     "other elements unwrapped",
+			fragment: "<div><span>Storm</span></div>",
+			want:     "Storm",
+		},
+		{
+			name:     "event handlers and styles stripped",
+			fragment: `<p onclick="steal()" style="position:fixed">a</p>`,
+			want:     "<p>a</p>",
+		},
+		{
+			name:     "javascript link dropped",
+			fragment: `<a href="javascript:alert(1)">x</a>`,
+			want:     `<a rel="nofollow noopener">x</a>`,
+		},
+		{
+			name:     "javascript link in mixed case",
+			fragment: `<A HREF="JaVaScRiPt:alert(1)">x</A>`,
+			want:     `<a rel="nofollow noopener">x</a>`,
+		},
+		{
+			name:     "javascript link behind entities",
+			fragment: `<a href="&#106;avascript:alert(1)">x</a>`,
+			want:     `<a rel="nofollow noopener">x</a>`,
+		},
+		{
+			name:     "javascript link behind whitespace",
+			fragment: `<a href=" java	script:alert(1)">x</a>`,
+			want:     `<a rel="nofollow noopener">x</a>`,
+		},
+		{
+			name:     "relative link resolved",
+			fragment: `<a href="/story">x</a>`,
+			base:     base,
+			want:     `<a href="https://example.com/story" rel="nofollow noopener">x</a>`,
+		},
+		{
+			name:     "relative link without a base dropped",
+			fragment: `<a href="/story">x</a>`,
+			want:     `<a rel="nofollow noopener">x</a>`,
+		},
+		{
+			name:     "image kept",
+			fragment: `<img src="https://cdn.example.com/a.jpg" alt="A" onerror="steal()">`,
+			want:     `<img src="https://cdn.example.com/a.jpg" alt="A">`,
+		},
+		{
+			name:     "data image dropped",
+			fragment: `<img src="data:image/png;base64,AAAA">`,
+			want:     "",
+		},
+		{
+			name:     "text escaped",
+			fragment: "a < b & c",
+			want:     "a &lt; b &amp; c",
+		},
+		{
+			name:     "attribute values escaped",
+			fragment: `<a title="&quot;><script>alert(1)</script>">x</a>`,
+			want:     `<a title="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;" rel="nofollow noopener">x</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.fragment, tt.base); got != tt.want {
+				t.Errorf("HTML(%q) = %q, want %q", tt.fragment, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package sanitize
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ellipsis ends truncated text.
+const ellipsis = "…"
+
+// inlineTags are the elements that may sit within a word.
+var inlineTags = map[atom.Atom]bool{
+	atom.A:      true,
+	atom.Abbr:   true,
+	atom.B:      true,
+	atom.Code:   true,
+	atom.Del:    true,
+	atom.Em:     true,
+	atom.Font:   true,
+	atom.I:      true,
+	atom.Ins:    true,
+	atom.Mark:   true,
+	atom.Q:      true,
+	atom.S:      true,
+	atom.Small:  true,
+	atom.Span:   true,
+	atom.Strong: true,
+	atom.Sub:    true,
+	atom.Sup:    true,
+	atom.Time:   true,
+	atom.U:      true,
+}
+
+// Text reduces a fragment that may carry HTML to plain text: tags are
+// stripped, scripts and the like dropped with their content, entities
+// decoded, invisible characters removed and whitespace collapsed to single
+// spaces.
+func Text(fragment string) string {
+	if !strings.ContainsAny(fragment, "<&") {
+		return collapseSpace(fragment)
+	}
+
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+
+	dropped := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				// what could not be tokenized is kept as it was
+				b.Write(z.Raw())
+			}
+			return collapseSpace(b.String())
+		}
+
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[tok.DataAtom] {
+				if tt == html.StartTagToken && !isVoid(tok.DataAtom) {
+					dropped++
+				}
+				continue
+			}
+			if !inlineTags[tok.DataAtom] {
+				// block elements separate words, e.g. <br> or </p><p>
+				b.WriteByte(' ')
+			}
+		case html.EndTagToken:
+			if droppedTags[tok.DataAtom] && dropped > 0 {
+				dropped--
+			}
+			if !inlineTags[tok.DataAtom] {
+				b.WriteByte(' ')
+			}
+		case html.TextToken:
+			if dropped == 0 {
+				b.WriteString(tok.Data)
+			}
+		}
+	}
+}
+
+// collapseSpace drops control and zero-width characters and turns every
+// run of whitespace into a single space, trimming both ends.
+func collapseSpace(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\u200b', r == '\u200c', r == '\u200d', r == '\ufeff', r == utf8.RuneError:
+			return -1
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Truncate shortens the text to at most max runes, ellipsis included. The
+// text is cut at the last word boundary that keeps at least half of it, or
+// mid-word when a single word is that long.
+func Truncate(s string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+
+	runes := []rune(s)
+	cut := max - utf8.RuneCountInString(ellipsis)
+	if cut <= 0 {
+		return string(runes[:max])
+	}
+
+	for i := cut; i > cut/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+
+	kept := strings.TrimRightFunc(string(runes[:cut]), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+	return kept + ellipsis
+}
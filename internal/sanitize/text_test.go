@@ -0,0 +1,67 @@
+package sanitize
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{name: "plain", fragment: "  Storm   hits\ncoast ", want: "Storm hits coast"},
+		{name: "tags stripped", fragment: "<p>Storm <b>hits</b></p><p>coast</p>", want: "Storm hits coast"},
+		{name: "inline tag within a word", fragment: "Sto<b>rm</b> hits", want: "Storm hits"},
+		{name: "block tag between words", fragment: "Storm<br>hits", want: "Storm hits"},
+		{name: "entities decoded", fragment: "Fish &amp; chips &#8211; &quot;cheap&quot;", want: "Fish & chips – \"cheap\""},
+		{name: "script dropped with its content", fragment: "<script>alert(1)</script>Storm", want: "Storm"},
+		{name: "style dropped with its content", fragment: "<style>p { color: red }</style>Storm", want: "Storm"},
+		{name: "nested dropped elements", fragment: "<svg><script>x</script><text>y</text></svg>Storm", want: "Storm"},
+		{name: "zero-width characters", fragment: "Sto\u200brm\ufeff hits", want: "Storm hits"},
+		{name: "control characters", fragment: "Storm\x00 hits\x1b", want: "Storm hits"},
+		{name: "lone ampersand", fragment: "Q&A", want: "Q&A"},
+		{name: "less-than sign", fragment: "1 < 2", want: "1 < 2"},
+		{name: "empty", fragment: " \n\t ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.fragment); got != tt.want {
+				t.Errorf("Text(%q) = %q, want %q", tt.fragment, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want string
+	}{
+		{name: "short", text: "Storm", max: 10, want: "Storm"},
+		{name: "exact length", text: "Storm", max: 5, want: "Storm"},
+		{name: "word boundary", text: "Storm hits the coast", max: 12, want: "Storm hits…"},
+		{name: "mid-word", text: "Supercalifragilistic", max: 8, want: "Superca…"},
+		{name: "punctuation trimmed", text: "Storm, hits coast", max: 9, want: "Storm…"},
+		{name: "counted in runes", text: "Привет мир и всё", max: 9, want: "Привет…"},
+		{name: "no room for the ellipsis", text: "Storm", max: 1, want: "S"},
+		{name: "zero", text: "Storm", max: 0, want: ""},
+		{name: "negative", text: "Storm", max: -1, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.text, tt.max)
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+			}
+			if n := utf8.RuneCountInString(got); tt.max > 0 && n > tt.max {
+				t.Errorf("Truncate(%q, %d) is %d runes long", tt.text, tt.max, n)
+			}
+		})
+	}
+}
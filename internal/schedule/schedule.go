@@ -0,0 +1,193 @@
+// Package schedule decides when a feed is next due for a crawl, from how
+// often it publishes and the hints it gives.
+package schedule
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	DefaultMinInterval     = time.Minute
+	DefaultMaxInterval     = 6 * time.Hour
+	DefaultInitialInterval = 15 * time.Minute
+
+	// how many of the most recent items the publish frequency is taken from
+	observedItems = 10
+
+	notModifiedBackoff = 1.5
+	failedBackoff      = 2
+)
+
+type Outcome int
+
+const (
+	// Fetched is a crawl that returned the feed's items.
+	Fetched Outcome = iota
+	// NotModified is a crawl the server answered with 304 Not Modified.
+	NotModified
+	// Failed is a crawl that did not produce a feed.
+	Failed
+)
+
+// Policy bounds the intervals feeds are polled at.
+type Policy struct {
+	Min time.Duration
+	Max time.Duration
+	// Initial is the interval of a feed whose frequency is not known yet.
+	Initial time.Duration
+}
+
+var DefaultPolicy = Policy{
+	Min:     DefaultMinInterval,
+	Max:     DefaultMaxInterval,
+	Initial: DefaultInitialInterval,
+}
+
+// Input is what is known about a feed after crawling it.
+type Input struct {
+	Outcome Outcome
+	// Fixed is an interval set for the feed by hand, overriding the adaptive one.
+	Fixed time.Duration
+	// Previous is the interval the feed was last scheduled at.
+	Previous time.Duration
+	// PublishedAt holds the publish times of the feed's items.
+	PublishedAt []time.Time
+
+	// RSS hints: the feed may be cached for TTL, and is not updated during
+	// the SkipHours (0-23, GMT) and SkipDays.
+	TTL       time.Duration
+	SkipHours []int
+	SkipDays  []time.Weekday
+}
+
+// Next returns when the feed is next due and the interval it was scheduled at.
+//
+// A fetched feed is polled about as often as it has been publishing, and less
+// often the longer it has been since its last item. Feeds that did not change
+// or failed back off from their previous interval. The interval never drops
+// below the feed's TTL and is kept within the policy's bounds; the due time is
+// then moved out of the hours and days the feed asks to be skipped.
+func (p Policy) Next(now time.Time, in Input) (time.Time, time.Duration) {
+	p = p.withDefaults()
+
+	if in.Fixed > 0 {
+		return now.Add(in.Fixed), in.Fixed
+	}
+
+	previous := in.Previous
+	if previous <= 0 {
+		previous = p.Initial
+	}
+
+	var interval time.Duration
+	switch in.Outcome {
+	case Fetched:
+		interval = publishInterval(now, in.PublishedAt, p.Initial)
+	case NotModified:
+		interval = time.Duration(float64(previous) * notModifiedBackoff)
+	default:
+		interval = previous * failedBackoff
+	}
+
+	if interval < in.TTL {
+		interval = in.TTL
+	}
+	if interval < p.Min {
+		interval = p.Min
+	}
+	if interval > p.Max {
+		interval = p.Max
+	}
+
+	next := skip(now.Add(interval), in.SkipHours, in.SkipDays)
+	if latest := now.Add(p.Max); next.After(latest) {
+		next = latest
+	}
+	return next, interval
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.Min <= 0 {
+		p.Min = DefaultMinInterval
+	}
+	if p.Max < p.Min {
+		p.Max = DefaultMaxInterval
+		if p.Max < p.Min {
+			p.Max = p.Min
+		}
+	}
+	if p.Initial <= 0 {
+		p.Initial = DefaultInitialInterval
+	}
+	return p
+}
+
+// publishInterval is the median gap between the most recent items, raised
+// to half the time since the latest item so that feeds gone quiet are polled
+// less and less.
+func publishInterval(now time.Time, publishedAt []time.Time, fallback time.Duration) time.Duration {
+	var times []time.Time
+	for _, t := range publishedAt {
+		if !t.IsZero() && !t.After(now) {
+			times = append(times, t)
+		}
+	}
+	if len(times) == 0 {
+		return fallback
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].After(times[j]) })
+	if len(times) > observedItems {
+		times = times[:observedItems]
+	}
+
+	var gaps []time.Duration
+	for i := 1; i < len(times); i++ {
+		if gap := times[i-1].Sub(times[i]); gap > 0 {
+			gaps = append(gaps, gap)
+		}
+	}
+
+	interval := fallback
+	if len(gaps) > 0 {
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+		interval = gaps[len(gaps)/2]
+	}
+
+	if quiet := now.Sub(times[0]) / 2; quiet > interval {
+		interval = quiet
+	}
+	return interval
+}
+
+// skip moves t forward to the start of the first hour that is neither one of
+// the skipped hours nor on one of the skipped days, in GMT.
+func skip(t time.Time, hours []int, days []time.Weekday) time.Time {
+	if len(hours) == 0 && len(days) == 0 {
+		return t
+	}
+
+	skipHour := make(map[int]bool, len(hours))
+	for _, h := range hours {
+		skipHour[h] = true
+	}
+	skipDay := make(map[time.Weekday]bool, len(days))
+	for _, d := range days {
+		skipDay[d] = true
+	}
+
+	utc := t.UTC()
+	// a week covers every combination of hour and day
+	for i := 0; i < 7*24; i++ {
+		if !skipHour[utc.Hour()] && !skipDay[utc.Weekday()] {
+			if i == 0 {
+				return t
+			}
+			return utc.Truncate(time.Hour).In(t.Location())
+		}
+		utc = utc.Truncate(time.Hour).Add(time.Hour)
+	}
+	// everything is skipped, which leaves nothing to honor
+	return t
+}
@@ -0,0 +1,179 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPolicyNext(t *testing.T) {
+	// a Tuesday
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+	allHours := make([]int, 24)
+	for h := range allHours {
+		allHours[h] = h
+	}
+
+	tests := []struct {
+		name         string
+		in           Input
+		wantInterval time.Duration
+		wantNext     time.Time
+	}{
+		{
+			name:         "fixed interval",
+			in:           Input{Fixed: 30 * time.Minute, Previous: time.Hour, TTL: 2 * time.Hour},
+			wantInterval: 30 * time.Minute,
+		},
+		{
+			name:         "frequency not known yet",
+			in:           Input{Outcome: Fetched},
+			wantInterval: DefaultInitialInterval,
+		},
+		{
+			name:         "median gap between items",
+			in:           Input{Outcome: Fetched, PublishedAt: []time.Time{ago(10 * time.Minute), ago(70 * time.Minute), ago(100 * time.Minute), ago(190 * time.Minute)}},
+			wantInterval: time.Hour,
+		},
+		{
+			name:         "gone quiet",
+			in:           Input{Outcome: Fetched, PublishedAt: []time.Time{ago(4 * time.Hour), ago(5 * time.Hour), ago(6 * time.Hour)}},
+			wantInterval: 2 * time.Hour,
+		},
+		{
+			name:         "quiet for days",
+			in:           Input{Outcome: Fetched, PublishedAt: []time.Time{ago(48 * time.Hour), ago(49 * time.Hour)}},
+			wantInterval: DefaultMaxInterval,
+		},
+		{
+			name:         "publishing faster than the minimum",
+			in:           Input{Outcome: Fetched, PublishedAt: []time.Time{ago(10 * time.Second), ago(20 * time.Second), ago(30 * time.Second)}},
+			wantInterval: DefaultMinInterval,
+		},
+		{
+			name:         "future and undated items ignored",
+			in:           Input{Outcome: Fetched, PublishedAt: []time.Time{now.Add(time.Hour), {}, ago(30 * time.Minute), ago(90 * time.Minute)}},
+			wantInterval: time.Hour,
+		},
+		{
+			name:         "not modified backs off",
+			in:           Input{Outcome: NotModified, Previous: 20 * time.Minute},
+			wantInterval: 30 * time.Minute,
+		},
+		{
+			name:         "not modified without a previous interval",
+			in:           Input{Outcome: NotModified},
+			wantInterval: DefaultInitialInterval * 3 / 2,
+		},
+		{
+			name:         "failed backs off",
+			in:           Input{Outcome: Failed, Previous: 20 * time.Minute},
+			wantInterval: 40 * time.Minute,
+		},
+		{
+			name:         "failed backs off up to the maximum",
+			in:           Input{Outcome: Failed, Previous: 4 * time.Hour},
+			wantInterval: DefaultMaxInterval,
+		},
+		{
+			name:         "ttl raises the interval",
+			in:           Input{Outcome: Fetched, TTL: time.Hour},
+			wantInterval: time.Hour,
+		},
+		{
+			name:         "ttl above the maximum",
+			in:           Input{Outcome: Fetched, TTL: 24 * time.Hour},
+			wantInterval: DefaultMaxInterval,
+		},
+		{
+			name:         "skipped hours",
+			in:           Input{Outcome: Fetched, SkipHours: []int{10, 11}},
+			wantInterval: DefaultInitialInterval,
+			wantNext:     time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "skipped hours after the ttl",
+			in:           Input{Outcome: Fetched, TTL: 3 * time.Hour, SkipHours: []int{13, 14}},
+			wantInterval: 3 * time.Hour,
+			wantNext:     time.Date(2021, 6, 1, 15, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "skipping never delays past the maximum",
+			in:           Input{Outcome: Fetched, SkipDays: []time.Weekday{time.Tuesday}},
+			wantInterval: DefaultInitialInterval,
+			wantNext:     now.Add(DefaultMaxInterval),
+		},
+		{
+			name:         "every hour skipped",
+			in:           Input{Outcome: Fetched, SkipHours: allHours},
+			wantInterval: DefaultInitialInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, interval := DefaultPolicy.Next(now, tt.in)
+			if interval != tt.wantInterval {
+				t.Errorf("interval = %v, want %v", interval, tt.wantInterval)
+			}
+			wantNext := tt.wantNext
+			if wantNext.IsZero() {
+				wantNext = now.Add(tt.wantInterval)
+			}
+			if !next.Equal(wantNext) {
+				t.Errorf("next = %v, want %v", next, wantNext)
+			}
+		})
+	}
+}
+
+func TestSkip(t *testing.T) {
+	// a Tuesday
+	at := time.Date(2021, 6, 1, 22, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		hours []int
+		days  []time.Weekday
+		want  time.Time
+	}{
+		{name: "nothing skipped", want: at},
+		{name: "other hours skipped", hours: []int{1, 2}, want: at},
+		{name: "skipped hour", hours: []int{22}, want: time.Date(2021, 6, 1, 23, 0, 0, 0, time.UTC)},
+		{name: "skipped hours into the next day", hours: []int{22, 23, 0}, want: time.Date(2021, 6, 2, 1, 0, 0, 0, time.UTC)},
+		{name: "skipped day", days: []time.Weekday{time.Tuesday}, want: time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "skipped days and hours", hours: []int{0, 1}, days: []time.Weekday{time.Tuesday, time.Wednesday}, want: time.Date(2021, 6, 3, 2, 0, 0, 0, time.UTC)},
+		{name: "every day skipped", days: []time.Weekday{0, 1, 2, 3, 4, 5, 6}, want: at},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := skip(at, tt.hours, tt.days); !got.Equal(tt.want) {
+				t.Errorf("skip = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyDefaults(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		policy Policy
+		in     Input
+		want   time.Duration
+	}{
+		{name: "zero policy", policy: Policy{}, in: Input{Outcome: Fetched}, want: DefaultInitialInterval},
+		{name: "maximum below minimum", policy: Policy{Min: 10 * time.Hour, Max: time.Hour}, in: Input{Outcome: Failed, Previous: 24 * time.Hour}, want: 10 * time.Hour},
+		{name: "custom bounds", policy: Policy{Min: time.Minute, Max: time.Hour, Initial: 5 * time.Minute}, in: Input{Outcome: Failed, Previous: 45 * time.Minute}, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := tt.policy.Next(now, tt.in); got != tt.want {
+				t.Errorf("interval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
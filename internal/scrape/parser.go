@@ -0,0 +1,171 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/jonboulle/clockwork"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/fetch"
+)
+
+// pageAccept is the Accept header listing pages are requested with.
+const pageAccept = "text/html, application/xhtml+xml;q=0.9, */*;q=0.5"
+
+type Parser struct {
+	client  fetch.Doer
+	maxSize int64
+	clock   clockwork.Clock
+}
+
+type Option func(*Parser)
+
+// WithClock sets the clock the publication date of undated items is read from.
+func WithClock(clock clockwork.Clock) Option {
+	return func(p *Parser) {
+		p.clock = clock
+	}
+}
+
+// WithMaxSize caps the size of the pages fetched, once decompressed.
+func WithMaxSize(maxSize int64) Option {
+	return func(p *Parser) {
+		if maxSize > 0 {
+			p.maxSize = maxSize
+		}
+	}
+}
+
+func NewParser(client fetch.Doer, opts ...Option) *Parser {
+	p := &Parser{
+		client:  client,
+		maxSize: fetch.DefaultMaxDocumentSize,
+		clock:   clockwork.NewRealClock(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Parse fetches the listing page of the source and maps the items its
+// scrape rules find into the articles of a feed. Warnings about selectors
+// that stopped matching are set on the feed; when the item selector matches
+// nothing at all, or no item has a link, ErrNoItems is returned instead.
+// Items without a date are published at the time of the crawl, as undated
+// feed items are. Validators, gone and moved sources are handled as they are
+// for feeds.
+func (p *Parser) Parse(ctx context.Context, source *domain.Source) (*domain.Feed, error) {
+	if source.ScrapeRules == nil {
+		return nil, fmt.Errorf("%w: missing scrape rules", domain.ErrInvalidSource)
+	}
+
+	doc, err := fetch.Get(ctx, p.client, fetch.Request{
+		URL:          source.URL,
+		ETag:         source.ETag,
+		LastModified: source.LastModified,
+		Auth:         source.Auth,
+		Accept:       pageAccept,
+		MaxSize:      p.maxSize,
+	})
+	if err != nil {
+		return nil, fetch.SourceError(err)
+	}
+
+	feed, err := p.scrape(doc, source.ScrapeRules)
+	if err != nil {
+		return nil, doc.SourceError(err)
+	}
+	if len(feed.Articles) == 0 {
+		return nil, doc.SourceError(fmt.Errorf("%w: %s", ErrNoItems, strings.Join(feed.Warnings, "; ")))
+	}
+
+	now := p.clock.Now()
+	for _, a := range feed.Articles {
+		if a.PublishedAt.IsZero() {
+			a.PublishedAt = now
+		}
+	}
+
+	feed.FeedLink = source.URL
+	feed.Category = source.Category
+	feed.Provider = source.Provider
+	feed.ETag = doc.Header.Get("ETag")
+	feed.LastModified = doc.Header.Get("Last-Modified")
+	if doc.MovedTo != "" {
+		feed.MovedTo = doc.MovedTo
+		feed.FeedLink = doc.MovedTo
+	}
+
+	return feed, nil
+}
+
+// Preview fetches the page and scrapes it with the rules, for tuning them
+// before they are saved on a source.
+func (p *Parser) Preview(ctx context.Context, pageURL string, rules *domain.ScrapeRules) (*domain.ScrapePreview, error) {
+	doc, err := fetch.Get(ctx, p.client, fetch.Request{
+		URL:     pageURL,
+		Accept:  pageAccept,
+		MaxSize: p.maxSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := p.scrape(doc, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ScrapePreview{
+		Title:    feed.Title,
+		Articles: feed.Articles,
+		Warnings: feed.Warnings,
+	}, nil
+}
+
+// Validate checks the rules, see the package level Validate.
+func (p *Parser) Validate(rules *domain.ScrapeRules) error {
+	return Validate(rules)
+}
+
+func (p *Parser) scrape(doc *fetch.Document, rules *domain.ScrapeRules) (*domain.Feed, error) {
+	if err := Validate(rules); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSource, err)
+	}
+
+	base, err := url.Parse(doc.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing page url: %w", err)
+	}
+
+	body, err := doc.UTF8()
+	if err != nil {
+		return nil, err
+	}
+	page, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing page: %w", err)
+	}
+
+	// a <base href> changes what the links of the page are relative to
+	if href, ok := page.Find("base[href]").First().Attr("href"); ok {
+		if u, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			base = base.ResolveReference(u)
+		}
+	}
+
+	feed := &domain.Feed{
+		Title:    strings.Join(strings.Fields(page.Find("title").First().Text()), " "),
+		Link:     doc.URL,
+		Language: strings.ToLower(strings.TrimSpace(page.Find("html").AttrOr("lang", ""))),
+	}
+	feed.Articles, feed.Warnings = Extract(page, base, rules)
+
+	return feed, nil
+}
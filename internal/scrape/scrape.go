@@ -0,0 +1,238 @@
+// Package scrape extracts the items of listing pages with CSS selectors, for
+// the publishers with neither feeds nor sitemaps.
+package scrape
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/media"
+)
+
+var ErrNoItems = errors.New("no items found")
+
+// dateLayouts are tried on the dates of items when the rules give no layout.
+var dateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02/01/2006",
+	"2 January 2006",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 Jan 2006",
+}
+
+// Validate checks that the rules have an item selector and that all of their
+// selectors are valid CSS.
+func Validate(rules *domain.ScrapeRules) error {
+	if rules == nil || strings.TrimSpace(rules.Item) == "" {
+		return errors.New("missing item selector")
+	}
+
+	for _, sel := range []struct{ name, value string }{
+		{"item", rules.Item},
+		{"title", rules.Title},
+		{"link", rules.Link},
+		{"summary", rules.Summary},
+		{"image", rules.Image},
+		{"date", rules.Date},
+	} {
+		if sel.value == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(sel.value); err != nil {
+			return fmt.Errorf("invalid %s selector %q: %v", sel.name, sel.value, err)
+		}
+	}
+	return nil
+}
+
+// Extract reads the items of the page with the rules, resolving links against
+// base. Items without a link are left out. Warnings are returned for the
+// selectors that matched only some of the items, or none, and for dates that
+// could not be parsed, which usually means the page changed under the rules.
+func Extract(page *goquery.Document, base *url.URL, rules *domain.ScrapeRules) ([]*domain.Article, []string) {
+	items := page.Find(rules.Item)
+	if items.Length() == 0 {
+		return nil, []string{fmt.Sprintf("item selector %q matched nothing", rules.Item)}
+	}
+
+	var (
+		articles []*domain.Article
+		misses   = make(map[string]int)
+		badDates int
+		seen     = make(map[string]bool)
+	)
+	items.Each(func(_ int, item *goquery.Selection) {
+		link, ok := itemLink(item, rules.Link, base)
+		if !ok {
+			misses["link"]++
+			return
+		}
+		if seen[link] {
+			return
+		}
+		seen[link] = true
+
+		article := &domain.Article{
+			Link: link,
+			GUID: link,
+		}
+
+		if rules.Title != "" {
+			article.Title = text(item.Find(rules.Title))
+		} else {
+			article.Title = text(linkSelection(item, rules.Link))
+		}
+		if article.Title == "" {
+			misses["title"]++
+		}
+
+		if rules.Summary != "" {
+			if article.Description = text(item.Find(rules.Summary)); article.Description == "" {
+				misses["summary"]++
+			}
+		}
+
+		if rules.Image != "" {
+			src := imageURL(item.Find(rules.Image).First())
+			if src == "" {
+				misses["image"]++
+			}
+			article.Images = media.FromURLs(base.String(), domain.ImageOriginHTML, src)
+			article.SetThumbnail(media.Best(article.Images))
+		}
+
+		if rules.Date != "" {
+			raw := dateText(item.Find(rules.Date).First())
+			switch publishedAt, ok := parseDate(raw, rules.DateLayout); {
+			case raw == "":
+				misses["date"]++
+			case !ok:
+				badDates++
+			default:
+				article.PublishedAt = publishedAt
+			}
+		}
+
+		articles = append(articles, article)
+	})
+
+	var warnings []string
+	for _, field := range []struct{ name, selector string }{
+		{"link", rules.Link},
+		{"title", rules.Title},
+		{"summary", rules.Summary},
+		{"image", rules.Image},
+		{"date", rules.Date},
+	} {
+		n := misses[field.name]
+		switch {
+		case n == 0:
+		case field.selector == "":
+			warnings = append(warnings, fmt.Sprintf("no %s found in %d of %d items", field.name, n, items.Length()))
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s selector %q missed %d of %d items",
+				field.name, field.selector, n, items.Length()))
+		}
+	}
+	if badDates > 0 {
+		warnings = append(warnings, fmt.Sprintf("dates of %d of %d items could not be parsed with layout %q",
+			badDates, items.Length(), rules.DateLayout))
+	}
+
+	return articles, warnings
+}
+
+// linkSelection is the element the link of the item is read from: the match
+// of the selector, or else the item itself when it is a link, or its first link.
+func linkSelection(item *goquery.Selection, selector string) *goquery.Selection {
+	if selector != "" {
+		return item.Find(selector).First()
+	}
+	if item.Is("a[href]") {
+		return item
+	}
+	return item.Find("a[href]").First()
+}
+
+func itemLink(item *goquery.Selection, selector string, base *url.URL) (string, bool) {
+	sel := linkSelection(item, selector)
+	href, ok := sel.Attr("href")
+	if !ok {
+		// the selector may point at an element inside the link
+		href, ok = sel.Closest("a[href]").Attr("href")
+	}
+	if !ok {
+		return "", false
+	}
+
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+	u = base.ResolveReference(u)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	u.Fragment = ""
+	return u.String(), true
+}
+
+// imageURL reads the address of an image element, lazy loaded or not, or of
+// any element pointing at one.
+func imageURL(sel *goquery.Selection) string {
+	for _, attr := range []string{"src", "data-src", "data-lazy-src", "data-original", "content", "href"} {
+		if v, ok := sel.Attr(attr); ok && strings.TrimSpace(v) != "" && !strings.HasPrefix(v, "data:") {
+			return strings.TrimSpace(v)
+		}
+	}
+	if srcset, ok := sel.Attr("srcset"); ok {
+		if fields := strings.Fields(srcset); len(fields) > 0 {
+			return strings.TrimSuffix(fields[0], ",")
+		}
+	}
+	return ""
+}
+
+// dateText prefers the machine readable date of <time datetime> or of a
+// content attribute over the text of the element.
+func dateText(sel *goquery.Selection) string {
+	for _, attr := range []string{"datetime", "content"} {
+		if v, ok := sel.Attr(attr); ok && strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return text(sel)
+}
+
+func parseDate(raw, layout string) (time.Time, bool) {
+	if layout != "" {
+		t, err := time.Parse(layout, raw)
+		return t, err == nil
+	}
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l, raw); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func text(sel *goquery.Selection) string {
+	return strings.Join(strings.Fields(sel.First().Text()), " ")
+}
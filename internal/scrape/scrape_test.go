@@ -0,0 +1,239 @@
+package scrape
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/jonboulle/clockwork"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+const listing = `<!DOCTYPE html>
+<html lang="en">
+<head><title>Latest news</title></head>
+<body>
+  <article class="story">
+    <h2><a href="/news/storm#top"> Storm   hits coast </a></h2>
+    <p class="summary">High winds overnight.</p>
+    <img data-src="/img/storm.jpg" src="data:image/gif;base64,R0lGOD">
+    <time datetime="2021-06-01T10:00:00Z">1 June</time>
+  </article>
+  <article class="story">
+    <h2><a href="https://example.com/news/budget">Budget vote delayed</a></h2>
+    <img srcset="https://cdn.example.com/budget-320.jpg 320w, https://cdn.example.com/budget-640.jpg 640w">
+    <span class="date">2 June 2021</span>
+  </article>
+  <article class="story">
+    <h2><a href="javascript:alert(1)">Not a story</a></h2>
+  </article>
+  <article class="story">
+    <h2>No link at all</h2>
+  </article>
+  <article class="story">
+    <h2><a href="/news/storm">Storm hits coast again</a></h2>
+  </article>
+</body>
+</html>`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   *domain.ScrapeRules
+		wantErr bool
+	}{
+		{name: "item only", rules: &domain.ScrapeRules{Item: "article"}},
+		{name: "every selector", rules: &domain.ScrapeRules{Item: "article", Title: "h2", Link: "h2 a", Summary: "p", Image: "img", Date: "time"}},
+		{name: "no rules", rules: nil, wantErr: true},
+		{name: "no item selector", rules: &domain.ScrapeRules{Title: "h2"}, wantErr: true},
+		{name: "blank item selector", rules: &domain.ScrapeRules{Item: "  "}, wantErr: true},
+		{name: "invalid item selector", rules: &domain.ScrapeRules{Item: "article["}, wantErr: true},
+		{name: "invalid field selector", rules: &domain.ScrapeRules{Item: "article", Date: ":nope("}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.rules); (err != nil) != tt.wantErr {
+				t.Errorf("Validate error = %v, want an error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	base, _ := url.Parse("https://example.com/latest")
+
+	tests := []struct {
+		name     string
+		rules    *domain.ScrapeRules
+		links    []string
+		titles   []string
+		warnings []string
+	}{
+		{
+			name:   "links and titles from the first link",
+			rules:  &domain.ScrapeRules{Item: "article.story"},
+			links:  []string{"https://example.com/news/storm", "https://example.com/news/budget"},
+			titles: []string{"Storm hits coast", "Budget vote delayed"},
+			warnings: []string{
+				"no link found in 2 of 5 items",
+			},
+		},
+		{
+			name:   "every field",
+			rules:  &domain.ScrapeRules{Item: "article.story", Title: "h2", Link: "h2 a", Summary: ".summary", Image: "img", Date: "time, .date"},
+			links:  []string{"https://example.com/news/storm", "https://example.com/news/budget"},
+			titles: []string{"Storm hits coast", "Budget vote delayed"},
+			warnings: []string{
+				`link selector "h2 a" missed 2 of 5 items`,
+				`summary selector ".summary" missed 1 of 5 items`,
+			},
+		},
+		{
+			name:     "item selector matching nothing",
+			rules:    &domain.ScrapeRules{Item: "li.story"},
+			warnings: []string{`item selector "li.story" matched nothing`},
+		},
+		{
+			name:   "date layout not matching",
+			rules:  &domain.ScrapeRules{Item: "article.story", Date: "time", DateLayout: "2006"},
+			links:  []string{"https://example.com/news/storm", "https://example.com/news/budget"},
+			titles: []string{"Storm hits coast", "Budget vote delayed"},
+			warnings: []string{
+				"no link found in 2 of 5 items",
+				`date selector "time" missed 1 of 5 items`,
+				`dates of 1 of 5 items could not be parsed with layout "2006"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := goquery.NewDocumentFromReader(strings.NewReader(listing))
+			if err != nil {
+				t.Fatal(err)
+			}
+			articles, warnings := Extract(page, base, tt.rules)
+
+			var links, titles []string
+			for _, a := range articles {
+				links = append(links, a.Link)
+				titles = append(titles, a.Title)
+				if a.GUID != a.Link {
+					t.Errorf("guid %q, want the link %q", a.GUID, a.Link)
+				}
+			}
+			if !reflect.DeepEqual(links, tt.links) {
+				t.Errorf("links = %v, want %v", links, tt.links)
+			}
+			if !reflect.DeepEqual(titles, tt.titles) {
+				t.Errorf("titles = %v, want %v", titles, tt.titles)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestExtractFields(t *testing.T) {
+	base, _ := url.Parse("https://example.com/latest")
+	page, err := goquery.NewDocumentFromReader(strings.NewReader(listing))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	articles, _ := Extract(page, base, &domain.ScrapeRules{Item: "article.story", Summary: ".summary", Image: "img", Date: "time, .date"})
+	if len(articles) != 2 {
+		t.Fatalf("got %d articles, want 2", len(articles))
+	}
+	storm, budget := articles[0], articles[1]
+
+	if storm.Description != "High winds overnight." {
+		t.Errorf("description = %q", storm.Description)
+	}
+	if storm.ThumbnailURL != "https://example.com/img/storm.jpg" {
+		t.Errorf("lazy loaded thumbnail = %q", storm.ThumbnailURL)
+	}
+	if budget.ThumbnailURL != "https://cdn.example.com/budget-320.jpg" {
+		t.Errorf("srcset thumbnail = %q", budget.ThumbnailURL)
+	}
+	if want := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC); !storm.PublishedAt.Equal(want) {
+		t.Errorf("datetime attribute = %v, want %v", storm.PublishedAt, want)
+	}
+	if want := time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC); !budget.PublishedAt.Equal(want) {
+		t.Errorf("date text = %v, want %v", budget.PublishedAt, want)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		raw    string
+		layout string
+		want   time.Time
+		ok     bool
+	}{
+		{raw: "2021-06-01T10:00:00Z", want: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC), ok: true},
+		{raw: "Tue, 01 Jun 2021 10:00:00 +0000", want: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC), ok: true},
+		{raw: "2021-06-01 10:00", want: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC), ok: true},
+		{raw: "01/06/2021", want: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "June 1, 2021", want: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "1 Jun 2021", want: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "1.6.2021", layout: "2.1.2006", want: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{raw: "2021-06-01", layout: "2.1.2006"},
+		{raw: "yesterday"},
+		{raw: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := parseDate(tt.raw, tt.layout)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("parseDate(%q, %q) = %v, %v, want %v, %v", tt.raw, tt.layout, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// pageDoer serves the listing page for every request.
+type pageDoer struct{}
+
+func (pageDoer) Do(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:       io.NopCloser(strings.NewReader(listing)),
+		Request:    req,
+	}, nil
+}
+
+func TestParseUndated(t *testing.T) {
+	crawledAt := time.Date(2021, 6, 3, 12, 0, 0, 0, time.UTC)
+	source := &domain.Source{
+		URL:         "https://example.com/news",
+		ScrapeRules: &domain.ScrapeRules{Item: "article.story", Title: "h2", Date: "time"},
+	}
+
+	feed, err := NewParser(pageDoer{}, WithClock(clockwork.NewFakeClockAt(crawledAt))).Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Articles) != 2 {
+		t.Fatalf("got %d articles, want 2", len(feed.Articles))
+	}
+	if got := feed.Articles[0].PublishedAt; !got.Equal(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("dated item published at %v", got)
+	}
+	for _, a := range feed.Articles[1:] {
+		if !a.PublishedAt.Equal(crawledAt) {
+			t.Errorf("%s published at %v, want the crawl time %v", a.Link, a.PublishedAt, crawledAt)
+		}
+	}
+}
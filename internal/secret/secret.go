@@ -0,0 +1,109 @@
+// Package secret encrypts the secrets kept in the database, such as the
+// credentials of sources, so that a dump of the database does not give them
+// away without the key.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeySize is the size of the keys, for AES-256.
+const KeySize = 32
+
+// Versions prefix sealed secrets, for the day the scheme or key changes.
+const (
+	// versionUnbound secrets were sealed without additional data, and are
+	// only opened for the ones stored before versionBound.
+	versionUnbound byte = 1
+	// versionBound secrets are bound to additional data, such as the id of
+	// the row they are stored in, so that they cannot be moved to another.
+	versionBound byte = 2
+)
+
+var (
+	ErrKeySize   = fmt.Errorf("secret key must be %d bytes", KeySize)
+	ErrMalformed = errors.New("malformed secret")
+)
+
+// Box seals and opens secrets with AES-256-GCM.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates a box sealing with the key.
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, ErrKeySize
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// NewFromBase64 creates a box sealing with the base64 encoded key, as
+// generated by `openssl rand -base64 32`.
+func NewFromBase64(encoded string) (*Box, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %w", err)
+	}
+	return New(key)
+}
+
+// Seal encrypts the plaintext under a random nonce, bound to the additional
+// data it must be opened with.
+func (b *Box) Seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := make([]byte, 0, 1+len(nonce)+len(plaintext)+b.aead.Overhead())
+	sealed = append(sealed, versionBound)
+	sealed = append(sealed, nonce...)
+	return b.aead.Seal(sealed, nonce, plaintext, associated(versionBound, additionalData)), nil
+}
+
+// Open decrypts what Seal encrypted, failing when it was sealed with another
+// key or additional data, or tampered with. Secrets sealed before they were
+// bound to additional data are opened regardless of it.
+func (b *Box) Open(sealed, additionalData []byte) ([]byte, error) {
+	n := b.aead.NonceSize()
+	if len(sealed) < 1+n+b.aead.Overhead() {
+		return nil, ErrMalformed
+	}
+
+	v := sealed[0]
+	switch v {
+	case versionBound:
+	case versionUnbound:
+		additionalData = nil
+	default:
+		return nil, ErrMalformed
+	}
+
+	plaintext, err := b.aead.Open(nil, sealed[1:1+n], sealed[1+n:], associated(v, additionalData))
+	if err != nil {
+		return nil, fmt.Errorf("error opening secret: %w", err)
+	}
+	return plaintext, nil
+}
+
+// associated is what a secret of the version is authenticated with along
+// with its ciphertext.
+func associated(v byte, additionalData []byte) []byte {
+	return append([]byte{v}, additionalData...)
+}
@@ -0,0 +1,161 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newKey(t *testing.T) []byte {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newBox(t *testing.T, key []byte) *Box {
+	box, err := New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return box
+}
+
+func TestSealOpen(t *testing.T) {
+	box := newBox(t, newKey(t))
+	plaintext := []byte(`{"type":"bearer","secret":"token"}`)
+	sourceID := []byte("source-1")
+
+	sealed, err := box.Seal(plaintext, sourceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatalf("sealed secret contains the plaintext")
+	}
+
+	again, err := box.Seal(plaintext, sourceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sealed, again) {
+		t.Errorf("sealing twice gave the same secret")
+	}
+
+	opened, err := box.Open(sealed, sourceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open = %q, want %q", opened, plaintext)
+	}
+}
+
+func TestOpenFailures(t *testing.T) {
+	key := newKey(t)
+	box := newBox(t, key)
+	sourceID := []byte("source-1")
+	sealed, err := box.Seal([]byte("secret"), sourceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamper := func(i int, b byte) []byte {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= b
+		return tampered
+	}
+
+	tests := []struct {
+		name      string
+		box       *Box
+		sealed    []byte
+		sourceID  []byte
+		malformed bool
+	}{
+		{name: "wrong key", box: newBox(t, newKey(t)), sealed: sealed, sourceID: sourceID},
+		{name: "other source", box: box, sealed: sealed, sourceID: []byte("source-2")},
+		{name: "tampered ciphertext", box: box, sealed: tamper(len(sealed)-1, 0x01), sourceID: sourceID},
+		{name: "tampered nonce", box: box, sealed: tamper(1, 0x01), sourceID: sourceID},
+		{name: "unbound version", box: box, sealed: tamper(0, versionBound^versionUnbound), sourceID: sourceID},
+		{name: "unknown version", box: box, sealed: tamper(0, 0xff), sourceID: sourceID, malformed: true},
+		{name: "truncated", box: box, sealed: sealed[:10], sourceID: sourceID, malformed: true},
+		{name: "empty", box: box, sealed: nil, sourceID: sourceID, malformed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened, err := tt.box.Open(tt.sealed, tt.sourceID)
+			if err == nil {
+				t.Fatalf("Open = %q, want an error", opened)
+			}
+			if errors.Is(err, ErrMalformed) != tt.malformed {
+				t.Errorf("Open error = %v, malformed %v", err, tt.malformed)
+			}
+		})
+	}
+}
+
+func TestOpenUnbound(t *testing.T) {
+	box := newBox(t, newKey(t))
+
+	// as sealed before secrets were bound to the id of their source
+	nonce := make([]byte, box.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+	sealed := append([]byte{versionUnbound}, nonce...)
+	sealed = box.aead.Seal(sealed, nonce, []byte("secret"), []byte{versionUnbound})
+
+	opened, err := box.Open(sealed, []byte("source-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(opened) != "secret" {
+		t.Errorf("Open = %q, want %q", opened, "secret")
+	}
+}
+
+func TestNewFromBase64(t *testing.T) {
+	key := newKey(t)
+
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr error
+		fail    bool
+	}{
+		{name: "valid", encoded: base64.StdEncoding.EncodeToString(key)},
+		{name: "surrounding whitespace", encoded: " " + base64.StdEncoding.EncodeToString(key) + "\n"},
+		{name: "short key", encoded: base64.StdEncoding.EncodeToString(key[:16]), wantErr: ErrKeySize},
+		{name: "long key", encoded: base64.StdEncoding.EncodeToString(append(key, 0)), wantErr: ErrKeySize},
+		{name: "empty", encoded: "", wantErr: ErrKeySize},
+		{name: "not base64", encoded: "not a key!", fail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box, err := NewFromBase64(tt.encoded)
+			if wantFail := tt.fail || tt.wantErr != nil; (err != nil) != wantFail {
+				t.Fatalf("NewFromBase64 error = %v, want an error %v", err, wantFail)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewFromBase64 error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			sealed, err := box.Seal([]byte("secret"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := newBox(t, key).Open(sealed, nil); err != nil {
+				t.Errorf("secret sealed with the decoded key does not open with the key: %v", err)
+			}
+		})
+	}
+}
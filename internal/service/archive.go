@@ -0,0 +1,314 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	uuid "github.com/kevinburke/go.uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// payloads pruned per worker run, the rest wait for the next one
+	pruneBatchSize uint64 = 500
+	// payloads read from the store at once while reprocessing
+	reprocessBatchSize uint64 = 100
+)
+
+var errArchiveDisabled = errors.New("payload archival is not enabled")
+
+// Archive keeps the compressed bodies of archived payloads, on disk or as
+// Postgres large objects.
+type Archive interface {
+	PutPayloadBody(ctx context.Context, body []byte) (string, error)
+	GetPayloadBody(ctx context.Context, location string) ([]byte, error)
+	DeletePayloadBody(ctx context.Context, location string) error
+}
+
+type archiveConfig struct {
+	// bodies are the body stores of every storage available, storage the
+	// one new payloads are archived to
+	bodies    map[domain.PayloadStorage]Archive
+	storage   domain.PayloadStorage
+	retention time.Duration
+}
+
+// bodiesOf returns the body store of the payloads archived to the storage.
+func (c *archiveConfig) bodiesOf(storage domain.PayloadStorage) (Archive, error) {
+	bodies, ok := c.bodies[storage]
+	if !ok {
+		return nil, fmt.Errorf("%s storage is not available", storage)
+	}
+	return bodies, nil
+}
+
+// archivePayload keeps the document the feed of the result was parsed from.
+// Failing to do so is logged, the crawl goes on regardless.
+func (s *Service) archivePayload(ctx context.Context, result *domain.CrawlResult) {
+	if s.archive == nil || result.Feed == nil || result.Feed.Payload == nil || result.SourceID == uuid.Nil {
+		return
+	}
+	payload := result.Feed.Payload
+	payload.SourceID = result.SourceID
+	payload.FeedLink = result.Feed.FeedLink
+	payload.FetchedAt = s.clock.Now()
+
+	if err := s.storePayload(ctx, payload); err != nil {
+		logging.Error(ctx, "failed to archive payload",
+			zap.String("source", result.Source),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *Service) storePayload(ctx context.Context, payload *domain.Payload) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(payload.Body); err != nil {
+		return fmt.Errorf("error compressing payload: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("error compressing payload: %w", err)
+	}
+
+	bodies := s.archive.bodies[s.archive.storage]
+	location, err := bodies.PutPayloadBody(ctx, buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("error storing payload body: %w", err)
+	}
+	payload.Size = int64(len(payload.Body))
+	payload.StoredSize = int64(buf.Len())
+	payload.Storage = s.archive.storage
+	payload.Location = location
+
+	if _, err := s.store.CreatePayload(ctx, payload); err != nil {
+		// the body is of no use without its record
+		if err := bodies.DeletePayloadBody(ctx, location); err != nil {
+			logging.Error(ctx, "failed to delete orphaned payload body", zap.String("location", location), zap.Error(err))
+		}
+		return fmt.Errorf("error creating payload in db: %w", err)
+	}
+	return nil
+}
+
+// PruneArchive removes a batch of the payloads archived longer ago than the
+// retention allows. Payloads that cannot be removed are logged and skipped
+// past, so that they never hold the others back. Payloads are kept forever
+// without a retention.
+func (s *Service) PruneArchive(ctx context.Context) error {
+	if s.archive == nil || s.archive.retention <= 0 {
+		return nil
+	}
+
+	before := s.clock.Now().Add(-s.archive.retention)
+	limit := pruneBatchSize
+	var pruned, failed uint64
+	for pruned < pruneBatchSize {
+		// the payloads that could not be removed are still the oldest
+		offset := failed
+		payloads, err := s.store.SelectPayloads(ctx, &domain.SelectPayloadFilters{
+			To:     &before,
+			Limit:  &limit,
+			Offset: &offset,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to query expired payloads: %w", err)
+		}
+
+		for _, payload := range payloads {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := s.deletePayload(ctx, payload); err != nil {
+				failed++
+				logging.Error(ctx, "failed to prune payload",
+					zap.String("payload", payload.ID.String()),
+					zap.Error(err),
+				)
+				continue
+			}
+			pruned++
+		}
+
+		if uint64(len(payloads)) < limit {
+			return nil
+		}
+	}
+	return nil
+}
+
+// deletePayload removes the body of the payload, then its record. A payload
+// archived to a storage that is no longer available is kept, as its body
+// could not be removed.
+func (s *Service) deletePayload(ctx context.Context, payload *domain.Payload) error {
+	bodies, err := s.archive.bodiesOf(payload.Storage)
+	if err != nil {
+		return err
+	}
+	if err := bodies.DeletePayloadBody(ctx, payload.Location); err != nil {
+		return fmt.Errorf("error deleting payload body: %w", err)
+	}
+	return s.store.DeletePayload(ctx, payload.ID)
+}
+
+// ReprocessPayloads replays the archived payloads matching the filters,
+// oldest first, through the current parser of their source and the same path
+// crawled feeds are stored through. With dryRun the payloads are only parsed.
+// The articles of a payload revised since a later payload of their source was
+// archived are left as they are, unless overwriteNewer, in which case they are
+// rewritten as they were then, until the later payloads are replayed too.
+func (s *Service) ReprocessPayloads(ctx context.Context, filters *domain.SelectPayloadFilters, dryRun, overwriteNewer bool) (*domain.ReprocessResult, error) {
+	if s.archive == nil {
+		return nil, errArchiveDisabled
+	}
+
+	f := *filters
+	limit := reprocessBatchSize
+	f.Limit = &limit
+
+	result := &domain.ReprocessResult{}
+	sources := make(map[uuid.UUID]*domain.Source)
+	for offset := uint64(0); ; offset += limit {
+		f.Offset = &offset
+		payloads, err := s.store.SelectPayloads(ctx, &f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query payloads: %w", err)
+		}
+
+		for _, payload := range payloads {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			result.Payloads++
+
+			source, ok := sources[payload.SourceID]
+			if !ok {
+				source, err = s.store.GetSource(ctx, payload.SourceID)
+				switch {
+				case errors.Is(err, domain.ErrSourceNotFound):
+				case err != nil:
+					return nil, fmt.Errorf("failed to get source: %w", err)
+				}
+				sources[payload.SourceID] = source
+			}
+			if source == nil {
+				result.Skipped++
+				continue
+			}
+
+			if err := s.reprocessPayload(ctx, source, payload, dryRun, overwriteNewer, result); err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, fmt.Sprintf("payload %s: %v", payload.ID, err))
+				logging.Error(ctx, "failed to reprocess payload",
+					zap.String("payload", payload.ID.String()),
+					zap.Error(err),
+				)
+			}
+		}
+
+		if uint64(len(payloads)) < limit {
+			return result, nil
+		}
+	}
+}
+
+func (s *Service) reprocessPayload(ctx context.Context, source *domain.Source, payload *domain.Payload, dryRun, overwriteNewer bool, result *domain.ReprocessResult) error {
+	bodies, err := s.archive.bodiesOf(payload.Storage)
+	if err != nil {
+		return err
+	}
+	stored, err := bodies.GetPayloadBody(ctx, payload.Location)
+	if err != nil {
+		return fmt.Errorf("error reading payload body: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(stored))
+	if err != nil {
+		return fmt.Errorf("error decompressing payload: %w", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return fmt.Errorf("error decompressing payload: %w", err)
+	}
+
+	// the feed is stored under the link it had when the payload was archived
+	replayed := *source
+	replayed.URL = payload.FeedLink
+	crawlResult := s.crawler.Ingest(ctx, &replayed, body)
+	if crawlResult.Err != nil {
+		return crawlResult.Err
+	}
+	result.Articles += len(crawlResult.Feed.Articles)
+
+	if !overwriteNewer {
+		articles, err := s.withoutNewerArticles(ctx, payload, crawlResult.Feed.Articles)
+		if err != nil {
+			return err
+		}
+		result.Newer += len(crawlResult.Feed.Articles) - len(articles)
+		crawlResult.Feed.Articles = articles
+	}
+	if dryRun {
+		return nil
+	}
+
+	sourceResult := s.storeCrawlResult(ctx, crawlResult)
+	if sourceResult.Status == domain.CrawlStatusFailed {
+		return errors.New(sourceResult.Error)
+	}
+	result.ItemCounts.Add(sourceResult.ItemCounts)
+	return nil
+}
+
+// withoutNewerArticles drops the articles of the payload that were revised
+// since the next payload of its source was archived, whose stored version is
+// then newer than the one replayed. Revisions made by the crawl that archived
+// the payload itself happen before the next one is.
+func (s *Service) withoutNewerArticles(ctx context.Context, payload *domain.Payload, articles []*domain.Article) ([]*domain.Article, error) {
+	// Postgres keeps times to the microsecond
+	after := payload.FetchedAt.Add(time.Microsecond)
+	limit := uint64(1)
+	later, err := s.store.SelectPayloads(ctx, &domain.SelectPayloadFilters{
+		SourceIDs: []uuid.UUID{payload.SourceID},
+		From:      &after,
+		Limit:     &limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query later payloads: %w", err)
+	}
+	if len(later) == 0 {
+		// the latest payload, nothing stored since is newer
+		return articles, nil
+	}
+
+	guids := make([]string, len(articles))
+	for i, a := range articles {
+		guids[i] = strings.TrimSpace(a.GUID)
+	}
+	revised, err := s.store.SelectRevisedGUIDs(ctx, guids, later[0].FetchedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revised articles: %w", err)
+	}
+	if len(revised) == 0 {
+		return articles, nil
+	}
+
+	newer := make(map[string]bool, len(revised))
+	for _, guid := range revised {
+		newer[guid] = true
+	}
+	kept := make([]*domain.Article, 0, len(articles))
+	for _, a := range articles {
+		if !newer[strings.TrimSpace(a.GUID)] {
+			kept = append(kept, a)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jeffreyyong/news-feeder/internal/canonical"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	"go.uber.org/zap"
+)
+
+// canonicalize normalizes the links of the articles into their canonical
+// URL, which deduplicates the same story carried by several feeds. With
+// canonical.resolve, readPages replaces it by the rel="canonical" of the
+// pages of the articles not stored yet.
+func canonicalize(articles []*domain.Article) {
+	for _, a := range articles {
+		a.CanonicalURL = canonical.URL(a.Link)
+	}
+}
+
+// newArticles returns those of the articles that are not stored yet, which
+// are the only ones worth fetching the page of. None are returned when the
+// stored ones cannot be told apart.
+func (s *Service) newArticles(ctx context.Context, articles []*domain.Article) []*domain.Article {
+	guids := make([]string, len(articles))
+	for i, a := range articles {
+		guids[i] = a.GUID
+	}
+	known, err := s.store.SelectKnownGUIDs(ctx, guids)
+	if err != nil {
+		logging.Error(ctx, "failed to query known articles", zap.Error(err))
+		return nil
+	}
+
+	isKnown := make(map[string]bool, len(known))
+	for _, guid := range known {
+		isKnown[guid] = true
+	}
+
+	var unknown []*domain.Article
+	for _, a := range articles {
+		if !isKnown[a.GUID] {
+			unknown = append(unknown, a)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/extract"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	uuid "github.com/kevinburke/go.uuid"
+	"go.uber.org/zap"
+)
+
+// maxExtractionAttempts is how many times extracting an article is tried
+// before it is left as failed.
+const maxExtractionAttempts = 3
+
+// Extractor pulls the main content out of an article's page.
+type Extractor interface {
+	Extract(ctx context.Context, pageURL string) (*extract.Result, error)
+}
+
+// extractionConfig is nil, and no content is extracted, unless the service
+// is created WithExtractor.
+type extractionConfig struct {
+	extractor Extractor
+	batchSize uint64
+}
+
+// ExtractArticles extracts the content of a batch of newly stored articles,
+// retrying the ones that failed before up to maxExtractionAttempts times.
+func (s *Service) ExtractArticles(ctx context.Context) error {
+	if s.extraction == nil {
+		return nil
+	}
+
+	maxAttempts := maxExtractionAttempts
+	contents, err := s.store.SelectArticleContents(ctx, &domain.SelectArticleContentFilters{
+		Statuses: []domain.ExtractionStatus{
+			domain.ExtractionStatusPending,
+			domain.ExtractionStatusFailed,
+		},
+		MaxAttempts: &maxAttempts,
+		Limit:       &s.extraction.batchSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query pending article content: %w", err)
+	}
+
+	for _, content := range contents {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.extractArticle(ctx, content)
+	}
+	return nil
+}
+
+func (s *Service) extractArticle(ctx context.Context, content *domain.ArticleContent) {
+	content.Attempts++
+
+	result, err := s.extraction.extractor.Extract(ctx, content.Link)
+	if err != nil {
+		logging.Error(ctx, "failed to extract article content",
+			zap.String("link", content.Link),
+			zap.Error(err),
+		)
+	}
+	s.recordExtraction(content, result, err)
+
+	if err := s.store.UpdateArticleContent(ctx, content); err != nil {
+		logging.Error(ctx, "failed to update article content",
+			zap.String("link", content.Link),
+			zap.Error(err),
+		)
+	}
+}
+
+// recordExtraction records the outcome of an extraction attempt on the content.
+func (s *Service) recordExtraction(content *domain.ArticleContent, result *extract.Result, err error) {
+	if err != nil {
+		content.Status = domain.ExtractionStatusFailed
+		content.Error = err.Error()
+		return
+	}
+
+	now := s.clock.Now()
+	content.Status = domain.ExtractionStatusSucceeded
+	content.HTML = result.HTML
+	content.Text = result.Text
+	content.WordCount = result.WordCount
+	content.Error = ""
+	content.ExtractedAt = &now
+}
+
+// GetArticleContent returns the extracted content of an article along with
+// the state of its extraction.
+func (s *Service) GetArticleContent(ctx context.Context, articleID uuid.UUID) (*domain.ArticleContent, error) {
+	content, err := s.store.GetArticleContent(ctx, articleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get article content: %w", err)
+	}
+
+	return content, nil
+}
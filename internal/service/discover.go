@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// Discoverer finds the feeds of a website.
+type Discoverer interface {
+	Discover(ctx context.Context, pageURL string) ([]*domain.FeedCandidate, error)
+}
+
+var errDiscoveryDisabled = errors.New("feed discovery is not enabled")
+
+// DiscoverFeeds returns the feeds of the website of the page, with the source
+// each is registered as when it already is one. Registering one of them is
+// left to CreateSource.
+func (s *Service) DiscoverFeeds(ctx context.Context, pageURL string) ([]*domain.FeedCandidate, error) {
+	if s.discoverer == nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidSource, errDiscoveryDisabled)
+	}
+	if err := validateSourceURL(pageURL); err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.discoverer.Discover(ctx, pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover feeds: %w", err)
+	}
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	urls := make([]string, len(candidates))
+	for i, c := range candidates {
+		urls[i] = c.URL
+	}
+	sources, err := s.store.SelectSources(ctx, &domain.SelectSourceFilters{URLs: urls})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sources: %w", err)
+	}
+
+	registered := make(map[string]*domain.Source, len(sources))
+	for _, source := range sources {
+		registered[source.URL] = source
+	}
+	for _, c := range candidates {
+		if source, ok := registered[c.URL]; ok {
+			c.SourceID = &source.ID
+		}
+	}
+	return candidates, nil
+}
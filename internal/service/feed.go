@@ -4,13 +4,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/fingerprint"
+	"github.com/jeffreyyong/news-feeder/internal/localfeed"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	"github.com/jeffreyyong/news-feeder/internal/schedule"
 	"github.com/jonboulle/clockwork"
+	"go.uber.org/zap"
 
 	uuid "github.com/kevinburke/go.uuid"
 )
 
+// interruptedStoreTimeout bounds storing the results of a crawl that was
+// interrupted, whose own context is done.
+const interruptedStoreTimeout = 30 * time.Second
+
+// directoryPollInterval is how often directory sources are read unless they
+// set a poll_interval. They are not scheduled adaptively, as an empty
+// directory is reported like a 304 and would back off to the max interval.
+const directoryPollInterval = time.Minute
+
 // Store is the db interface
 type Store interface {
 	ExecInTransaction(ctx context.Context, f func(ctx context.Context) error) error
@@ -18,18 +33,77 @@ type Store interface {
 	CreateFeed(ctx context.Context, feed *domain.Feed) (string, error)
 	SelectFeeds(ctx context.Context, f *domain.SelectFeedFilters) ([]*domain.Feed, error)
 
-	CreateArticle(ctx context.Context, article *domain.Article) (string, error)
+	CreateArticle(ctx context.Context, article *domain.Article) (string, domain.ArticleWriteStatus, error)
 	SelectArticles(ctx context.Context, f *domain.SelectArticleFilters) ([]*domain.Article, error)
+	GetArticle(ctx context.Context, id uuid.UUID) (*domain.Article, error)
+	SelectKnownGUIDs(ctx context.Context, guids []string) ([]string, error)
+	SelectRevisedGUIDs(ctx context.Context, guids []string, since time.Time) ([]string, error)
+	SelectArticleRevisions(ctx context.Context, articleID uuid.UUID) ([]*domain.ArticleRevision, error)
+	SelectArticleImages(ctx context.Context, articleIDs []uuid.UUID) ([]*domain.ArticleImage, error)
+
+	CreateSource(ctx context.Context, source *domain.Source) (string, error)
+	UpdateSource(ctx context.Context, id uuid.UUID, update *domain.SourceUpdate) error
+	ScheduleSource(ctx context.Context, id uuid.UUID, schedule *domain.SourceSchedule) error
+	DeleteSource(ctx context.Context, id uuid.UUID) error
+	GetSource(ctx context.Context, id uuid.UUID) (*domain.Source, error)
+	SelectSources(ctx context.Context, f *domain.SelectSourceFilters) ([]*domain.Source, error)
+	CountSources(ctx context.Context) (int, error)
+	MarkSourceGone(ctx context.Context, id uuid.UUID, at time.Time) error
+	CreateSourceEvent(ctx context.Context, event *domain.SourceEvent) (string, error)
+	SelectSourceEvents(ctx context.Context, sourceID uuid.UUID) ([]*domain.SourceEvent, error)
+
+	PutCategory(ctx context.Context, category *domain.CategoryInfo) error
+	SelectCategories(ctx context.Context) ([]*domain.CategoryInfo, error)
+	PutProvider(ctx context.Context, provider *domain.ProviderInfo) error
+	SelectProviders(ctx context.Context) ([]*domain.ProviderInfo, error)
+
+	CreateCrawlRun(ctx context.Context, run *domain.CrawlRun) (string, error)
+	UpdateCrawlRun(ctx context.Context, run *domain.CrawlRun) error
+	GetCrawlRun(ctx context.Context, id uuid.UUID) (*domain.CrawlRun, error)
+	SelectCrawlRuns(ctx context.Context, f *domain.SelectCrawlRunFilters) ([]*domain.CrawlRun, error)
+	CreateCrawlSourceResult(ctx context.Context, result *domain.CrawlSourceResult) (string, error)
+	SelectCrawlSourceResults(ctx context.Context, f *domain.SelectCrawlSourceResultFilters) ([]*domain.CrawlSourceResult, error)
+
+	CreateWebSubSubscription(ctx context.Context, sub *domain.WebSubSubscription) (string, error)
+	UpdateWebSubSubscription(ctx context.Context, sub *domain.WebSubSubscription) error
+	GetWebSubSubscription(ctx context.Context, id uuid.UUID) (*domain.WebSubSubscription, error)
+	SelectWebSubSubscriptions(ctx context.Context, f *domain.SelectWebSubSubscriptionFilters) ([]*domain.WebSubSubscription, error)
+
+	QueueArticleContent(ctx context.Context, articleID uuid.UUID) error
+	UpdateArticleContent(ctx context.Context, content *domain.ArticleContent) error
+	GetArticleContent(ctx context.Context, articleID uuid.UUID) (*domain.ArticleContent, error)
+	SelectArticleContents(ctx context.Context, f *domain.SelectArticleContentFilters) ([]*domain.ArticleContent, error)
+
+	CreateStory(ctx context.Context, story *domain.Story) (string, error)
+	AssignArticleStory(ctx context.Context, articleID, storyID uuid.UUID, publishedAt time.Time) error
+	SelectStoryCandidates(ctx context.Context, f *domain.SelectStoryCandidateFilters) ([]*domain.Article, error)
+	SelectStories(ctx context.Context, f *domain.SelectStoryFilters) ([]*domain.Story, error)
+
+	CreatePayload(ctx context.Context, payload *domain.Payload) (string, error)
+	SelectPayloads(ctx context.Context, f *domain.SelectPayloadFilters) ([]*domain.Payload, error)
+	DeletePayload(ctx context.Context, id uuid.UUID) error
 }
 
 type Crawler interface {
-	Crawl(ctx context.Context) ([]*domain.Feed, error)
+	Crawl(ctx context.Context) ([]*domain.CrawlResult, error)
+	Ingest(ctx context.Context, source *domain.Source, body []byte) *domain.CrawlResult
 }
 
 type Service struct {
 	store   Store
 	clock   clockwork.Clock
 	crawler Crawler
+	policy  schedule.Policy
+	websub  *webSubConfig
+
+	extraction *extractionConfig
+	pages      *pageConfig
+	imageProxy ImageProxy
+	stories    *storyConfig
+	scraper    Scraper
+	filesRoot  string
+	discoverer Discoverer
+	archive    *archiveConfig
 }
 
 func New(store Store, crawler Crawler, opts ...Option) (*Service, error) {
@@ -37,7 +111,12 @@ func New(store Store, crawler Crawler, opts ...Option) (*Service, error) {
 		return nil, errors.New("nil store")
 	}
 
-	s := &Service{store: store, crawler: crawler}
+	s := &Service{
+		store:   store,
+		crawler: crawler,
+		clock:   clockwork.NewRealClock(),
+		policy:  schedule.DefaultPolicy,
+	}
 
 	for _, opt := range opts {
 		if err := opt(s); err != nil {
@@ -55,40 +134,380 @@ func (s *Service) ListArticles(ctx context.Context, filters *domain.SelectArticl
 		return nil, fmt.Errorf("failed to query articles: %w", err)
 	}
 
+	if err := s.attachImages(ctx, articles); err != nil {
+		return nil, err
+	}
 	return articles, nil
 }
 
+// ListArticleRevisions lists the previous versions of an article, most recent
+// first, each with the fields the version after it changed.
+func (s *Service) ListArticleRevisions(ctx context.Context, articleID uuid.UUID) ([]*domain.ArticleRevision, error) {
+	if _, err := s.store.GetArticle(ctx, articleID); err != nil {
+		return nil, fmt.Errorf("failed to get article: %w", err)
+	}
+
+	revisions, err := s.store.SelectArticleRevisions(ctx, articleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query article revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
 // ListFeeds lists feeds that have been stored in the persistence layer.
 func (s *Service) ListFeeds(ctx context.Context, filters *domain.SelectFeedFilters) ([]*domain.Feed, error) {
 	return nil, nil
 }
 
-// 1. take the sources, for each feed, create Feed and create Article.
+// CrawlFeeds crawls every source and stores the resulting feeds and articles.
+// Each source is stored in its own transaction so that one broken feed does
+// not prevent the others from being saved. The outcome of the run and of each
+// source is recorded as crawl history.
+//
+// Only sources that are due are crawled, a run with nothing due is not recorded.
 func (s *Service) CrawlFeeds(ctx context.Context) error {
-	feeds, err := s.crawler.Crawl(ctx)
+	startedAt := s.clock.Now()
+	results, crawlErr := s.crawler.Crawl(ctx)
+	if len(results) == 0 && crawlErr == nil {
+		return nil
+	}
+
+	if crawlErr != nil && ctx.Err() != nil {
+		// the sources that finished before the crawl was interrupted are
+		// still stored and rescheduled
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(withoutCancel{ctx}, interruptedStoreTimeout)
+		defer cancel()
+	}
+
+	run, err := s.startCrawlRun(ctx, startedAt)
 	if err != nil {
 		return err
 	}
 
-	if err := s.store.ExecInTransaction(ctx, func(ctx context.Context) error {
-		for _, feed := range feeds {
-			feedID, err := s.store.CreateFeed(ctx, feed)
+	for _, result := range results {
+		s.recordCrawlResult(ctx, run, result)
+	}
+
+	if err := s.finishCrawlRun(ctx, run, crawlErr); err != nil {
+		return err
+	}
+	return crawlErr
+}
+
+// withoutCancel keeps the values of a context, such as its logger, but not
+// its deadline or cancellation.
+type withoutCancel struct {
+	context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (withoutCancel) Done() <-chan struct{}       { return nil }
+func (withoutCancel) Err() error                  { return nil }
+
+func (s *Service) startCrawlRun(ctx context.Context, startedAt time.Time) (*domain.CrawlRun, error) {
+	run := &domain.CrawlRun{
+		Status:    domain.CrawlStatusRunning,
+		StartedAt: startedAt,
+	}
+	runID, err := s.store.CreateCrawlRun(ctx, run)
+	if err != nil {
+		return nil, fmt.Errorf("error creating crawl run in db: %w", err)
+	}
+	run.ID, _ = uuid.FromString(runID)
+	return run, nil
+}
+
+// scheduleSource records the crawl of the result's source and schedules its
+// next one according to the policy.
+func (s *Service) scheduleSource(ctx context.Context, result *domain.CrawlResult) error {
+	source, err := s.store.GetSource(ctx, result.SourceID)
+	if err != nil {
+		return err
+	}
+
+	in := schedule.Input{
+		Fixed:     time.Duration(source.PollInterval) * time.Second,
+		Previous:  time.Duration(source.ScheduledInterval) * time.Second,
+		TTL:       time.Duration(source.TTL) * time.Second,
+		SkipHours: make([]int, len(source.SkipHours)),
+	}
+	if in.Fixed == 0 && source.Type == domain.SourceTypeDirectory {
+		in.Fixed = directoryPollInterval
+	}
+	for i, h := range source.SkipHours {
+		in.SkipHours[i] = int(h)
+	}
+	for _, d := range source.SkipDays {
+		if day, ok := weekdays[d]; ok {
+			in.SkipDays = append(in.SkipDays, day)
+		}
+	}
+
+	var hints *domain.SourceHints
+	switch {
+	case result.Err != nil:
+		in.Outcome = schedule.Failed
+	case result.NotModified:
+		in.Outcome = schedule.NotModified
+	default:
+		in.Outcome = schedule.Fetched
+		in.TTL, in.SkipHours, in.SkipDays = result.Feed.TTL, result.Feed.SkipHours, result.Feed.SkipDays
+		for _, a := range result.Feed.Articles {
+			in.PublishedAt = append(in.PublishedAt, a.PublishedAt)
+		}
+
+		hints = &domain.SourceHints{TTL: int(result.Feed.TTL / time.Second)}
+		for _, h := range result.Feed.SkipHours {
+			hints.SkipHours = append(hints.SkipHours, int64(h))
+		}
+		for _, d := range result.Feed.SkipDays {
+			hints.SkipDays = append(hints.SkipDays, d.String())
+		}
+	}
+
+	now := s.clock.Now()
+	next, interval := s.policy.Next(now, in)
+
+	return s.store.ScheduleSource(ctx, source.ID, &domain.SourceSchedule{
+		CrawledAt:  now,
+		NextPollAt: next,
+		Interval:   int(interval / time.Second),
+		Hints:      hints,
+	})
+}
+
+var weekdays = map[string]time.Weekday{
+	time.Sunday.String():    time.Sunday,
+	time.Monday.String():    time.Monday,
+	time.Tuesday.String():   time.Tuesday,
+	time.Wednesday.String(): time.Wednesday,
+	time.Thursday.String():  time.Thursday,
+	time.Friday.String():    time.Friday,
+	time.Saturday.String():  time.Saturday,
+}
+
+// recordCrawlResult archives the document of a result and stores its feed,
+// records its outcome against the run, follows a source that moved or died
+// and subscribes to the hubs the feed advertises.
+func (s *Service) recordCrawlResult(ctx context.Context, run *domain.CrawlRun, result *domain.CrawlResult) {
+	s.archivePayload(ctx, result)
+	sourceResult := s.storeCrawlResult(ctx, result)
+	sourceResult.CrawlRunID = run.ID
+	if result.SourceID != uuid.Nil {
+		sourceResult.SourceID = &result.SourceID
+
+		if err := s.scheduleSource(ctx, result); err != nil {
+			logging.Error(ctx, "failed to schedule source",
+				zap.String("source", sourceResult.Source),
+				zap.Error(err),
+			)
+		}
+		s.followSource(ctx, result)
+
+		if result.Feed != nil && sourceResult.Status == domain.CrawlStatusSucceeded {
+			s.ensureSubscription(ctx, result.SourceID, result.Feed)
+		}
+	}
+	if result.Feed != nil && sourceResult.Status == domain.CrawlStatusSucceeded {
+		s.finishFiles(ctx, result.Feed)
+	}
+
+	if _, err := s.store.CreateCrawlSourceResult(ctx, sourceResult); err != nil {
+		logging.Error(ctx, "failed to record crawl source result",
+			zap.String("source", sourceResult.Source),
+			zap.Error(err),
+		)
+	}
+
+	run.SourceCount++
+	run.ItemCounts.Add(sourceResult.ItemCounts)
+	if sourceResult.Status == domain.CrawlStatusFailed {
+		run.FailedCount++
+	}
+}
+
+// finishFiles moves the files of a watched directory a stored feed was read
+// from into its done folder. Files of feeds that failed to be stored are left
+// to be read again by the next crawl.
+func (s *Service) finishFiles(ctx context.Context, feed *domain.Feed) {
+	for _, path := range feed.Files {
+		if err := localfeed.Done(path, s.clock.Now()); err != nil {
+			logging.Error(ctx, "failed to move feed file", zap.String("path", path), zap.Error(err))
+		}
+	}
+}
+
+func (s *Service) finishCrawlRun(ctx context.Context, run *domain.CrawlRun, crawlErr error) error {
+	finishedAt := s.clock.Now()
+	duration := finishedAt.Sub(run.StartedAt).Milliseconds()
+	run.FinishedAt = &finishedAt
+	run.DurationMS = &duration
+	run.Status = crawlRunStatus(run, crawlErr)
+
+	if err := s.store.UpdateCrawlRun(ctx, run); err != nil {
+		return fmt.Errorf("error updating crawl run in db: %w", err)
+	}
+	return nil
+}
+
+// storeCrawlResult persists the feed of a single crawl result and reports how
+// it went. Failures are captured on the returned result rather than returned.
+func (s *Service) storeCrawlResult(ctx context.Context, result *domain.CrawlResult) *domain.CrawlSourceResult {
+	sourceResult := &domain.CrawlSourceResult{
+		Source:     result.Source,
+		Status:     domain.CrawlStatusSucceeded,
+		DurationMS: result.Duration.Milliseconds(),
+	}
+
+	if result.Err != nil {
+		sourceResult.Status = domain.CrawlStatusFailed
+		sourceResult.Error = result.Err.Error()
+		return sourceResult
+	}
+
+	if result.NotModified {
+		sourceResult.Status = domain.CrawlStatusUnchanged
+		return sourceResult
+	}
+
+	if len(result.Feed.Warnings) > 0 {
+		sourceResult.Warnings = result.Feed.Warnings
+		logging.Print(ctx, "source crawled with warnings",
+			zap.String("source", result.Source),
+			zap.Strings("warnings", result.Feed.Warnings),
+		)
+	}
+
+	normalize(result.Feed)
+	canonicalize(result.Feed.Articles)
+	s.readPages(ctx, result.Feed.Articles)
+
+	counts, err := s.storeFeed(ctx, result.Feed)
+	if err != nil {
+		logging.Error(ctx, "failed to store feed",
+			zap.String("source", result.Source),
+			zap.Error(err),
+		)
+		sourceResult.Status = domain.CrawlStatusFailed
+		sourceResult.Error = err.Error()
+		return sourceResult
+	}
+
+	sourceResult.ItemCounts = counts
+	return sourceResult
+}
+
+// storeFeed creates the feed and its articles within a single transaction.
+func (s *Service) storeFeed(ctx context.Context, feed *domain.Feed) (domain.ItemCounts, error) {
+	var counts domain.ItemCounts
+
+	err := s.store.ExecInTransaction(ctx, func(ctx context.Context) error {
+		// reset in case the transaction is retried
+		counts = domain.ItemCounts{}
+
+		feedID, err := s.store.CreateFeed(ctx, feed)
+		if err != nil {
+			return fmt.Errorf("error creating feed in db: %w", err)
+		}
+
+		id, _ := uuid.FromString(feedID)
+		var created []*domain.Article
+		for _, article := range feed.Articles {
+			if article.GUID == "" {
+				// nothing tells such an item apart from the others
+				counts.Skipped++
+				continue
+			}
+			article.FeedID = id
+			article.Shingles = fingerprint.Shingles(article.Title + " " + article.Description)
+			articleID, status, err := s.store.CreateArticle(ctx, article)
 			if err != nil {
-				return fmt.Errorf("error creating feed in db: %w", err)
+				return fmt.Errorf("error creating article in db: %w", err)
 			}
 
-			for _, article := range feed.Articles {
-				id, _ := uuid.FromString(feedID)
-				article.FeedID = id
-				_, err := s.store.CreateArticle(ctx, article)
-				if err != nil {
-					return fmt.Errorf("error creating article in db: %w", err)
+			if status == domain.ArticleWriteCreated {
+				article.ID, _ = uuid.FromString(articleID)
+				created = append(created, article)
+
+				if s.extraction != nil {
+					if err := s.store.QueueArticleContent(ctx, article.ID); err != nil {
+						return fmt.Errorf("error queueing article content in db: %w", err)
+					}
+					// extracted from the page already read for its canonical url or image
+					if content := article.Extracted; content != nil {
+						content.ArticleID = article.ID
+						if err := s.store.UpdateArticleContent(ctx, content); err != nil {
+							return fmt.Errorf("error updating article content in db: %w", err)
+						}
+					}
 				}
 			}
+
+			switch status {
+			case domain.ArticleWriteCreated:
+				counts.New++
+			case domain.ArticleWriteUpdated:
+				counts.Updated++
+			default:
+				counts.Skipped++
+			}
+		}
+
+		if err := s.clusterStories(ctx, id, feed.Provider, created); err != nil {
+			return fmt.Errorf("error grouping articles into stories: %w", err)
 		}
 		return nil
-	}); err != nil {
-		return err
+	})
+	return counts, err
+}
+
+func crawlRunStatus(run *domain.CrawlRun, crawlErr error) domain.CrawlStatus {
+	switch {
+	case crawlErr != nil, run.SourceCount > 0 && run.FailedCount == run.SourceCount:
+		return domain.CrawlStatusFailed
+	case run.FailedCount > 0:
+		return domain.CrawlStatusPartial
+	default:
+		return domain.CrawlStatusSucceeded
 	}
-	return nil
+}
+
+// ListCrawlRuns lists the recorded crawl runs.
+func (s *Service) ListCrawlRuns(ctx context.Context, filters *domain.SelectCrawlRunFilters) ([]*domain.CrawlRun, error) {
+	runs, err := s.store.SelectCrawlRuns(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// GetCrawlRun returns a crawl run along with the result of every source crawled within it.
+func (s *Service) GetCrawlRun(ctx context.Context, id uuid.UUID) (*domain.CrawlRun, error) {
+	run, err := s.store.GetCrawlRun(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get crawl run: %w", err)
+	}
+
+	results, err := s.store.SelectCrawlSourceResults(ctx, &domain.SelectCrawlSourceResultFilters{
+		CrawlRunIDs: []uuid.UUID{id},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl source results: %w", err)
+	}
+	run.Results = results
+
+	return run, nil
+}
+
+// ListCrawlSourceResults lists the recorded per-source crawl results.
+func (s *Service) ListCrawlSourceResults(ctx context.Context, filters *domain.SelectCrawlSourceResultFilters) ([]*domain.CrawlSourceResult, error) {
+	results, err := s.store.SelectCrawlSourceResults(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl source results: %w", err)
+	}
+
+	return results, nil
 }
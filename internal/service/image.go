@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+// ImageProxy serves publisher images scaled down from our own origin.
+type ImageProxy interface {
+	Image(ctx context.Context, sourceURL string, width int) (*domain.Image, error)
+}
+
+// GetArticleImage returns an image of the article scaled down to about width,
+// 0 for the largest width served. The image is the one whose domain.ImageVersion
+// is version among the thumbnail, the candidate images and the thumbnails of
+// the revisions of the article, or the current thumbnail without a version.
+func (s *Service) GetArticleImage(ctx context.Context, articleID uuid.UUID, version string, width int) (*domain.Image, error) {
+	if s.imageProxy == nil {
+		return nil, domain.ErrImageNotFound
+	}
+
+	article, err := s.store.GetArticle(ctx, articleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get article: %w", err)
+	}
+
+	sourceURL, err := s.articleImageURL(ctx, article, version)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := s.imageProxy.Image(ctx, sourceURL, width)
+	if err != nil {
+		return nil, fmt.Errorf("failed to proxy article image: %w", err)
+	}
+	return img, nil
+}
+
+func (s *Service) articleImageURL(ctx context.Context, article *domain.Article, version string) (string, error) {
+	if version == "" || (article.ThumbnailURL != "" && domain.ImageVersion(article.ThumbnailURL) == version) {
+		if article.ThumbnailURL == "" {
+			return "", domain.ErrImageNotFound
+		}
+		return article.ThumbnailURL, nil
+	}
+
+	images, err := s.store.SelectArticleImages(ctx, []uuid.UUID{article.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to query article images: %w", err)
+	}
+	for _, img := range images {
+		if domain.ImageVersion(img.URL) == version {
+			return img.URL, nil
+		}
+	}
+
+	revisions, err := s.store.SelectArticleRevisions(ctx, article.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to query article revisions: %w", err)
+	}
+	for _, rev := range revisions {
+		if rev.ThumbnailURL != "" && domain.ImageVersion(rev.ThumbnailURL) == version {
+			return rev.ThumbnailURL, nil
+		}
+	}
+	return "", domain.ErrImageNotFound
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+func hasImage(images []*domain.ArticleImage, url string) bool {
+	for _, img := range images {
+		if img.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+// attachImages loads the candidate images of the articles.
+func (s *Service) attachImages(ctx context.Context, articles []*domain.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(articles))
+	byID := make(map[uuid.UUID]*domain.Article, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+		byID[a.ID] = a
+		a.Images = []*domain.ArticleImage{}
+	}
+
+	images, err := s.store.SelectArticleImages(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("failed to query article images: %w", err)
+	}
+	for _, img := range images {
+		if a, ok := byID[img.ArticleID]; ok {
+			a.Images = append(a.Images, img)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/sanitize"
+)
+
+// Lengths, in runes, ingested text is truncated to. The columns are
+// unbounded, these keep one verbose item from bloating the API.
+const (
+	maxTitleLength       = 500
+	maxDescriptionLength = 2000
+	maxNameLength        = 255
+)
+
+// normalize cleans the text of the feed and its articles ahead of storing
+// them, whatever parsed them: titles, descriptions, authors and tags are
+// reduced to plain text and truncated, full content is sanitized to the
+// allowed HTML.
+func normalize(feed *domain.Feed) {
+	feed.Title = sanitize.Truncate(sanitize.Text(feed.Title), maxTitleLength)
+	feed.Description = sanitize.Truncate(sanitize.Text(feed.Description), maxDescriptionLength)
+	feed.Link = strings.TrimSpace(feed.Link)
+
+	for _, a := range feed.Articles {
+		a.Title = sanitize.Truncate(sanitize.Text(a.Title), maxTitleLength)
+		a.Description = sanitize.Truncate(sanitize.Text(a.Description), maxDescriptionLength)
+		a.Link = strings.TrimSpace(a.Link)
+		a.GUID = strings.TrimSpace(a.GUID)
+		a.Authors = normalizeNames(a.Authors)
+		a.Tags = normalizeNames(a.Tags)
+
+		if a.Content != "" {
+			base, _ := url.Parse(a.Link)
+			a.Content = sanitize.HTML(a.Content, base)
+		}
+	}
+}
+
+// normalizeNames reduces the names to plain text, dropping the ones left
+// empty or repeated.
+func normalizeNames(names []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(names))
+	for _, n := range names {
+		n = sanitize.Truncate(sanitize.Text(n), maxNameLength)
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	return out
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/localfeed"
+	"github.com/jeffreyyong/news-feeder/internal/opml"
+)
+
+const opmlTitle = "news-feeder sources"
+
+// ImportOPML registers every feed of the document as an enabled source.
+// Outline categories are mapped onto the taxonomy by slug or display name,
+// the most specific matching category winning. Feeds that are already
+// registered are left as they are.
+func (s *Service) ImportOPML(ctx context.Context, doc *opml.Document) (*domain.SourceImportResult, error) {
+	categories, err := s.store.SelectCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+
+	known := make(map[string]domain.Category, 2*len(categories))
+	for _, c := range categories {
+		known[string(c.Slug)] = c.Slug
+		known[domain.Slugify(c.DisplayName)] = c.Slug
+	}
+
+	result := &domain.SourceImportResult{}
+	unmapped := make(map[string]bool)
+
+	for _, feed := range doc.Feeds() {
+		source := &domain.Source{
+			URL:     feed.URL,
+			Enabled: true,
+			Notes:   feed.Title,
+		}
+
+		for i := len(feed.Categories) - 1; i >= 0; i-- {
+			if slug, ok := known[domain.Slugify(feed.Categories[i])]; ok {
+				source.Category = slug
+				break
+			}
+		}
+		if source.Category == "" {
+			for _, c := range feed.Categories {
+				unmapped[c] = true
+			}
+		}
+
+		_, err := s.CreateSource(ctx, source)
+		switch {
+		case errors.Is(err, domain.ErrSourceAlreadyExists):
+			result.Existing++
+		case err != nil:
+			result.Failed = append(result.Failed, &domain.SourceImportFailed{URL: feed.URL, Error: err.Error()})
+		default:
+			result.Created++
+		}
+	}
+
+	for c := range unmapped {
+		result.UnmappedCategories = append(result.UnmappedCategories, c)
+	}
+	sort.Strings(result.UnmappedCategories)
+
+	return result, nil
+}
+
+// ExportOPML returns the registered feed sources as an OPML 2.0 document,
+// with the sources filed under an outline per category. Sources of other
+// types, such as sitemaps, and local files are left out as OPML readers
+// could not use them.
+func (s *Service) ExportOPML(ctx context.Context) (*opml.Document, error) {
+	sources, err := s.store.SelectSources(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sources: %w", err)
+	}
+
+	categories, err := s.store.SelectCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+
+	urls := make([]string, len(sources))
+	for i, source := range sources {
+		urls[i] = source.URL
+	}
+
+	feeds := make(map[string]*domain.Feed)
+	if len(urls) > 0 {
+		crawled, err := s.store.SelectFeeds(ctx, &domain.SelectFeedFilters{FeedLinks: urls})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query feeds: %w", err)
+		}
+		for _, f := range crawled {
+			feeds[f.FeedLink] = f
+		}
+	}
+
+	doc := &opml.Document{
+		Version: opml.Version,
+		Head: opml.Head{
+			Title:       opmlTitle,
+			DateCreated: s.clock.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	folders := make(map[domain.Category]*opml.Outline)
+	for _, c := range categories {
+		folders[c.Slug] = &opml.Outline{Text: c.DisplayName, Title: c.DisplayName}
+	}
+
+	for _, source := range sources {
+		if source.Type != domain.SourceTypeFeed || localfeed.IsFileURL(source.URL) {
+			continue
+		}
+		outline := &opml.Outline{
+			Text:     source.URL,
+			Type:     opml.OutlineTypeRSS,
+			XMLURL:   source.URL,
+			Category: string(source.Category),
+		}
+		if f, ok := feeds[source.URL]; ok {
+			outline.Text = f.Title
+			outline.Title = f.Title
+			outline.HTMLURL = f.Link
+		} else if source.Notes != "" {
+			outline.Text = strings.SplitN(source.Notes, "\n", 2)[0]
+		}
+
+		folder, ok := folders[source.Category]
+		if !ok {
+			doc.Body.Outlines = append(doc.Body.Outlines, outline)
+			continue
+		}
+		folder.Outlines = append(folder.Outlines, outline)
+	}
+
+	for _, c := range categories {
+		if folder := folders[c.Slug]; len(folder.Outlines) > 0 {
+			doc.Body.Outlines = append(doc.Body.Outlines, folder)
+		}
+	}
+
+	return doc, nil
+}
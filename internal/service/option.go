@@ -1,6 +1,15 @@
 package service
 
-import "github.com/jonboulle/clockwork"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/schedule"
+)
 
 type Option func(*Service) error
 
@@ -11,3 +20,165 @@ func WithClock(clock clockwork.Clock) Option {
 		return nil
 	}
 }
+
+// WithSchedulePolicy sets the bounds sources are polled within.
+func WithSchedulePolicy(policy schedule.Policy) Option {
+	return func(s *Service) error {
+		if policy.Max > 0 && policy.Max < policy.Min {
+			return errors.New("max poll interval below min poll interval")
+		}
+		s.policy = policy
+		return nil
+	}
+}
+
+// WithWebSub enables subscribing to the hubs feeds advertise. Hubs push to
+// callbackURL with the subscription id appended, leases are requested for
+// leaseSeconds and renewed renewBefore they expire.
+func WithWebSub(client WebSubClient, callbackURL string, leaseSeconds int, renewBefore time.Duration) Option {
+	return func(s *Service) error {
+		if client == nil {
+			return errors.New("nil websub client")
+		}
+		if callbackURL == "" {
+			return errors.New("empty websub callback url")
+		}
+		s.websub = &webSubConfig{
+			client:       client,
+			callbackURL:  callbackURL,
+			leaseSeconds: leaseSeconds,
+			renewBefore:  renewBefore,
+		}
+		return nil
+	}
+}
+
+// WithExtractor enables extracting the full content of newly stored articles,
+// batchSize articles at a time.
+func WithExtractor(extractor Extractor, batchSize int) Option {
+	return func(s *Service) error {
+		if extractor == nil {
+			return errors.New("nil extractor")
+		}
+		if batchSize <= 0 {
+			return errors.New("extraction batch size must be positive")
+		}
+		s.extraction = &extractionConfig{
+			extractor: extractor,
+			batchSize: uint64(batchSize),
+		}
+		return nil
+	}
+}
+
+// WithCanonicalResolver enables resolving the canonical URL of every new
+// article from its page, rather than only normalizing its link.
+func WithCanonicalResolver(reader PageReader) Option {
+	return func(s *Service) error {
+		if reader == nil {
+			return errors.New("nil canonical resolver")
+		}
+		s.pageConfig(reader).canonical = true
+		return nil
+	}
+}
+
+// WithStoryClustering enables grouping new articles into stories with the
+// articles published within window of them that share at least similarity
+// of their words.
+func WithStoryClustering(similarity float64, window time.Duration) Option {
+	return func(s *Service) error {
+		if similarity <= 0 || similarity > 1 {
+			return errors.New("story similarity must be within (0, 1]")
+		}
+		if window <= 0 {
+			return errors.New("story window must be positive")
+		}
+		s.stories = &storyConfig{
+			similarity: similarity,
+			window:     window,
+		}
+		return nil
+	}
+}
+
+// WithImageResolver enables adding the og:image of the page of every new
+// article to the images its thumbnail is picked from.
+func WithImageResolver(reader PageReader) Option {
+	return func(s *Service) error {
+		if reader == nil {
+			return errors.New("nil image resolver")
+		}
+		s.pageConfig(reader).images = true
+		return nil
+	}
+}
+
+// WithImageProxy enables serving the thumbnails of articles through the proxy.
+func WithImageProxy(proxy ImageProxy) Option {
+	return func(s *Service) error {
+		if proxy == nil {
+			return errors.New("nil image proxy")
+		}
+		s.imageProxy = proxy
+		return nil
+	}
+}
+
+// WithScraper enables scrape sources, whose listing pages are read with CSS selectors.
+func WithScraper(scraper Scraper) Option {
+	return func(s *Service) error {
+		if scraper == nil {
+			return errors.New("nil scraper")
+		}
+		s.scraper = scraper
+		return nil
+	}
+}
+
+// WithLocalFiles enables file:// and directory sources, which must be under root.
+func WithLocalFiles(root string) Option {
+	return func(s *Service) error {
+		if root == "" {
+			return errors.New("empty files root")
+		}
+		s.filesRoot = root
+		return nil
+	}
+}
+
+// WithDiscoverer enables discovering the feeds of websites.
+func WithDiscoverer(discoverer Discoverer) Option {
+	return func(s *Service) error {
+		if discoverer == nil {
+			return errors.New("nil discoverer")
+		}
+		s.discoverer = discoverer
+		return nil
+	}
+}
+
+// WithArchive enables archiving the documents feeds are parsed from, their
+// bodies being kept in the given storage for the retention, or forever when
+// it is 0. bodies holds the body store of every storage available, so that
+// payloads archived before switching storage can still be replayed and
+// pruned.
+func WithArchive(bodies map[domain.PayloadStorage]Archive, storage domain.PayloadStorage, retention time.Duration) Option {
+	return func(s *Service) error {
+		if !storage.Valid() {
+			return fmt.Errorf("unknown payload storage %q", storage)
+		}
+		if bodies[storage] == nil {
+			return fmt.Errorf("nil archive for %s storage", storage)
+		}
+		if retention < 0 {
+			return errors.New("negative archive retention")
+		}
+		s.archive = &archiveConfig{
+			bodies:    bodies,
+			storage:   storage,
+			retention: retention,
+		}
+		return nil
+	}
+}
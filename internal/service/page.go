@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jeffreyyong/news-feeder/internal/canonical"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/extract"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	"github.com/jeffreyyong/news-feeder/internal/media"
+	"go.uber.org/zap"
+)
+
+// pageConcurrency is how many pages of the articles of a feed are read at once.
+const pageConcurrency = 4
+
+// PageReader reads an article's page for its canonical URL, the image it is
+// shared with and its main content, in a single fetch.
+type PageReader interface {
+	ReadPage(ctx context.Context, pageURL string) (*extract.Page, error)
+}
+
+// pageConfig is nil, and no page is read while crawling, unless the service
+// is created WithCanonicalResolver or WithImageResolver.
+type pageConfig struct {
+	reader    PageReader
+	canonical bool
+	images    bool
+}
+
+// pageConfig returns the page config of the service, creating it around the
+// reader the first time.
+func (s *Service) pageConfig(reader PageReader) *pageConfig {
+	if s.pages == nil {
+		s.pages = &pageConfig{reader: reader}
+	}
+	return s.pages
+}
+
+// readPages reads the page of every article not stored yet, up to
+// pageConcurrency at once. It resolves their canonical URL and adds the
+// image they are shared with to their candidates as configured, and keeps
+// their content when it is extracted too, so that no page is fetched twice.
+// It runs ahead of storing the feed so that no page is fetched within a
+// transaction.
+func (s *Service) readPages(ctx context.Context, articles []*domain.Article) {
+	if s.pages == nil || len(articles) == 0 {
+		return
+	}
+
+	sem := make(chan struct{}, pageConcurrency)
+	var wg sync.WaitGroup
+	for _, a := range s.newArticles(ctx, articles) {
+		if a.Link == "" {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(a *domain.Article) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			s.readPage(ctx, a)
+		}(a)
+	}
+	wg.Wait()
+}
+
+func (s *Service) readPage(ctx context.Context, a *domain.Article) {
+	page, err := s.pages.reader.ReadPage(ctx, a.Link)
+	if err != nil {
+		logging.Error(ctx, "failed to read article page",
+			zap.String("link", a.Link),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if s.pages.canonical {
+		a.CanonicalURL = canonical.URL(page.Canonical)
+	}
+
+	if s.pages.images && page.Image != nil && !hasImage(a.Images, page.Image.URL) {
+		a.Images = append(a.Images, page.Image)
+		a.SetThumbnail(media.Best(a.Images))
+	}
+
+	if s.extraction != nil {
+		content := &domain.ArticleContent{Link: a.Link, Attempts: 1}
+		s.recordExtraction(content, page.Content, page.ContentErr)
+		a.Extracted = content
+	}
+}
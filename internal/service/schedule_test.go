@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	uuid "github.com/kevinburke/go.uuid"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// scheduleStore keeps a single source and the schedule it was last given,
+// the rest of Store is left unimplemented.
+type scheduleStore struct {
+	Store
+	source   *domain.Source
+	schedule *domain.SourceSchedule
+}
+
+func (s *scheduleStore) GetSource(ctx context.Context, id uuid.UUID) (*domain.Source, error) {
+	return s.source, nil
+}
+
+func (s *scheduleStore) ScheduleSource(ctx context.Context, id uuid.UUID, schedule *domain.SourceSchedule) error {
+	s.schedule = schedule
+	return nil
+}
+
+func TestScheduleSource(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		source domain.Source
+		result domain.CrawlResult
+		want   time.Duration
+	}{
+		{
+			name:   "feed not modified backs off",
+			source: domain.Source{Type: domain.SourceTypeFeed, ScheduledInterval: 3600},
+			result: domain.CrawlResult{NotModified: true},
+			want:   90 * time.Minute,
+		},
+		{
+			name:   "empty directory",
+			source: domain.Source{Type: domain.SourceTypeDirectory, ScheduledInterval: 3600},
+			result: domain.CrawlResult{NotModified: true},
+			want:   directoryPollInterval,
+		},
+		{
+			name:   "directory with a poll interval",
+			source: domain.Source{Type: domain.SourceTypeDirectory, PollInterval: 300},
+			result: domain.CrawlResult{NotModified: true},
+			want:   5 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.source
+			source.ID = uuid.NewV4()
+			store := &scheduleStore{source: &source}
+			s, err := New(store, nil, WithClock(clockwork.NewFakeClockAt(now)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := tt.result
+			result.SourceID = source.ID
+			if err := s.scheduleSource(context.Background(), &result); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := time.Duration(store.schedule.Interval) * time.Second; got != tt.want {
+				t.Errorf("interval = %v, want %v", got, tt.want)
+			}
+			if !store.schedule.NextPollAt.Equal(now.Add(tt.want)) {
+				t.Errorf("next poll at %v, want %v", store.schedule.NextPollAt, now.Add(tt.want))
+			}
+		})
+	}
+}
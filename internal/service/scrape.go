@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// Scraper extracts the items of the listing pages of scrape sources.
+type Scraper interface {
+	Validate(rules *domain.ScrapeRules) error
+	Preview(ctx context.Context, pageURL string, rules *domain.ScrapeRules) (*domain.ScrapePreview, error)
+}
+
+var errScrapingDisabled = errors.New("scraping is not enabled")
+
+// PreviewScrape scrapes the page with the rules without storing anything, for
+// tuning the rules of a scrape source.
+func (s *Service) PreviewScrape(ctx context.Context, req *domain.ScrapePreviewRequest) (*domain.ScrapePreview, error) {
+	if err := validateSourceURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := s.validateScrapeRules(req.ScrapeRules); err != nil {
+		return nil, err
+	}
+
+	preview, err := s.scraper.Preview(ctx, req.URL, req.ScrapeRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape page: %w", err)
+	}
+	return preview, nil
+}
+
+func (s *Service) validateScrapeRules(rules *domain.ScrapeRules) error {
+	if s.scraper == nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidSource, errScrapingDisabled)
+	}
+	if err := s.scraper.Validate(rules); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidSource, err)
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/localfeed"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	"github.com/jeffreyyong/news-feeder/internal/rss"
+	uuid "github.com/kevinburke/go.uuid"
+	"go.uber.org/zap"
+)
+
+// CreateSource registers a new source to be crawled.
+func (s *Service) CreateSource(ctx context.Context, source *domain.Source) (*domain.Source, error) {
+	if source.Type == "" {
+		source.Type = domain.SourceTypeFeed
+	}
+	if err := s.validateSource(source); err != nil {
+		return nil, err
+	}
+	if source.PollInterval < 0 {
+		return nil, fmt.Errorf("%w: negative poll interval", domain.ErrInvalidSource)
+	}
+
+	id, err := s.store.CreateSource(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create source: %w", err)
+	}
+
+	return s.GetSource(ctx, uuid.FromStringOrNil(id))
+}
+
+// GetSource returns a registered source.
+func (s *Service) GetSource(ctx context.Context, id uuid.UUID) (*domain.Source, error) {
+	source, err := s.store.GetSource(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source: %w", err)
+	}
+
+	return source, nil
+}
+
+// ListSources lists the registered sources.
+func (s *Service) ListSources(ctx context.Context, filters *domain.SelectSourceFilters) ([]*domain.Source, error) {
+	sources, err := s.store.SelectSources(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sources: %w", err)
+	}
+
+	return sources, nil
+}
+
+// UpdateSource changes the given fields of a registered source.
+func (s *Service) UpdateSource(ctx context.Context, id uuid.UUID, update *domain.SourceUpdate) (*domain.Source, error) {
+	if update.PollInterval != nil && *update.PollInterval < 0 {
+		return nil, fmt.Errorf("%w: negative poll interval", domain.ErrInvalidSource)
+	}
+	if update.URL != nil || update.Type != nil || update.ScrapeRules != nil || update.MappingRules != nil || update.Auth != nil {
+		if err := s.validateSourceUpdate(ctx, id, update); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.store.UpdateSource(ctx, id, update); err != nil {
+		return nil, fmt.Errorf("failed to update source: %w", err)
+	}
+
+	return s.GetSource(ctx, id)
+}
+
+// validateSourceUpdate checks the source as it would be after the update.
+func (s *Service) validateSourceUpdate(ctx context.Context, id uuid.UUID, update *domain.SourceUpdate) error {
+	source, err := s.GetSource(ctx, id)
+	if err != nil {
+		return err
+	}
+	if update.URL != nil {
+		source.URL = *update.URL
+	}
+	if update.Type != nil {
+		source.Type = *update.Type
+	}
+	if update.ScrapeRules != nil {
+		source.ScrapeRules = update.ScrapeRules
+	}
+	if update.MappingRules != nil {
+		source.MappingRules = update.MappingRules
+	}
+	if update.Auth != nil {
+		source.Auth = update.Auth
+		if update.Auth.Type == "" {
+			source.Auth = nil
+		}
+	}
+	return s.validateSource(source)
+}
+
+// validateSource checks the url of the source against its type: file:// urls
+// must be under the files root, directory sources must be one, and scrape
+// sources need valid rules. Credentials are only for sources fetched over http,
+// and mapping rules for feed and directory sources.
+func (s *Service) validateSource(source *domain.Source) error {
+	if !source.Type.Valid() {
+		return fmt.Errorf("%w: unknown type %q", domain.ErrInvalidSource, source.Type)
+	}
+
+	switch {
+	case localfeed.IsFileURL(source.URL):
+		if source.Type != domain.SourceTypeFeed && source.Type != domain.SourceTypeDirectory {
+			return fmt.Errorf("%w: file urls are only for feed and directory sources", domain.ErrInvalidSource)
+		}
+		if s.filesRoot == "" {
+			return fmt.Errorf("%w: local files are not enabled", domain.ErrInvalidSource)
+		}
+		if _, err := localfeed.Path(s.filesRoot, source.URL); err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrInvalidSource, err)
+		}
+	case source.Type == domain.SourceTypeDirectory:
+		return fmt.Errorf("%w: directory sources need a file url", domain.ErrInvalidSource)
+	default:
+		if err := validateSourceURL(source.URL); err != nil {
+			return err
+		}
+	}
+
+	if source.Auth != nil {
+		if localfeed.IsFileURL(source.URL) {
+			return fmt.Errorf("%w: credentials are only for http sources", domain.ErrInvalidSource)
+		}
+		if err := source.Auth.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrInvalidSource, err)
+		}
+	}
+
+	if source.MappingRules != nil && !source.MappingRules.Empty() {
+		if source.Type != domain.SourceTypeFeed && source.Type != domain.SourceTypeDirectory {
+			return fmt.Errorf("%w: mapping rules are only for feed and directory sources", domain.ErrInvalidSource)
+		}
+		if err := rss.ValidateMapping(source.MappingRules); err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrInvalidSource, err)
+		}
+	}
+
+	if source.Type == domain.SourceTypeScrape {
+		return s.validateScrapeRules(source.ScrapeRules)
+	}
+	return nil
+}
+
+// DeleteSource removes a registered source, the feeds and articles crawled from it are kept.
+func (s *Service) DeleteSource(ctx context.Context, id uuid.UUID) error {
+	if err := s.store.DeleteSource(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete source: %w", err)
+	}
+
+	return nil
+}
+
+// SeedSources registers the given sources as enabled feed sources, but only
+// on first boot when no source has been registered yet. It returns how many
+// were added.
+func (s *Service) SeedSources(ctx context.Context, sources []*domain.Source) (int, error) {
+	var seeded int
+
+	err := s.store.ExecInTransaction(ctx, func(ctx context.Context) error {
+		seeded = 0
+
+		n, err := s.store.CountSources(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to count sources: %w", err)
+		}
+		if n > 0 {
+			return nil
+		}
+
+		for _, source := range sources {
+			if err := validateSourceURL(source.URL); err != nil {
+				return err
+			}
+			seed := &domain.Source{
+				URL:      source.URL,
+				Type:     domain.SourceTypeFeed,
+				Enabled:  true,
+				Category: source.Category,
+				Provider: source.Provider,
+			}
+			if _, err := s.store.CreateSource(ctx, seed); err != nil {
+				return fmt.Errorf("failed to seed source (%s): %w", source.URL, err)
+			}
+			seeded++
+		}
+		return nil
+	})
+	return seeded, err
+}
+
+// ListSourceEvents lists the changes made to a source because of how it
+// answered, most recent first.
+func (s *Service) ListSourceEvents(ctx context.Context, id uuid.UUID) ([]*domain.SourceEvent, error) {
+	if _, err := s.GetSource(ctx, id); err != nil {
+		return nil, err
+	}
+
+	events, err := s.store.SelectSourceEvents(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query source events: %w", err)
+	}
+
+	return events, nil
+}
+
+// followSource disables a source that answered 410 Gone and points a source
+// that permanently redirected at its new url, recording an event for either.
+func (s *Service) followSource(ctx context.Context, result *domain.CrawlResult) {
+	if !result.Gone && result.MovedTo == "" {
+		return
+	}
+
+	source, err := s.store.GetSource(ctx, result.SourceID)
+	if err != nil {
+		logging.Error(ctx, "failed to get source", zap.String("source", result.Source), zap.Error(err))
+		return
+	}
+
+	var event *domain.SourceEvent
+	switch {
+	case result.Gone:
+		event, err = s.retireSource(ctx, source)
+	case result.MovedTo != source.URL:
+		event, err = s.moveSource(ctx, source, result.MovedTo)
+	}
+	if err != nil {
+		logging.Error(ctx, "failed to follow source",
+			zap.String("source", result.Source),
+			zap.Error(err),
+		)
+		return
+	}
+	if event == nil {
+		return
+	}
+
+	if _, err := s.store.CreateSourceEvent(ctx, event); err != nil {
+		logging.Error(ctx, "failed to record source event",
+			zap.String("source", result.Source),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s *Service) retireSource(ctx context.Context, source *domain.Source) (*domain.SourceEvent, error) {
+	if err := s.store.MarkSourceGone(ctx, source.ID, s.clock.Now()); err != nil {
+		return nil, err
+	}
+	logging.Print(ctx, "source is gone, disabled it", zap.String("source", source.URL))
+
+	return &domain.SourceEvent{
+		SourceID: source.ID,
+		Kind:     domain.SourceEventGone,
+		OldURL:   source.URL,
+	}, nil
+}
+
+// moveSource updates the url of the source. When another source already has
+// the new url the source is a duplicate of it and is disabled instead.
+func (s *Service) moveSource(ctx context.Context, source *domain.Source, movedTo string) (*domain.SourceEvent, error) {
+	if err := validateSourceURL(movedTo); err != nil {
+		return nil, err
+	}
+	event := &domain.SourceEvent{
+		SourceID: source.ID,
+		Kind:     domain.SourceEventMoved,
+		OldURL:   source.URL,
+		NewURL:   movedTo,
+	}
+
+	err := s.store.UpdateSource(ctx, source.ID, &domain.SourceUpdate{URL: &movedTo})
+	switch {
+	case errors.Is(err, domain.ErrSourceAlreadyExists):
+		enabled := false
+		if err := s.store.UpdateSource(ctx, source.ID, &domain.SourceUpdate{Enabled: &enabled}); err != nil {
+			return nil, err
+		}
+		event.Detail = "another source already has the new url, disabled this one"
+	case err != nil:
+		return nil, err
+	}
+	logging.Print(ctx, "source moved", zap.String("source", source.URL), zap.String("moved_to", movedTo))
+
+	return event, nil
+}
+
+func validateSourceURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidSource, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported url scheme %q", domain.ErrInvalidSource, u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%w: missing host", domain.ErrInvalidSource)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/fingerprint"
+	uuid "github.com/kevinburke/go.uuid"
+	"github.com/lib/pq"
+)
+
+// maxStoryCandidates bounds how many articles sharing a shingle with the new
+// articles of a feed they are compared with, the most recent ones.
+const maxStoryCandidates = 500
+
+// storyConfig is nil, and articles are not grouped into stories, unless the
+// service is created WithStoryClustering.
+type storyConfig struct {
+	similarity float64
+	window     time.Duration
+}
+
+// clusterStories groups each of the newly created articles of a feed of the
+// provider with its nearest duplicate of another feed and provider published
+// within the window, if any, joining the story of the duplicate or starting
+// one for the two of them. Articles without a publication date are left out.
+func (s *Service) clusterStories(ctx context.Context, feedID uuid.UUID, provider domain.Provider, articles []*domain.Article) error {
+	if s.stories == nil {
+		return nil
+	}
+
+	var (
+		dated    []*domain.Article
+		from, to time.Time
+		shingles pq.Int64Array
+	)
+	seen := make(map[int64]bool)
+	for _, a := range articles {
+		if a.PublishedAt.IsZero() || len(a.Shingles) == 0 {
+			continue
+		}
+		for _, shingle := range a.Shingles {
+			if !seen[shingle] {
+				seen[shingle] = true
+				shingles = append(shingles, shingle)
+			}
+		}
+		if len(dated) == 0 || a.PublishedAt.Before(from) {
+			from = a.PublishedAt
+		}
+		if len(dated) == 0 || a.PublishedAt.After(to) {
+			to = a.PublishedAt
+		}
+		dated = append(dated, a)
+	}
+	if len(dated) == 0 {
+		return nil
+	}
+	articles = dated
+
+	limit := uint64(maxStoryCandidates)
+	candidates, err := s.store.SelectStoryCandidates(ctx, &domain.SelectStoryCandidateFilters{
+		PublishedFrom: from.Add(-s.stories.window),
+		PublishedTo:   to.Add(s.stories.window),
+		Shingles:      shingles,
+		ExcludeFeedID: &feedID,
+		Limit:         &limit,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query story candidates: %w", err)
+	}
+
+	for _, a := range articles {
+		match := s.nearestDuplicate(a, provider, candidates)
+		if match == nil {
+			continue
+		}
+
+		if match.StoryID == nil {
+			storyID, err := s.startStory(ctx, match, a)
+			if err != nil {
+				return err
+			}
+			match.StoryID = &storyID
+		}
+
+		if err := s.store.AssignArticleStory(ctx, a.ID, *match.StoryID, a.PublishedAt); err != nil {
+			return err
+		}
+		a.StoryID = match.StoryID
+	}
+	return nil
+}
+
+// nearestDuplicate returns the candidate most similar to the article, as long
+// as it is similar enough, was published within the window of it and comes
+// from another feed and provider. Articles of a feed without a provider are
+// only kept apart from the articles of the same feed.
+func (s *Service) nearestDuplicate(article *domain.Article, provider domain.Provider, candidates []*domain.Article) *domain.Article {
+	var nearest *domain.Article
+	best := s.stories.similarity
+	for _, c := range candidates {
+		if c.ID == article.ID || c.FeedID == article.FeedID || sharesProvider(c, provider) {
+			continue
+		}
+
+		gap := c.PublishedAt.Sub(article.PublishedAt)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap > s.stories.window {
+			continue
+		}
+
+		if similarity := fingerprint.Similarity(article.Shingles, c.Shingles); similarity >= best {
+			nearest, best = c, similarity
+		}
+	}
+	return nearest
+}
+
+func sharesProvider(article *domain.Article, provider domain.Provider) bool {
+	if provider == "" {
+		return false
+	}
+	for _, p := range article.Providers {
+		if p == string(provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// startStory creates a story for two near-duplicates, titled after the
+// earlier of them, and assigns the existing article to it.
+func (s *Service) startStory(ctx context.Context, existing, article *domain.Article) (uuid.UUID, error) {
+	story := &domain.Story{
+		Title:         existing.Title,
+		LastArticleAt: article.PublishedAt,
+	}
+	if article.PublishedAt.Before(existing.PublishedAt) {
+		story.Title, story.LastArticleAt = article.Title, existing.PublishedAt
+	}
+
+	id, err := s.store.CreateStory(ctx, story)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create story: %w", err)
+	}
+	storyID, _ := uuid.FromString(id)
+
+	if err := s.store.AssignArticleStory(ctx, existing.ID, storyID, existing.PublishedAt); err != nil {
+		return uuid.Nil, err
+	}
+	return storyID, nil
+}
+
+// ListStories lists the stories, the most recently active first, each with
+// its articles, the most recent first.
+func (s *Service) ListStories(ctx context.Context, filters *domain.SelectStoryFilters) ([]*domain.Story, error) {
+	stories, err := s.store.SelectStories(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stories: %w", err)
+	}
+	if len(stories) == 0 {
+		return stories, nil
+	}
+
+	ids := make([]uuid.UUID, len(stories))
+	byID := make(map[uuid.UUID]*domain.Story, len(stories))
+	for i, story := range stories {
+		ids[i] = story.ID
+		byID[story.ID] = story
+		story.Articles = []*domain.Article{}
+	}
+
+	articles, err := s.store.SelectArticles(ctx, &domain.SelectArticleFilters{StoryIDs: ids})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query story articles: %w", err)
+	}
+	if err := s.attachImages(ctx, articles); err != nil {
+		return nil, err
+	}
+	for _, a := range articles {
+		if a.StoryID == nil {
+			continue
+		}
+		if story, ok := byID[*a.StoryID]; ok {
+			story.Articles = append(story.Articles, a)
+		}
+	}
+
+	return stories, nil
+}
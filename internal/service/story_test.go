@@ -0,0 +1,90 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/fingerprint"
+	uuid "github.com/kevinburke/go.uuid"
+	"github.com/lib/pq"
+)
+
+func TestNearestDuplicate(t *testing.T) {
+	s := &Service{stories: &storyConfig{similarity: 0.5, window: 48 * time.Hour}}
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	ourFeed, otherFeed := uuid.NewV4(), uuid.NewV4()
+
+	article := &domain.Article{
+		ID:          uuid.NewV4(),
+		FeedID:      ourFeed,
+		PublishedAt: now,
+		Shingles:    fingerprint.Shingles("Storm hits the south coast overnight"),
+	}
+
+	candidate := func(feedID uuid.UUID, providers []string, at time.Time, text string) *domain.Article {
+		return &domain.Article{
+			ID:          uuid.NewV4(),
+			FeedID:      feedID,
+			Providers:   pq.StringArray(providers),
+			PublishedAt: at,
+			Shingles:    fingerprint.Shingles(text),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		provider  domain.Provider
+		candidate *domain.Article
+		match     bool
+	}{
+		{
+			name:      "other provider",
+			provider:  "bbc",
+			candidate: candidate(otherFeed, []string{"sky"}, now.Add(time.Hour), "Storm hits south coast overnight"),
+			match:     true,
+		},
+		{
+			name:      "same feed",
+			provider:  "bbc",
+			candidate: candidate(ourFeed, []string{"bbc"}, now.Add(time.Hour), "Storm hits south coast overnight"),
+		},
+		{
+			name:      "same provider, other feed",
+			provider:  "bbc",
+			candidate: candidate(otherFeed, []string{"bbc"}, now.Add(time.Hour), "Storm hits south coast overnight"),
+		},
+		{
+			name:      "carried by our provider among others",
+			provider:  "bbc",
+			candidate: candidate(otherFeed, []string{"bbc", "sky"}, now.Add(time.Hour), "Storm hits south coast overnight"),
+		},
+		{
+			name:      "no provider, other feed",
+			candidate: candidate(otherFeed, nil, now.Add(time.Hour), "Storm hits south coast overnight"),
+			match:     true,
+		},
+		{
+			name:      "outside the window",
+			provider:  "bbc",
+			candidate: candidate(otherFeed, []string{"sky"}, now.Add(-72*time.Hour), "Storm hits south coast overnight"),
+		},
+		{
+			name:      "not similar enough",
+			provider:  "bbc",
+			candidate: candidate(otherFeed, []string{"sky"}, now, "Budget vote delayed again"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.nearestDuplicate(article, tt.provider, []*domain.Article{article, tt.candidate})
+			if (got != nil) != tt.match {
+				t.Errorf("nearestDuplicate = %v, want a match %v", got, tt.match)
+			}
+			if got != nil && got != tt.candidate {
+				t.Errorf("nearestDuplicate returned the article itself")
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// ListCategories lists the categories of the taxonomy.
+func (s *Service) ListCategories(ctx context.Context) ([]*domain.CategoryInfo, error) {
+	categories, err := s.store.SelectCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// ListProviders lists the providers of the taxonomy.
+func (s *Service) ListProviders(ctx context.Context) ([]*domain.ProviderInfo, error) {
+	providers, err := s.store.SelectProviders(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query providers: %w", err)
+	}
+
+	return providers, nil
+}
+
+// PutCategory creates or updates a category of the taxonomy.
+func (s *Service) PutCategory(ctx context.Context, category *domain.CategoryInfo) error {
+	if !domain.ValidSlug(string(category.Slug)) {
+		return fmt.Errorf("%w: bad slug %q", domain.ErrInvalidTaxonomy, category.Slug)
+	}
+	if category.DisplayName == "" {
+		return fmt.Errorf("%w: missing display name", domain.ErrInvalidTaxonomy)
+	}
+
+	if err := s.store.PutCategory(ctx, category); err != nil {
+		return fmt.Errorf("failed to put category: %w", err)
+	}
+
+	return nil
+}
+
+// PutProvider creates or updates a provider of the taxonomy.
+func (s *Service) PutProvider(ctx context.Context, provider *domain.ProviderInfo) error {
+	if !domain.ValidSlug(string(provider.Slug)) {
+		return fmt.Errorf("%w: bad slug %q", domain.ErrInvalidTaxonomy, provider.Slug)
+	}
+	if provider.DisplayName == "" {
+		return fmt.Errorf("%w: missing display name", domain.ErrInvalidTaxonomy)
+	}
+
+	if err := s.store.PutProvider(ctx, provider); err != nil {
+		return fmt.Errorf("failed to put provider: %w", err)
+	}
+
+	return nil
+}
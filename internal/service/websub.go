@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	"github.com/jeffreyyong/news-feeder/internal/websub"
+	uuid "github.com/kevinburke/go.uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// a failed subscription request is retried after subscribeRetryBase,
+	// then twice as long every time up to subscribeRetryMax, and given up on
+	// after maxSubscribeAttempts requests
+	subscribeRetryBase   = time.Minute
+	subscribeRetryMax    = 24 * time.Hour
+	maxSubscribeAttempts = 10
+
+	// a request the hub accepted but has not verified within
+	// subscribeVerifyTimeout counts as a failed attempt and is sent again
+	subscribeVerifyTimeout = 10 * time.Minute
+)
+
+// WebSubClient sends subscription requests to hubs.
+type WebSubClient interface {
+	Subscribe(ctx context.Context, req websub.SubscriptionRequest) error
+	Unsubscribe(ctx context.Context, req websub.SubscriptionRequest) error
+}
+
+// webSubConfig holds what is needed to subscribe to hubs. It is nil, and
+// feeds are only ever polled, unless the service is created WithWebSub.
+type webSubConfig struct {
+	client       WebSubClient
+	callbackURL  string
+	leaseSeconds int
+	renewBefore  time.Duration
+}
+
+// ensureSubscription subscribes to the first hub a freshly stored feed
+// advertises, unless the source is already subscribed to it. Failures are
+// recorded on the subscription and retried by RenewSubscriptions.
+func (s *Service) ensureSubscription(ctx context.Context, sourceID uuid.UUID, feed *domain.Feed) {
+	if s.websub == nil || len(feed.Hubs) == 0 {
+		return
+	}
+
+	hub := feed.Hubs[0]
+	topic := feed.SelfURL
+	if topic == "" {
+		topic = feed.FeedLink
+	}
+
+	subs, err := s.store.SelectWebSubSubscriptions(ctx, &domain.SelectWebSubSubscriptionFilters{
+		SourceIDs: []uuid.UUID{sourceID},
+	})
+	if err != nil {
+		logging.Error(ctx, "failed to query websub subscriptions", zap.Error(err))
+		return
+	}
+
+	var sub *domain.WebSubSubscription
+	if len(subs) > 0 {
+		sub = subs[0]
+		if sub.Hub == hub && sub.Topic == topic && sub.Status != domain.SubscriptionStatusUnsubscribed {
+			return
+		}
+		sub.Hub = hub
+		sub.Topic = topic
+		// a new hub or topic is given its own attempts
+		sub.Attempts = 0
+		sub.NextAttemptAt = nil
+	} else {
+		sub = &domain.WebSubSubscription{
+			SourceID:     sourceID,
+			Hub:          hub,
+			Topic:        topic,
+			Status:       domain.SubscriptionStatusPending,
+			LeaseSeconds: s.websub.leaseSeconds,
+		}
+		id, err := s.store.CreateWebSubSubscription(ctx, sub)
+		if err != nil {
+			logging.Error(ctx, "failed to create websub subscription", zap.Error(err))
+			return
+		}
+		sub.ID, _ = uuid.FromString(id)
+	}
+
+	s.subscribe(ctx, sub)
+}
+
+// subscribe (re)sends a subscription request to the hub with a fresh secret.
+// The subscription stays pending until the hub verifies the intent, the last
+// verified secret staying valid meanwhile. A request that fails, or is not
+// verified in time, is retried with a backoff by RenewSubscriptions.
+func (s *Service) subscribe(ctx context.Context, sub *domain.WebSubSubscription) {
+	secret, err := newSecret()
+	if err != nil {
+		logging.Error(ctx, "failed to generate websub secret", zap.Error(err))
+		return
+	}
+	// while a renewal is unverified the previous secret is the one verified
+	if sub.PreviousSecret == "" {
+		sub.PreviousSecret = sub.Secret
+	}
+	sub.Secret = secret
+	sub.Status = domain.SubscriptionStatusPending
+	sub.LeaseSeconds = s.websub.leaseSeconds
+	sub.LastError = ""
+	// set before sending, as the hub may verify before the request returns
+	sub.Attempts++
+	retryAt := s.clock.Now().Add(subscribeRetryDelay(sub.Attempts))
+	if verifyBy := s.clock.Now().Add(subscribeVerifyTimeout); retryAt.Before(verifyBy) {
+		retryAt = verifyBy
+	}
+	sub.NextAttemptAt = &retryAt
+
+	// the secret must be stored before the hub starts signing pushes with it
+	if err := s.store.UpdateWebSubSubscription(ctx, sub); err != nil {
+		logging.Error(ctx, "failed to update websub subscription", zap.Error(err))
+		return
+	}
+
+	err = s.websub.client.Subscribe(ctx, websub.SubscriptionRequest{
+		Hub:          sub.Hub,
+		Topic:        sub.Topic,
+		Callback:     s.callbackURL(sub.ID),
+		Secret:       sub.Secret,
+		LeaseSeconds: sub.LeaseSeconds,
+	})
+	if err == nil {
+		return
+	}
+
+	logging.Error(ctx, "failed to subscribe to websub hub",
+		zap.String("hub", sub.Hub),
+		zap.String("topic", sub.Topic),
+		zap.Error(err),
+	)
+	s.failSubscription(ctx, sub, err.Error())
+}
+
+// failSubscription records a failed subscription attempt, to be retried after
+// a backoff until maxSubscribeAttempts were made.
+func (s *Service) failSubscription(ctx context.Context, sub *domain.WebSubSubscription, reason string) {
+	sub.Status = domain.SubscriptionStatusFailed
+	sub.LastError = reason
+	sub.NextAttemptAt = nil
+	if sub.Attempts < maxSubscribeAttempts {
+		next := s.clock.Now().Add(subscribeRetryDelay(sub.Attempts))
+		sub.NextAttemptAt = &next
+	} else {
+		logging.Error(ctx, "giving up on websub hub",
+			zap.String("hub", sub.Hub),
+			zap.String("topic", sub.Topic),
+			zap.Int("attempts", sub.Attempts),
+		)
+	}
+	if err := s.store.UpdateWebSubSubscription(ctx, sub); err != nil {
+		logging.Error(ctx, "failed to update websub subscription", zap.Error(err))
+	}
+}
+
+// subscribeRetryDelay is how long to wait after the given number of failed
+// subscription requests in a row.
+func subscribeRetryDelay(attempts int) time.Duration {
+	delay := subscribeRetryBase
+	for i := 1; i < attempts && delay < subscribeRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > subscribeRetryMax {
+		delay = subscribeRetryMax
+	}
+	return delay
+}
+
+func (s *Service) callbackURL(id uuid.UUID) string {
+	return strings.TrimRight(s.websub.callbackURL, "/") + "/" + id.String()
+}
+
+// VerifyIntent answers a hub verifying that we asked for the subscription
+// (un)subscribe request it received, returning the challenge to echo back.
+// A denied request is recorded and answers with an empty challenge. A hub
+// that does not tell the lease it granted is taken to grant the one asked for.
+func (s *Service) VerifyIntent(ctx context.Context, id uuid.UUID, mode, topic, challenge string, leaseSeconds int, reason string) (string, error) {
+	sub, err := s.store.GetWebSubSubscription(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get websub subscription: %w", err)
+	}
+	if topic != sub.Topic {
+		return "", domain.ErrTopicMismatch
+	}
+
+	switch mode {
+	case websub.ModeSubscribe:
+		if sub.Status == domain.SubscriptionStatusUnsubscribed {
+			return "", domain.ErrSubscriptionNotFound
+		}
+		if leaseSeconds <= 0 {
+			leaseSeconds = sub.LeaseSeconds
+		}
+		if leaseSeconds <= 0 {
+			leaseSeconds = s.websub.leaseSeconds
+		}
+		expiresAt := s.clock.Now().Add(time.Duration(leaseSeconds) * time.Second)
+		sub.Status = domain.SubscriptionStatusActive
+		sub.LeaseSeconds = leaseSeconds
+		sub.LeaseExpiresAt = &expiresAt
+		sub.LastError = ""
+		// the hub signs with the secret of the request it verified from now on
+		sub.PreviousSecret = ""
+		sub.Attempts = 0
+		sub.NextAttemptAt = nil
+	case websub.ModeUnsubscribe:
+		if sub.Status != domain.SubscriptionStatusUnsubscribed {
+			return "", domain.ErrSubscriptionNotFound
+		}
+		sub.LeaseExpiresAt = nil
+	case websub.ModeDenied:
+		sub.Status = domain.SubscriptionStatusDenied
+		sub.LastError = reason
+		challenge = ""
+	default:
+		return "", fmt.Errorf("unknown hub mode %q", mode)
+	}
+
+	if err := s.store.UpdateWebSubSubscription(ctx, sub); err != nil {
+		return "", fmt.Errorf("failed to update websub subscription: %w", err)
+	}
+	return challenge, nil
+}
+
+// HandlePush verifies the signature of content a hub pushed for a
+// subscription and stores it the same way CrawlFeeds stores a fetched feed,
+// as a crawl run of its own.
+func (s *Service) HandlePush(ctx context.Context, id uuid.UUID, signature string, body []byte) error {
+	sub, err := s.store.GetWebSubSubscription(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get websub subscription: %w", err)
+	}
+	if !websub.VerifySignature(sub.Secret, signature, body) &&
+		(sub.PreviousSecret == "" || !websub.VerifySignature(sub.PreviousSecret, signature, body)) {
+		return domain.ErrInvalidSignature
+	}
+
+	source, err := s.store.GetSource(ctx, sub.SourceID)
+	if err != nil {
+		return fmt.Errorf("failed to get source: %w", err)
+	}
+	if !source.Enabled {
+		logging.Print(ctx, "ignoring websub push for disabled source", zap.String("source", source.URL))
+		return nil
+	}
+
+	run, err := s.startCrawlRun(ctx, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	result := s.crawler.Ingest(ctx, source, body)
+	if result.Feed != nil {
+		result.Feed.Payload = &domain.Payload{
+			Origin: domain.PayloadOriginPush,
+			URL:    source.URL,
+			Body:   body,
+		}
+	}
+	s.recordCrawlResult(ctx, run, result)
+	return s.finishCrawlRun(ctx, run, nil)
+}
+
+// RenewSubscriptions renews the leases running out within the renewal window
+// and retries the failed subscriptions, and those the hub did not verify in
+// time, that are due until they are given up on. Subscriptions of disabled
+// sources are unsubscribed from instead.
+func (s *Service) RenewSubscriptions(ctx context.Context) error {
+	if s.websub == nil {
+		return nil
+	}
+
+	subs, err := s.store.SelectWebSubSubscriptions(ctx, &domain.SelectWebSubSubscriptionFilters{
+		Statuses: []domain.SubscriptionStatus{
+			domain.SubscriptionStatusActive,
+			domain.SubscriptionStatusPending,
+			domain.SubscriptionStatusFailed,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query websub subscriptions: %w", err)
+	}
+
+	if len(subs) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.SourceID
+	}
+	sources, err := s.store.SelectSources(ctx, &domain.SelectSourceFilters{IDs: ids})
+	if err != nil {
+		return fmt.Errorf("failed to query sources: %w", err)
+	}
+	byID := make(map[uuid.UUID]*domain.Source, len(sources))
+	for _, source := range sources {
+		byID[source.ID] = source
+	}
+
+	now := s.clock.Now()
+	renewBy := now.Add(s.websub.renewBefore)
+	for _, sub := range subs {
+		source, ok := byID[sub.SourceID]
+		if !ok {
+			logging.Error(ctx, "failed to get source",
+				zap.String("source_id", sub.SourceID.String()),
+				zap.Error(domain.ErrSourceNotFound),
+			)
+			continue
+		}
+
+		if !source.Enabled {
+			s.unsubscribe(ctx, sub)
+			continue
+		}
+
+		if sub.Status == domain.SubscriptionStatusActive && sub.LeaseExpiresAt != nil && sub.LeaseExpiresAt.After(renewBy) {
+			continue
+		}
+		if sub.Status == domain.SubscriptionStatusFailed && (sub.NextAttemptAt == nil || sub.NextAttemptAt.After(now)) {
+			continue
+		}
+		if sub.Status == domain.SubscriptionStatusPending {
+			if sub.NextAttemptAt != nil && sub.NextAttemptAt.After(now) {
+				continue
+			}
+			if sub.Attempts >= maxSubscribeAttempts {
+				s.failSubscription(ctx, sub, "hub did not verify the subscription")
+				continue
+			}
+		}
+		s.subscribe(ctx, sub)
+	}
+	return nil
+}
+
+func (s *Service) unsubscribe(ctx context.Context, sub *domain.WebSubSubscription) {
+	sub.Status = domain.SubscriptionStatusUnsubscribed
+	if err := s.store.UpdateWebSubSubscription(ctx, sub); err != nil {
+		logging.Error(ctx, "failed to update websub subscription", zap.Error(err))
+		return
+	}
+
+	err := s.websub.client.Unsubscribe(ctx, websub.SubscriptionRequest{
+		Hub:      sub.Hub,
+		Topic:    sub.Topic,
+		Callback: s.callbackURL(sub.ID),
+	})
+	if err != nil {
+		logging.Error(ctx, "failed to unsubscribe from websub hub",
+			zap.String("hub", sub.Hub),
+			zap.String("topic", sub.Topic),
+			zap.Error(err),
+		)
+	}
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	uuid "github.com/kevinburke/go.uuid"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/websub"
+)
+
+// webSubStore keeps subscriptions and sources in memory, the rest of Store
+// is left unimplemented.
+type webSubStore struct {
+	Store
+	subs    map[uuid.UUID]*domain.WebSubSubscription
+	sources map[uuid.UUID]*domain.Source
+}
+
+func (s *webSubStore) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (*domain.WebSubSubscription, error) {
+	sub, ok := s.subs[id]
+	if !ok {
+		return nil, domain.ErrSubscriptionNotFound
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+func (s *webSubStore) SelectWebSubSubscriptions(ctx context.Context, f *domain.SelectWebSubSubscriptionFilters) ([]*domain.WebSubSubscription, error) {
+	var subs []*domain.WebSubSubscription
+	for _, sub := range s.subs {
+		for _, status := range f.Statuses {
+			if sub.Status == status {
+				copied := *sub
+				subs = append(subs, &copied)
+			}
+		}
+	}
+	return subs, nil
+}
+
+func (s *webSubStore) UpdateWebSubSubscription(ctx context.Context, sub *domain.WebSubSubscription) error {
+	copied := *sub
+	s.subs[sub.ID] = &copied
+	return nil
+}
+
+func (s *webSubStore) SelectSources(ctx context.Context, f *domain.SelectSourceFilters) ([]*domain.Source, error) {
+	var sources []*domain.Source
+	for _, id := range f.IDs {
+		if source, ok := s.sources[id]; ok {
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+// stubHub accepts every subscription request, or fails them all with err.
+type stubHub struct {
+	err        error
+	subscribed []websub.SubscriptionRequest
+}
+
+func (h *stubHub) Subscribe(ctx context.Context, req websub.SubscriptionRequest) error {
+	h.subscribed = append(h.subscribed, req)
+	return h.err
+}
+
+func (h *stubHub) Unsubscribe(ctx context.Context, req websub.SubscriptionRequest) error {
+	return nil
+}
+
+func newWebSubService(t *testing.T, store *webSubStore, hub *stubHub, clock clockwork.Clock) *Service {
+	s, err := New(store, nil, WithClock(clock), WithWebSub(hub, "https://news.example.com/websub/callback", 86400, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestVerifyIntentLease(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		requested int
+		granted   int
+		want      time.Duration
+	}{
+		{name: "granted by the hub", requested: 3600, granted: 600, want: 600 * time.Second},
+		{name: "missing takes the requested lease", requested: 3600, granted: 0, want: time.Hour},
+		{name: "missing without a requested lease takes the configured one", requested: 0, granted: 0, want: 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := &domain.WebSubSubscription{
+				ID:           uuid.NewV4(),
+				Topic:        "https://example.com/feed.xml",
+				Status:       domain.SubscriptionStatusPending,
+				LeaseSeconds: tt.requested,
+			}
+			store := &webSubStore{subs: map[uuid.UUID]*domain.WebSubSubscription{sub.ID: sub}}
+			s := newWebSubService(t, store, &stubHub{}, clockwork.NewFakeClockAt(now))
+
+			challenge, err := s.VerifyIntent(context.Background(), sub.ID, websub.ModeSubscribe, sub.Topic, "challenge", tt.granted, "")
+			if err != nil || challenge != "challenge" {
+				t.Fatalf("VerifyIntent = %q, %v", challenge, err)
+			}
+
+			got := store.subs[sub.ID]
+			if got.Status != domain.SubscriptionStatusActive {
+				t.Errorf("status = %s", got.Status)
+			}
+			if got.LeaseExpiresAt == nil || !got.LeaseExpiresAt.Equal(now.Add(tt.want)) {
+				t.Errorf("lease expires at %v, want %v", got.LeaseExpiresAt, now.Add(tt.want))
+			}
+		})
+	}
+}
+
+func TestRenewSubscriptions(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name       string
+		sub        domain.WebSubSubscription
+		hubErr     error
+		resent     bool
+		wantStatus domain.SubscriptionStatus
+	}{
+		{
+			name:       "active lease far from expiry",
+			sub:        domain.WebSubSubscription{Status: domain.SubscriptionStatusActive, LeaseExpiresAt: at(48 * time.Hour)},
+			wantStatus: domain.SubscriptionStatusActive,
+		},
+		{
+			name:       "active lease about to expire",
+			sub:        domain.WebSubSubscription{Status: domain.SubscriptionStatusActive, LeaseExpiresAt: at(30 * time.Minute)},
+			resent:     true,
+			wantStatus: domain.SubscriptionStatusPending,
+		},
+		{
+			name:       "pending within the verification timeout",
+			sub:        domain.WebSubSubscription{Status: domain.SubscriptionStatusPending, Attempts: 1, NextAttemptAt: at(time.Minute)},
+			wantStatus: domain.SubscriptionStatusPending,
+		},
+		{
+			name:       "pending never verified",
+			sub:        domain.WebSubSubscription{Status: domain.SubscriptionStatusPending, Attempts: 1, NextAttemptAt: at(-time.Minute)},
+			resent:     true,
+			wantStatus: domain.SubscriptionStatusPending,
+		},
+		{
+			name:       "pending without a deadline",
+			sub:        domain.WebSubSubscription{Status: domain.SubscriptionStatusPending},
+			resent:     true,
+			wantStatus: domain.SubscriptionStatusPending,
+		},
+		{
+			name:       "pending never verified after the last attempt",
+			sub:        domain.WebSubSubscription{Status: domain.SubscriptionStatusPending, Attempts: maxSubscribeAttempts, NextAttemptAt: at(-time.Minute)},
+			wantStatus: domain.SubscriptionStatusFailed,
+		},
+		{
+			name:       "failed and due",
+			sub:        domain.WebSubSubscription{Status: domain.SubscriptionStatusFailed, Attempts: 2, NextAttemptAt: at(-time.Minute)},
+			hubErr:     errors.New("hub down"),
+			resent:     true,
+			wantStatus: domain.SubscriptionStatusFailed,
+		},
+		{
+			name:       "failed and backing off",
+			sub:        domain.WebSubSubscription{Status: domain.SubscriptionStatusFailed, Attempts: 2, NextAttemptAt: at(time.Minute)},
+			wantStatus: domain.SubscriptionStatusFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &domain.Source{ID: uuid.NewV4(), URL: "https://example.com/feed.xml", Enabled: true}
+			sub := tt.sub
+			sub.ID, sub.SourceID, sub.Hub, sub.Topic = uuid.NewV4(), source.ID, "https://hub.example.com/", source.URL
+			store := &webSubStore{
+				subs:    map[uuid.UUID]*domain.WebSubSubscription{sub.ID: &sub},
+				sources: map[uuid.UUID]*domain.Source{source.ID: source},
+			}
+			hub := &stubHub{err: tt.hubErr}
+			s := newWebSubService(t, store, hub, clockwork.NewFakeClockAt(now))
+
+			if err := s.RenewSubscriptions(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if resent := len(hub.subscribed) > 0; resent != tt.resent {
+				t.Errorf("resent = %v, want %v", resent, tt.resent)
+			}
+			got := store.subs[sub.ID]
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if tt.resent {
+				if got.Attempts != sub.Attempts+1 {
+					t.Errorf("attempts = %d, want %d", got.Attempts, sub.Attempts+1)
+				}
+				if got.NextAttemptAt == nil || got.NextAttemptAt.Before(now.Add(subscribeRetryDelay(got.Attempts))) {
+					t.Errorf("next attempt at %v", got.NextAttemptAt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package sitemap
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/fetch"
+	"github.com/jeffreyyong/news-feeder/internal/media"
+)
+
+// sitemapAccept is the Accept header sitemaps are requested with.
+const sitemapAccept = "application/xml, text/xml;q=0.9, application/x-gzip;q=0.8, */*;q=0.5"
+
+const (
+	// only the most recently modified sitemaps of an index are read, news
+	// sitemaps are split by day and anything older is already known
+	defaultMaxSitemaps = 5
+	// entries of a standard sitemap are capped as it may list a whole site
+	defaultMaxArticles = 1000
+)
+
+type Parser struct {
+	client      fetch.Doer
+	maxSize     int64
+	maxSitemaps int
+	maxArticles int
+	clock       clockwork.Clock
+}
+
+type Option func(*Parser)
+
+// WithClock sets the clock the publication date of undated entries is read from.
+func WithClock(clock clockwork.Clock) Option {
+	return func(p *Parser) {
+		p.clock = clock
+	}
+}
+
+// WithMaxSize caps the size of the sitemaps fetched, once decompressed.
+func WithMaxSize(maxSize int64) Option {
+	return func(p *Parser) {
+		if maxSize > 0 {
+			p.maxSize = maxSize
+		}
+	}
+}
+
+// WithMaxSitemaps caps how many sitemaps of a sitemap index are read.
+func WithMaxSitemaps(n int) Option {
+	return func(p *Parser) {
+		if n > 0 {
+			p.maxSitemaps = n
+		}
+	}
+}
+
+func NewParser(client fetch.Doer, opts ...Option) *Parser {
+	p := &Parser{
+		client:      client,
+		maxSize:     fetch.DefaultMaxDocumentSize,
+		maxSitemaps: defaultMaxSitemaps,
+		maxArticles: defaultMaxArticles,
+		clock:       clockwork.NewRealClock(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Parse fetches the sitemap of the source and maps its entries into the
+// articles of a feed, the same way rss.Parser does for feeds. A sitemap index
+// is followed to the sitemaps it lists that were modified last. Validators,
+// domain.ErrNotModified, domain.ErrSourceGone and permanent redirects are
+// handled as they are for feeds, for the source's own document.
+func (p *Parser) Parse(ctx context.Context, source *domain.Source) (*domain.Feed, error) {
+	doc, err := fetch.Get(ctx, p.client, fetch.Request{
+		URL:          source.URL,
+		ETag:         source.ETag,
+		LastModified: source.LastModified,
+		Auth:         source.Auth,
+		Accept:       sitemapAccept,
+		MaxSize:      p.maxSize,
+	})
+	if err != nil {
+		return nil, fetch.SourceError(err)
+	}
+
+	sitemap, err := p.decode(doc)
+	if err != nil {
+		return nil, doc.SourceError(err)
+	}
+
+	entries := sitemap.URLs
+	if sitemap.XMLName.Local == rootSitemapIndex {
+		entries, err = p.readIndex(ctx, doc.URL, sitemap.Sitemaps, source.Auth)
+		if err != nil {
+			return nil, doc.SourceError(err)
+		}
+	}
+
+	feed := p.mapFeed(source, doc.URL, entries)
+	feed.ETag = doc.Header.Get("ETag")
+	feed.LastModified = doc.Header.Get("Last-Modified")
+	if doc.MovedTo != "" {
+		feed.MovedTo = doc.MovedTo
+		feed.FeedLink = doc.MovedTo
+	}
+
+	return feed, nil
+}
+
+func (p *Parser) decode(doc *fetch.Document) (*document, error) {
+	body, err := gunzip(doc.Body, p.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	doc.Body = body
+
+	body, err = doc.UTF8()
+	if err != nil {
+		return nil, err
+	}
+	return decode(body)
+}
+
+// readIndex reads the entries of the most recently modified sitemaps of an
+// index. Nested indexes are not followed. It only fails when none of the
+// sitemaps could be read. The credentials of the source are only sent along
+// to the sitemaps of the same origin as the index.
+func (p *Parser) readIndex(ctx context.Context, base string, refs []reference, auth *domain.SourceAuth) ([]entry, error) {
+	refs = append([]reference(nil), refs...)
+	sort.SliceStable(refs, func(i, j int) bool {
+		ti, _ := parseDate(refs[i].LastMod)
+		tj, _ := parseDate(refs[j].LastMod)
+		return ti.After(tj)
+	})
+	if len(refs) > p.maxSitemaps {
+		refs = refs[:p.maxSitemaps]
+	}
+
+	var (
+		entries  []entry
+		firstErr error
+		read     int
+	)
+	for _, ref := range refs {
+		loc, ok := resolve(base, ref.Loc)
+		if !ok {
+			continue
+		}
+
+		var locAuth *domain.SourceAuth
+		if sameOrigin(base, loc) {
+			locAuth = auth
+		}
+		sitemap, err := p.fetchSitemap(ctx, loc, locAuth)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error reading sitemap (%s): %w", loc, err)
+			}
+			continue
+		}
+		read++
+		entries = append(entries, sitemap.URLs...)
+	}
+
+	if read == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return entries, nil
+}
+
+func (p *Parser) fetchSitemap(ctx context.Context, loc string, auth *domain.SourceAuth) (*document, error) {
+	doc, err := fetch.Get(ctx, p.client, fetch.Request{
+		URL:     loc,
+		Accept:  sitemapAccept,
+		MaxSize: p.maxSize,
+		Auth:    auth,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.decode(doc)
+}
+
+// mapFeed maps the entries into the articles of a feed, the most recent
+// first. Google News entries carry their title, publication date and
+// keywords; standard ones only their link, last modification and images, so
+// they are titled after their link, with a warning. Entries without any date
+// are published at the time of the crawl, as undated feed items are.
+func (p *Parser) mapFeed(source *domain.Source, base string, entries []entry) *domain.Feed {
+	now := p.clock.Now()
+	untitled := 0
+
+	feed := &domain.Feed{
+		Title:    hostname(base),
+		Link:     homepage(base),
+		FeedLink: source.URL,
+		Category: source.Category,
+		Provider: source.Provider,
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		link, ok := resolve(base, e.Loc)
+		if !ok || seen[link] {
+			continue
+		}
+		seen[link] = true
+
+		article := &domain.Article{
+			Link: link,
+			GUID: link,
+		}
+
+		lastMod, hasLastMod := parseDate(e.LastMod)
+		if hasLastMod {
+			article.PublishedAt = lastMod
+			article.ItemUpdatedAt = &lastMod
+		}
+
+		if n := e.News; n != nil {
+			article.Title = strings.TrimSpace(n.Title)
+			article.Tags = splitKeywords(n.Keywords)
+			if publishedAt, ok := parseDate(n.PublicationDate); ok {
+				article.PublishedAt = publishedAt
+			}
+			if name := strings.TrimSpace(n.Publication.Name); name != "" {
+				feed.Title = name
+			}
+			if lang := strings.TrimSpace(n.Publication.Language); lang != "" {
+				feed.Language = strings.ToLower(lang)
+			}
+		}
+
+		if article.PublishedAt.IsZero() {
+			article.PublishedAt = now
+		}
+		if article.Title == "" {
+			untitled++
+			article.Title = pathTitle(link)
+		}
+
+		images := make([]string, 0, len(e.Images))
+		for _, img := range e.Images {
+			images = append(images, img.Loc)
+		}
+		article.Images = media.FromURLs(link, domain.ImageOriginSitemap, images...)
+		article.SetThumbnail(media.Best(article.Images))
+
+		if article.PublishedAt.After(feed.UpdatedAt) {
+			feed.UpdatedAt = article.PublishedAt
+		}
+		feed.Articles = append(feed.Articles, article)
+	}
+
+	sort.SliceStable(feed.Articles, func(i, j int) bool {
+		return feed.Articles[i].PublishedAt.After(feed.Articles[j].PublishedAt)
+	})
+	if len(feed.Articles) > p.maxArticles {
+		feed.Articles = feed.Articles[:p.maxArticles]
+	}
+	if untitled > 0 {
+		feed.Warnings = append(feed.Warnings, fmt.Sprintf("no title found in %d of %d entries, titled after their url",
+			untitled, len(seen)))
+	}
+
+	return feed
+}
+
+// pathTitle makes a title of the last segment of the path of the link, e.g.
+// "Storm hits the coast" of https://example.com/news/storm-hits-the-coast.html.
+func pathTitle(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	segment := path.Base(strings.TrimRight(u.Path, "/"))
+	if segment == "." || segment == "/" {
+		return ""
+	}
+	switch ext := path.Ext(segment); strings.ToLower(ext) {
+	case ".html", ".htm", ".shtml", ".php", ".asp", ".aspx":
+		segment = strings.TrimSuffix(segment, ext)
+	}
+	words := strings.Fields(strings.NewReplacer("-", " ", "_", " ", "+", " ").Replace(segment))
+	if len(words) == 0 {
+		return ""
+	}
+	first, size := utf8.DecodeRuneInString(words[0])
+	words[0] = string(unicode.ToUpper(first)) + words[0][size:]
+	return strings.Join(words, " ")
+}
+
+// resolve resolves the loc of an entry against the sitemap's URL, only
+// accepting http(s) results.
+func resolve(base, loc string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(loc))
+	if err != nil || loc == "" {
+		return "", false
+	}
+	if b, err := url.Parse(base); err == nil {
+		u = b.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func hostname(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+func homepage(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+}
+
+// sameOrigin reports whether both urls have the same scheme and host, so
+// that credentials are neither sent to another host nor downgraded to http.
+func sameOrigin(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host != "" && strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
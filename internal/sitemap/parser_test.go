@@ -0,0 +1,207 @@
+package sitemap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// stubDoer serves the documents of pages, recording the Authorization header
+// every url was requested with.
+type stubDoer struct {
+	pages map[string]string
+
+	mu   sync.Mutex
+	auth map[string]string
+}
+
+func (d *stubDoer) Do(req *http.Request) (*http.Response, error) {
+	d.mu.Lock()
+	d.auth[req.URL.String()] = req.Header.Get("Authorization")
+	d.mu.Unlock()
+
+	body, ok := d.pages[req.URL.String()]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": {"application/xml"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestParse(t *testing.T) {
+	doer := &stubDoer{
+		auth: make(map[string]string),
+		pages: map[string]string{
+			"https://example.com/news-sitemap.xml": `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+        xmlns:news="http://www.google.com/schemas/sitemap-news/0.9">
+  <url>
+    <loc>/older</loc>
+    <news:news>
+      <news:publication><news:name>Example News</news:name><news:language>EN</news:language></news:publication>
+      <news:publication_date>2021-06-01T08:00:00Z</news:publication_date>
+      <news:title> Older story </news:title>
+    </news:news>
+  </url>
+  <url>
+    <loc>https://example.com/newer</loc>
+    <lastmod>2021-06-01T09:00:00Z</lastmod>
+    <news:news><news:publication_date>2021-06-01T10:00:00Z</news:publication_date><news:title>Newer story</news:title></news:news>
+  </url>
+  <url><loc>https://example.com/newer</loc></url>
+  <url><loc>javascript:alert(1)</loc></url>
+  <url><loc></loc></url>
+</urlset>`,
+		},
+	}
+	source := &domain.Source{URL: "https://example.com/news-sitemap.xml", Category: "uk", Provider: "example"}
+
+	feed, err := NewParser(doer).Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if feed.Title != "Example News" || feed.Language != "en" || feed.Link != "https://example.com/" || feed.Category != "uk" {
+		t.Errorf("feed = %q %q %q %q", feed.Title, feed.Language, feed.Link, feed.Category)
+	}
+	if len(feed.Articles) != 2 {
+		t.Fatalf("got %d articles, want 2", len(feed.Articles))
+	}
+	newer, older := feed.Articles[0], feed.Articles[1]
+	if newer.Link != "https://example.com/newer" || newer.Title != "Newer story" || !newer.PublishedAt.Equal(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("newer = %q %q %v", newer.Link, newer.Title, newer.PublishedAt)
+	}
+	if newer.ItemUpdatedAt == nil || !newer.ItemUpdatedAt.Equal(time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("newer updated at = %v", newer.ItemUpdatedAt)
+	}
+	if older.Link != "https://example.com/older" || older.GUID != older.Link || older.Title != "Older story" {
+		t.Errorf("older = %q %q %q", older.Link, older.GUID, older.Title)
+	}
+	if !feed.UpdatedAt.Equal(newer.PublishedAt) {
+		t.Errorf("feed updated at = %v, want %v", feed.UpdatedAt, newer.PublishedAt)
+	}
+	if len(feed.Warnings) != 0 {
+		t.Errorf("warnings = %v", feed.Warnings)
+	}
+}
+
+func TestParseStandard(t *testing.T) {
+	doer := &stubDoer{
+		auth: make(map[string]string),
+		pages: map[string]string{
+			"https://example.com/sitemap.xml": `<urlset>
+  <url><loc>https://example.com/news/storm-hits_the-coast.html</loc><lastmod>2021-06-01T08:00:00Z</lastmod></url>
+  <url><loc>https://example.com/news/élection-results/</loc></url>
+  <url><loc>https://example.com/</loc></url>
+</urlset>`,
+		},
+	}
+	crawledAt := time.Date(2021, 6, 2, 12, 0, 0, 0, time.UTC)
+
+	feed, err := NewParser(doer, WithClock(clockwork.NewFakeClockAt(crawledAt))).
+		Parse(context.Background(), &domain.Source{URL: "https://example.com/sitemap.xml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Articles) != 3 {
+		t.Fatalf("got %d articles, want 3", len(feed.Articles))
+	}
+
+	// the undated ones are published at the time of the crawl, so come first
+	for i, want := range []struct {
+		title       string
+		publishedAt time.Time
+	}{
+		{"Élection results", crawledAt},
+		{"", crawledAt},
+		{"Storm hits the coast", time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)},
+	} {
+		a := feed.Articles[i]
+		if a.Title != want.title || !a.PublishedAt.Equal(want.publishedAt) {
+			t.Errorf("article %d = %q %v, want %q %v", i, a.Title, a.PublishedAt, want.title, want.publishedAt)
+		}
+	}
+	if len(feed.Warnings) != 1 || !strings.Contains(feed.Warnings[0], "3 of 3 entries") {
+		t.Errorf("warnings = %v", feed.Warnings)
+	}
+}
+
+func TestParseIndex(t *testing.T) {
+	entry := func(loc string) string {
+		return `<urlset><url><loc>` + loc + `</loc></url></urlset>`
+	}
+	doer := &stubDoer{
+		auth: make(map[string]string),
+		pages: map[string]string{
+			"https://example.com/sitemap.xml": `<sitemapindex>
+  <sitemap><loc>https://example.com/oldest.xml</loc><lastmod>2021-05-01</lastmod></sitemap>
+  <sitemap><loc>/newest.xml</loc><lastmod>2021-06-03</lastmod></sitemap>
+  <sitemap><loc>https://cdn.example.net/other-host.xml</loc><lastmod>2021-06-02</lastmod></sitemap>
+  <sitemap><loc>http://example.com/plain.xml</loc><lastmod>2021-06-02</lastmod></sitemap>
+  <sitemap><loc>https://example.com/missing.xml</loc><lastmod>2021-06-01</lastmod></sitemap>
+</sitemapindex>`,
+			"https://example.com/newest.xml":         entry("https://example.com/a"),
+			"https://cdn.example.net/other-host.xml": entry("https://example.com/b"),
+			"http://example.com/plain.xml":           entry("https://example.com/c"),
+			"https://example.com/oldest.xml":         entry("https://example.com/d"),
+		},
+	}
+	source := &domain.Source{
+		URL:  "https://example.com/sitemap.xml",
+		Auth: &domain.SourceAuth{Type: domain.AuthTypeBearer, Secret: "token"},
+	}
+
+	feed, err := NewParser(doer, WithMaxSitemaps(4)).Parse(context.Background(), source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var links []string
+	for _, a := range feed.Articles {
+		links = append(links, a.Link)
+	}
+	// the oldest sitemap is past the limit and the missing one is skipped
+	if got := strings.Join(links, " "); got != "https://example.com/a https://example.com/b https://example.com/c" {
+		t.Errorf("articles = %v", got)
+	}
+	if _, ok := doer.auth["https://example.com/oldest.xml"]; ok {
+		t.Errorf("sitemap past the limit fetched")
+	}
+
+	for url, want := range map[string]string{
+		"https://example.com/sitemap.xml":        "Bearer token",
+		"https://example.com/newest.xml":         "Bearer token",
+		"https://cdn.example.net/other-host.xml": "",
+		"http://example.com/plain.xml":           "",
+	} {
+		if got := doer.auth[url]; got != want {
+			t.Errorf("%s requested with Authorization %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestParseIndexAllFailing(t *testing.T) {
+	doer := &stubDoer{
+		auth: make(map[string]string),
+		pages: map[string]string{
+			"https://example.com/sitemap.xml": `<sitemapindex><sitemap><loc>https://example.com/missing.xml</loc></sitemap></sitemapindex>`,
+		},
+	}
+
+	if _, err := NewParser(doer).Parse(context.Background(), &domain.Source{URL: "https://example.com/sitemap.xml"}); err == nil {
+		t.Errorf("Parse of an index whose sitemaps all fail succeeded")
+	}
+}
@@ -0,0 +1,130 @@
+// Package sitemap maps sitemaps and Google News sitemaps into feeds, for the
+// publishers that have no RSS.
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/fetch"
+)
+
+const (
+	rootURLSet       = "urlset"
+	rootSitemapIndex = "sitemapindex"
+)
+
+// document is either a urlset or a sitemap index, told apart by its root.
+// Elements are matched by their local name so that news:, image: and
+// misdeclared namespaces are all understood.
+type document struct {
+	XMLName  xml.Name
+	URLs     []entry     `xml:"url"`
+	Sitemaps []reference `xml:"sitemap"`
+}
+
+type entry struct {
+	Loc     string  `xml:"loc"`
+	LastMod string  `xml:"lastmod"`
+	News    *news   `xml:"news"`
+	Images  []image `xml:"image"`
+}
+
+type news struct {
+	Publication struct {
+		Name     string `xml:"name"`
+		Language string `xml:"language"`
+	} `xml:"publication"`
+	PublicationDate string `xml:"publication_date"`
+	Title           string `xml:"title"`
+	Keywords        string `xml:"keywords"`
+}
+
+type image struct {
+	Loc string `xml:"loc"`
+}
+
+type reference struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// gunzip decompresses a .xml.gz sitemap served without a Content-Encoding,
+// other bodies are returned as they are.
+func gunzip(body []byte, maxSize int64) ([]byte, error) {
+	if !bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		return body, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("error reading gzipped sitemap: %w", err)
+	}
+	body, err = io.ReadAll(io.LimitReader(zr, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading gzipped sitemap: %w", err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, fmt.Errorf("%w: gzipped sitemap over %d bytes", fetch.ErrTooLarge, maxSize)
+	}
+	return body, nil
+}
+
+// decode reads a urlset or a sitemap index.
+func decode(body []byte) (*document, error) {
+	var doc document
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing sitemap: %w", err)
+	}
+	switch doc.XMLName.Local {
+	case rootURLSet, rootSitemapIndex:
+		return &doc, nil
+	}
+	return nil, fmt.Errorf("error parsing sitemap: unexpected root element <%s>", doc.XMLName.Local)
+}
+
+// w3cLayouts are the W3C Datetime profiles sitemaps use, along with a few
+// common departures from it.
+var w3cLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseDate parses a W3C Datetime, reporting false when it is empty or invalid.
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range w3cLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// splitKeywords splits the comma separated news:keywords of an entry.
+func splitKeywords(s string) []string {
+	var keywords []string
+	seen := make(map[string]bool)
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		keywords = append(keywords, k)
+	}
+	return keywords
+}
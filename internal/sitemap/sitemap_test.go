@@ -0,0 +1,167 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeffreyyong/news-feeder/internal/fetch"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		root     string
+		urls     int
+		sitemaps int
+		wantErr  bool
+	}{
+		{
+			name: "urlset",
+			body: `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/a</loc><lastmod>2021-06-01</lastmod></url>
+  <url><loc>https://example.com/b</loc></url>
+</urlset>`,
+			root: rootURLSet,
+			urls: 2,
+		},
+		{
+			name: "sitemap index",
+			body: `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/news-1.xml</loc></sitemap>
+</sitemapindex>`,
+			root:     rootSitemapIndex,
+			sitemaps: 1,
+		},
+		{
+			name: "misdeclared namespaces",
+			body: `<urlset xmlns="http://www.google.com/schemas/sitemap/0.84"><url><loc>https://example.com/a</loc></url></urlset>`,
+			root: rootURLSet,
+			urls: 1,
+		},
+		{name: "other root", body: `<rss version="2.0"><channel></channel></rss>`, wantErr: true},
+		{name: "html", body: `<!DOCTYPE html><html><body></body></html>`, wantErr: true},
+		{name: "truncated", body: `<urlset><url><loc>https://example.com/a`, wantErr: true},
+		{name: "empty", body: ``, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := decode([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decode error = %v, want an error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if doc.XMLName.Local != tt.root || len(doc.URLs) != tt.urls || len(doc.Sitemaps) != tt.sitemaps {
+				t.Errorf("decode = <%s> with %d urls and %d sitemaps, want <%s> with %d and %d",
+					doc.XMLName.Local, len(doc.URLs), len(doc.Sitemaps), tt.root, tt.urls, tt.sitemaps)
+			}
+		})
+	}
+}
+
+func TestDecodeNews(t *testing.T) {
+	doc, err := decode([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+        xmlns:news="http://www.google.com/schemas/sitemap-news/0.9"
+        xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>https://example.com/storm</loc>
+    <news:news>
+      <news:publication><news:name>Example News</news:name><news:language>en</news:language></news:publication>
+      <news:publication_date>2021-06-01T10:30:00+01:00</news:publication_date>
+      <news:title>Storm hits coast</news:title>
+      <news:keywords>weather, storm, weather</news:keywords>
+    </news:news>
+    <image:image><image:loc>https://cdn.example.com/storm.jpg</image:loc></image:image>
+  </url>
+</urlset>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.URLs) != 1 || doc.URLs[0].News == nil {
+		t.Fatalf("decode = %+v, want one news entry", doc.URLs)
+	}
+	e := doc.URLs[0]
+	if e.News.Title != "Storm hits coast" || e.News.Publication.Name != "Example News" || e.News.PublicationDate != "2021-06-01T10:30:00+01:00" {
+		t.Errorf("news = %+v", e.News)
+	}
+	if len(e.Images) != 1 || e.Images[0].Loc != "https://cdn.example.com/storm.jpg" {
+		t.Errorf("images = %+v", e.Images)
+	}
+	if got, want := splitKeywords(e.News.Keywords), []string{"weather", "storm"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keywords = %v, want %v", got, want)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{value: "2021-06-01T10:30:15+01:00", want: time.Date(2021, 6, 1, 9, 30, 15, 0, time.UTC), ok: true},
+		{value: "2021-06-01T10:30:15.5Z", want: time.Date(2021, 6, 1, 10, 30, 15, 5e8, time.UTC), ok: true},
+		{value: "2021-06-01T10:30+01:00", want: time.Date(2021, 6, 1, 9, 30, 0, 0, time.UTC), ok: true},
+		{value: "2021-06-01T10:30:15", want: time.Date(2021, 6, 1, 10, 30, 15, 0, time.UTC), ok: true},
+		{value: "2021-06-01 10:30:15", want: time.Date(2021, 6, 1, 10, 30, 15, 0, time.UTC), ok: true},
+		{value: " 2021-06-01 ", want: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{value: "2021-06", want: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{value: "2021", want: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{value: "01/06/2021"},
+		{value: "yesterday"},
+		{value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseDate(tt.value)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("parseDate(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGunzip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(strings.Repeat("<urlset/>", 10)))
+	zw.Close()
+	gzipped := buf.Bytes()
+
+	tests := []struct {
+		name    string
+		body    []byte
+		maxSize int64
+		want    string
+		wantErr error
+	}{
+		{name: "plain", body: []byte("<urlset/>"), maxSize: 5, want: "<urlset/>"},
+		{name: "gzipped", body: gzipped, maxSize: 1000, want: strings.Repeat("<urlset/>", 10)},
+		{name: "gzipped over the limit", body: gzipped, maxSize: 50, wantErr: fetch.ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gunzip(tt.body, tt.maxSize)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("gunzip error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("gunzip = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := gunzip([]byte{0x1f, 0x8b, 0}, 1000); err == nil {
+		t.Errorf("gunzip of a corrupt body succeeded")
+	}
+}
@@ -2,10 +2,14 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jeffreyyong/news-feeder/internal/domain"
+	uuid "github.com/kevinburke/go.uuid"
 	"github.com/lib/pq"
 )
 
@@ -13,52 +17,477 @@ var createArticleSQLErrors = map[string]error{
 	"article_pkey": domain.ErrArticleAlreadyExists,
 }
 
-func (s Store) CreateArticle(ctx context.Context, article *domain.Article) (string, error) {
-	clauses := map[string]interface{}{
-		"title":         article.Title,
-		"description":   article.Description,
-		"link":          article.Link,
-		"thumbnail_url": article.ThumbnailURL,
-		"updated_at":    article.UpdatedAt,
-		"published_at":  article.PublishedAt,
-		"feed_id":       article.FeedID,
-		"guid":          article.GUID,
+// CreateArticle inserts the article, or finds the stored article with the same
+// GUID or, failing that, the same canonical URL. An article found by GUID is
+// updated when the publisher changed it, keeping the previous version as a
+// revision. Either way the article is linked to the feed it came from. The
+// returned status reports what happened to the article row. It is expected
+// to run within a transaction. Articles without a GUID are refused, as they
+// would all be taken for the same article.
+func (s Store) CreateArticle(ctx context.Context, article *domain.Article) (string, domain.ArticleWriteStatus, error) {
+	if article.GUID == "" {
+		return "", "", domain.ErrArticleWithoutGUID
+	}
+	article.ContentHash = article.Hash()
+
+	existing, err := s.articleBy(ctx, sq.Eq{"guid": article.GUID})
+	if err != nil {
+		return "", "", err
+	}
+
+	var status domain.ArticleWriteStatus
+	switch {
+	case existing != nil:
+		status, err = s.reviseArticle(ctx, existing, article)
+	case article.CanonicalURL != "":
+		// the same story carried by another feed under a GUID of its own
+		existing, err = s.articleBy(ctx, sq.Eq{"canonical_url": article.CanonicalURL})
+		status = domain.ArticleWriteSkipped
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if existing == nil {
+		var id string
+		id, status, err = s.insertArticle(ctx, article)
+		if err != nil {
+			return "", "", err
+		}
+		existing = &domain.Article{ID: uuid.FromStringOrNil(id)}
+	}
+
+	if status != domain.ArticleWriteSkipped {
+		if err := s.replaceArticleImages(ctx, existing.ID, article.Images); err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := s.linkArticleFeed(ctx, existing.ID, article.FeedID); err != nil {
+		return "", "", err
 	}
+	return existing.ID.String(), status, nil
+}
 
+func (s Store) insertArticle(ctx context.Context, article *domain.Article) (string, domain.ArticleWriteStatus, error) {
 	query, args, err := psql.
 		Insert("article").
-		SetMap(clauses).
-		Suffix(`ON CONFLICT (guid) DO UPDATE SET guid = excluded.guid RETURNING id`).
+		SetMap(articleClauses(article)).
+		Suffix(`ON CONFLICT (guid) DO NOTHING RETURNING id`).
 		ToSql()
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	row := s.connFromContext(ctx).QueryRowxContext(ctx, query, args...)
-	if row.Err() != nil {
-		if pqErr, ok := row.Err().(*pq.Error); ok {
+	var id string
+	err = s.connFromContext(ctx).GetContext(ctx, &id, query, args...)
+	if err == nil {
+		return id, domain.ArticleWriteCreated, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		if pqErr, ok := err.(*pq.Error); ok {
 			if mappedErr, ok := createArticleSQLErrors[pqErr.Constraint]; ok {
-				return "", mappedErr
+				return "", "", mappedErr
 			}
 		}
-		return "", row.Err()
+		return "", "", fmt.Errorf("failed to return article id: %w", err)
 	}
 
-	var id string
-	err = row.Scan(&id)
+	// stored concurrently since it was looked up
+	existing, err := s.articleBy(ctx, sq.Eq{"guid": article.GUID})
 	if err != nil {
-		return "", fmt.Errorf("failed to return article id: %w", err)
+		return "", "", err
+	}
+	if existing == nil {
+		return "", "", fmt.Errorf("article %s vanished after conflicting", article.GUID)
+	}
+	status, err := s.reviseArticle(ctx, existing, article)
+	return existing.ID.String(), status, err
+}
+
+func articleClauses(article *domain.Article) map[string]interface{} {
+	return map[string]interface{}{
+		"title":            article.Title,
+		"description":      article.Description,
+		"link":             article.Link,
+		"thumbnail_url":    article.ThumbnailURL,
+		"thumbnail_width":  article.ThumbnailWidth,
+		"thumbnail_height": article.ThumbnailHeight,
+		"updated_at":       article.UpdatedAt,
+		"published_at":     article.PublishedAt,
+		"feed_id":          article.FeedID,
+		"guid":             article.GUID,
+		"canonical_url":    article.CanonicalURL,
+		"authors":          pq.StringArray(nonNilStrings(article.Authors)),
+		"tags":             pq.StringArray(nonNilStrings(article.Tags)),
+		"content":          article.Content,
+		"item_updated_at":  article.ItemUpdatedAt,
+		"content_hash":     article.ContentHash,
+		"hash_version":     domain.ArticleHashVersion,
+		"shingles":         pq.Int64Array(nonNilInt64s(article.Shingles)),
 	}
-	return id, nil
+}
+
+// reviseArticle updates the stored version of the article when its hash
+// changed, keeping the stored version as a revision.
+func (s Store) reviseArticle(ctx context.Context, existing, article *domain.Article) (domain.ArticleWriteStatus, error) {
+	if existing.ContentHash == article.ContentHash {
+		if existing.HashVersion < domain.ArticleHashVersion {
+			if err := s.setArticleColumn(ctx, existing.ID, "hash_version", domain.ArticleHashVersion); err != nil {
+				return "", err
+			}
+		}
+		if existing.CanonicalURL == "" && article.CanonicalURL != "" {
+			return domain.ArticleWriteSkipped, s.setArticleColumn(ctx, existing.ID, "canonical_url", article.CanonicalURL)
+		}
+		return domain.ArticleWriteSkipped, nil
+	}
+	if existing.ContentHash == "" || existing.HashVersion < domain.ArticleHashVersion {
+		// stored before articles were hashed, or hashed differently, there is
+		// nothing to compare against
+		return domain.ArticleWriteSkipped, s.rebaselineArticle(ctx, existing, article)
+	}
+
+	if err := s.createArticleRevision(ctx, existing, existing.ChangedFields(article)); err != nil {
+		return "", err
+	}
+
+	clauses := articleClauses(article)
+	delete(clauses, "guid")
+	delete(clauses, "feed_id")
+	delete(clauses, "published_at")
+	clauses["updated_at"] = sq.Expr("now()")
+
+	query, args, err := psql.
+		Update("article").
+		SetMap(clauses).
+		Where(sq.Eq{"id": existing.ID}).
+		ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.connFromContext(ctx).ExecContext(ctx, query, args...); err != nil {
+		return "", fmt.Errorf("failed to update article: %w", err)
+	}
+	return domain.ArticleWriteUpdated, nil
+}
+
+// rebaselineArticle replaces the stored version of an article hashed under an
+// older ArticleHashVersion, which only differs from the new one by how
+// ingestion derives it, along with its candidate images, without keeping it
+// as a revision or bumping its updated_at.
+func (s Store) rebaselineArticle(ctx context.Context, existing, article *domain.Article) error {
+	clauses := articleClauses(article)
+	delete(clauses, "guid")
+	delete(clauses, "feed_id")
+	delete(clauses, "published_at")
+	delete(clauses, "updated_at")
+
+	query, args, err := psql.
+		Update("article").
+		SetMap(clauses).
+		Where(sq.Eq{"id": existing.ID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.connFromContext(ctx).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to rebaseline article: %w", err)
+	}
+	// the thumbnail may have been picked among different candidates
+	return s.replaceArticleImages(ctx, existing.ID, article.Images)
+}
+
+// articleBy returns the stored version of the article matching the
+// condition, locking it for the rest of the transaction, or nil when there
+// is none.
+func (s Store) articleBy(ctx context.Context, where sq.Sqlizer) (*domain.Article, error) {
+	query, args, err := psql.Select().
+		Columns(
+			"id",
+			"title",
+			"description",
+			"link",
+			"thumbnail_url",
+			"canonical_url",
+			"authors",
+			"tags",
+			"content",
+			"item_updated_at",
+			"content_hash",
+			"hash_version",
+		).
+		From("article").
+		Where(where).
+		OrderBy("created_at").
+		Limit(1).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var article domain.Article
+	if err := s.connFromContext(ctx).GetContext(ctx, &article, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get article: %w", err)
+	}
+	return &article, nil
+}
+
+// linkArticleFeed records that the feed carried the article.
+func (s Store) linkArticleFeed(ctx context.Context, articleID, feedID uuid.UUID) error {
+	query, args, err := psql.
+		Insert("article_feed").
+		Columns("article_id", "feed_id").
+		Values(articleID, feedID).
+		Suffix(`ON CONFLICT DO NOTHING`).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.connFromContext(ctx).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to link article to feed: %w", err)
+	}
+	return nil
+}
+
+// replaceArticleImages replaces the candidate images of the article.
+func (s Store) replaceArticleImages(ctx context.Context, articleID uuid.UUID, images []*domain.ArticleImage) error {
+	query, args, err := psql.
+		Delete("article_image").
+		Where(sq.Eq{"article_id": articleID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.connFromContext(ctx).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete article images: %w", err)
+	}
+
+	if len(images) == 0 {
+		return nil
+	}
+
+	insert := psql.
+		Insert("article_image").
+		Columns("article_id", "position", "url", "width", "height", "type", "origin")
+	for i, img := range images {
+		insert = insert.Values(articleID, i, img.URL, img.Width, img.Height, img.Type, img.Origin)
+	}
+	query, args, err = insert.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.connFromContext(ctx).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to create article images: %w", err)
+	}
+	return nil
+}
+
+// SelectArticleImages returns the candidate images of the articles, in the
+// order they were found in.
+func (s Store) SelectArticleImages(ctx context.Context, articleIDs []uuid.UUID) ([]*domain.ArticleImage, error) {
+	if len(articleIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := psql.Select().
+		Columns(
+			"article_id",
+			"url",
+			"width",
+			"height",
+			"type",
+			"origin",
+		).
+		From("article_image").
+		Where(sq.Eq{"article_id": articleIDs}).
+		OrderBy("article_id", "position").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var images []*domain.ArticleImage
+	if err = s.connFromContext(ctx).SelectContext(ctx, &images, query, args...); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (s Store) setArticleColumn(ctx context.Context, id uuid.UUID, column string, value interface{}) error {
+	query, args, err := psql.
+		Update("article").
+		Set(column, value).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// createArticleRevision keeps the version of the article about to be replaced.
+func (s Store) createArticleRevision(ctx context.Context, previous *domain.Article, changedFields []string) error {
+	clauses := map[string]interface{}{
+		"article_id":      previous.ID,
+		"title":           previous.Title,
+		"description":     previous.Description,
+		"link":            previous.Link,
+		"thumbnail_url":   previous.ThumbnailURL,
+		"authors":         pq.StringArray(nonNilStrings(previous.Authors)),
+		"tags":            pq.StringArray(nonNilStrings(previous.Tags)),
+		"content":         previous.Content,
+		"item_updated_at": previous.ItemUpdatedAt,
+		"content_hash":    previous.ContentHash,
+		"changed_fields":  pq.StringArray(nonNilStrings(changedFields)),
+	}
+
+	query, args, err := psql.
+		Insert("article_revision").
+		SetMap(clauses).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.connFromContext(ctx).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to create article revision: %w", err)
+	}
+	return nil
+}
+
+// SelectKnownGUIDs returns those of the given GUIDs that are already stored.
+func (s Store) SelectKnownGUIDs(ctx context.Context, guids []string) ([]string, error) {
+	if len(guids) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := psql.Select("guid").
+		From("article").
+		Where(sq.Eq{"guid": guids}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var known []string
+	if err = s.connFromContext(ctx).SelectContext(ctx, &known, query, args...); err != nil {
+		return nil, err
+	}
+	return known, nil
+}
+
+// SelectRevisedGUIDs returns those of the given GUIDs whose articles were
+// revised since the given time.
+func (s Store) SelectRevisedGUIDs(ctx context.Context, guids []string, since time.Time) ([]string, error) {
+	if len(guids) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := psql.Select("guid").
+		From("article").
+		Where(sq.Eq{"guid": guids}).
+		Where(sq.GtOrEq{"updated_at": since}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var revised []string
+	if err = s.connFromContext(ctx).SelectContext(ctx, &revised, query, args...); err != nil {
+		return nil, err
+	}
+	return revised, nil
+}
+
+// GetArticle returns a single article.
+func (s Store) GetArticle(ctx context.Context, id uuid.UUID) (*domain.Article, error) {
+	query, args, err := selectArticles().
+		Where(sq.Eq{"article.id": id}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var article domain.Article
+	if err := s.connFromContext(ctx).GetContext(ctx, &article, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrArticleNotFound
+		}
+		return nil, err
+	}
+	return &article, nil
+}
+
+// SelectArticleRevisions lists the previous versions of an article, most recent first.
+func (s Store) SelectArticleRevisions(ctx context.Context, articleID uuid.UUID) ([]*domain.ArticleRevision, error) {
+	query, args, err := psql.Select().
+		Columns(
+			"id",
+			"article_id",
+			"title",
+			"description",
+			"link",
+			"thumbnail_url",
+			"authors",
+			"tags",
+			"content",
+			"item_updated_at",
+			"changed_fields",
+			"revised_at",
+		).
+		From("article_revision").
+		Where(sq.Eq{"article_id": articleID}).
+		OrderBy("revised_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []*domain.ArticleRevision
+	if err = s.connFromContext(ctx).SelectContext(ctx, &revisions, query, args...); err != nil {
+		return nil, err
+	}
+	return revisions, nil
 }
 
 func applySelectArticleFilters(f *domain.SelectArticleFilters, query sq.SelectBuilder) sq.SelectBuilder {
-	if len(f.Categories) > 0 {
-		query = query.Where(sq.Eq{"feed.category": f.Categories})
+	if len(f.Categories) > 0 || len(f.Providers) > 0 {
+		// any of the feeds that carried the article counts
+		carried := sq.Select("1").
+			From("article_feed").
+			Join("feed ON feed.id = article_feed.feed_id").
+			Where("article_feed.article_id = article.id")
+		if len(f.Categories) > 0 {
+			carried = carried.Where(sq.Eq{"feed.category": f.Categories})
+		}
+		if len(f.Providers) > 0 {
+			carried = carried.Where(sq.Eq{"feed.provider": f.Providers})
+		}
+		query = query.Where(sq.Expr("EXISTS (?)", carried))
+	}
+
+	if len(f.Authors) > 0 {
+		query = query.Where(sq.Expr("article.authors && ?", pq.StringArray(f.Authors)))
 	}
 
-	if len(f.Providers) > 0 {
-		query = query.Where(sq.Eq{"feed.provider": f.Providers})
+	if len(f.Tags) > 0 {
+		query = query.Where(sq.Expr("article.tags && ?", pq.StringArray(f.Tags)))
+	}
+
+	if len(f.StoryIDs) > 0 {
+		query = query.Where(sq.Eq{"article.story_id": f.StoryIDs})
 	}
 
 	if f.Limit != nil {
@@ -72,22 +501,38 @@ func applySelectArticleFilters(f *domain.SelectArticleFilters, query sq.SelectBu
 	return query
 }
 
-func (s Store) SelectArticles(ctx context.Context, f *domain.SelectArticleFilters) ([]*domain.Article, error) {
-	queryBuilder := psql.Select().
+func selectArticles() sq.SelectBuilder {
+	return psql.Select().
 		Columns(
 			"article.id as id",
 			"article.title as title",
 			"article.description as description",
+			"article.link as link",
 			"article.thumbnail_url as thumbnail_url",
+			"article.thumbnail_width as thumbnail_width",
+			"article.thumbnail_height as thumbnail_height",
+			"article.canonical_url as canonical_url",
+			"article.story_id as story_id",
+			"ARRAY(SELECT DISTINCT feed.category FROM article_feed JOIN feed ON feed.id = article_feed.feed_id WHERE article_feed.article_id = article.id AND feed.category <> '' ORDER BY feed.category) as categories",
+			"ARRAY(SELECT DISTINCT feed.provider FROM article_feed JOIN feed ON feed.id = article_feed.feed_id WHERE article_feed.article_id = article.id AND feed.provider <> '' ORDER BY feed.provider) as providers",
+			"article.authors as authors",
+			"article.tags as tags",
+			"article.content as content",
+			"article.item_updated_at as item_updated_at",
 			"article.created_at as created_at",
 			"article.updated_at as updated_at",
 			"article.published_at as published_at",
 		).
-		From("article").
-		LeftJoin("feed ON article.feed_id = feed.id").
+		From("article")
+}
+
+func (s Store) SelectArticles(ctx context.Context, f *domain.SelectArticleFilters) ([]*domain.Article, error) {
+	queryBuilder := selectArticles().
 		OrderBy("published_at DESC")
 
-	if f != nil {
+	if f != nil && f.CollapseStories {
+		queryBuilder = selectCollapsedArticles(f)
+	} else if f != nil {
 		queryBuilder = applySelectArticleFilters(f, queryBuilder)
 	}
 	query, args, err := queryBuilder.ToSql()
@@ -101,3 +546,45 @@ func (s Store) SelectArticles(ctx context.Context, f *domain.SelectArticleFilter
 	}
 	return articles, nil
 }
+
+// selectCollapsedArticles keeps the most recent of the articles matching the
+// filters per story, articles outside of a story standing for themselves, and
+// paginates what is left.
+func selectCollapsedArticles(f *domain.SelectArticleFilters) sq.SelectBuilder {
+	matching := *f
+	matching.Limit, matching.Offset = nil, nil
+
+	latest := applySelectArticleFilters(&matching, selectArticles()).
+		Options("DISTINCT ON (COALESCE(article.story_id, article.id))").
+		OrderBy("COALESCE(article.story_id, article.id)", "article.published_at DESC")
+
+	query := psql.Select("*").
+		FromSelect(latest, "article").
+		OrderBy("published_at DESC")
+
+	if f.Limit != nil {
+		query = query.Limit(*f.Limit)
+	}
+
+	if f.Offset != nil {
+		query = query.Offset(*f.Offset)
+	}
+
+	return query
+}
+
+// nonNilStrings makes sure empty lists are stored as '{}' rather than NULL.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// nonNilInt64s makes sure empty lists are stored as '{}' rather than NULL.
+func nonNilInt64s(s []int64) []int64 {
+	if s == nil {
+		return []int64{}
+	}
+	return s
+}
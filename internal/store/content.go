@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+var articleContentColumns = []string{
+	"article_content.article_id AS article_id",
+	"article_content.status AS status",
+	"article_content.html AS html",
+	"article_content.text AS text",
+	"article_content.word_count AS word_count",
+	"article_content.error AS error",
+	"article_content.attempts AS attempts",
+	"article_content.extracted_at AS extracted_at",
+	"article_content.created_at AS created_at",
+	"article_content.updated_at AS updated_at",
+	"article.link AS link",
+}
+
+// QueueArticleContent records that the content of the article is to be
+// extracted, unless it already is.
+func (s Store) QueueArticleContent(ctx context.Context, articleID uuid.UUID) error {
+	query, args, err := psql.
+		Insert("article_content").
+		Columns("article_id", "status").
+		Values(articleID, domain.ExtractionStatusPending).
+		Suffix(`ON CONFLICT (article_id) DO NOTHING`).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// UpdateArticleContent records the outcome of an extraction attempt.
+func (s Store) UpdateArticleContent(ctx context.Context, content *domain.ArticleContent) error {
+	clauses := map[string]interface{}{
+		"status":       content.Status,
+		"html":         content.HTML,
+		"text":         content.Text,
+		"word_count":   content.WordCount,
+		"error":        content.Error,
+		"attempts":     content.Attempts,
+		"extracted_at": content.ExtractedAt,
+		"updated_at":   sq.Expr("now()"),
+	}
+
+	query, args, err := psql.
+		Update("article_content").
+		SetMap(clauses).
+		Where(sq.Eq{"article_id": content.ArticleID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrArticleContentNotFound
+	}
+	return nil
+}
+
+// GetArticleContent returns the extracted content of an article.
+func (s Store) GetArticleContent(ctx context.Context, articleID uuid.UUID) (*domain.ArticleContent, error) {
+	query, args, err := psql.Select().
+		Columns(articleContentColumns...).
+		From("article_content").
+		Join("article ON article_content.article_id = article.id").
+		Where(sq.Eq{"article_content.article_id": articleID}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var content domain.ArticleContent
+	if err := s.connFromContext(ctx).GetContext(ctx, &content, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrArticleContentNotFound
+		}
+		return nil, err
+	}
+	return &content, nil
+}
+
+func applySelectArticleContentFilters(f *domain.SelectArticleContentFilters, query sq.SelectBuilder) sq.SelectBuilder {
+	if len(f.Statuses) > 0 {
+		query = query.Where(sq.Eq{"article_content.status": f.Statuses})
+	}
+
+	if f.MaxAttempts != nil {
+		query = query.Where(sq.Lt{"article_content.attempts": *f.MaxAttempts})
+	}
+
+	if f.Limit != nil {
+		query = query.Limit(*f.Limit)
+	}
+
+	return query
+}
+
+// SelectArticleContents lists article content, oldest queued first.
+func (s Store) SelectArticleContents(ctx context.Context, f *domain.SelectArticleContentFilters) ([]*domain.ArticleContent, error) {
+	queryBuilder := psql.Select().
+		Columns(articleContentColumns...).
+		From("article_content").
+		Join("article ON article_content.article_id = article.id").
+		OrderBy("article_content.created_at")
+
+	if f != nil {
+		queryBuilder = applySelectArticleContentFilters(f, queryBuilder)
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []*domain.ArticleContent
+	if err = s.connFromContext(ctx).SelectContext(ctx, &contents, query, args...); err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	uuid "github.com/kevinburke/go.uuid"
+	"github.com/lib/pq"
+)
+
+var crawlRunColumns = []string{
+	"id",
+	"status",
+	"source_count",
+	"failed_count",
+	"items_new",
+	"items_updated",
+	"items_skipped",
+	"started_at",
+	"finished_at",
+	"duration_ms",
+}
+
+var crawlSourceResultColumns = []string{
+	"id",
+	"crawl_run_id",
+	"source_id",
+	"source",
+	"status",
+	"error",
+	"items_new",
+	"items_updated",
+	"items_skipped",
+	"duration_ms",
+	"warnings",
+	"created_at",
+}
+
+// CreateCrawlRun inserts a new crawl run and returns its id.
+func (s Store) CreateCrawlRun(ctx context.Context, run *domain.CrawlRun) (string, error) {
+	clauses := map[string]interface{}{
+		"status":       run.Status,
+		"source_count": run.SourceCount,
+		"started_at":   run.StartedAt,
+	}
+
+	query, args, err := psql.
+		Insert("crawl_run").
+		SetMap(clauses).
+		Suffix(`RETURNING id`).
+		ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	if err := s.connFromContext(ctx).GetContext(ctx, &id, query, args...); err != nil {
+		return "", fmt.Errorf("failed to return crawl run id: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateCrawlRun records the final state of a crawl run.
+func (s Store) UpdateCrawlRun(ctx context.Context, run *domain.CrawlRun) error {
+	clauses := map[string]interface{}{
+		"status":        run.Status,
+		"source_count":  run.SourceCount,
+		"failed_count":  run.FailedCount,
+		"items_new":     run.New,
+		"items_updated": run.Updated,
+		"items_skipped": run.Skipped,
+		"finished_at":   run.FinishedAt,
+		"duration_ms":   run.DurationMS,
+	}
+
+	query, args, err := psql.
+		Update("crawl_run").
+		SetMap(clauses).
+		Where(sq.Eq{"id": run.ID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrCrawlRunNotFound
+	}
+	return nil
+}
+
+// CreateCrawlSourceResult inserts the outcome of crawling a single source.
+func (s Store) CreateCrawlSourceResult(ctx context.Context, result *domain.CrawlSourceResult) (string, error) {
+	clauses := map[string]interface{}{
+		"crawl_run_id":  result.CrawlRunID,
+		"source_id":     result.SourceID,
+		"source":        result.Source,
+		"status":        result.Status,
+		"error":         result.Error,
+		"items_new":     result.New,
+		"items_updated": result.Updated,
+		"items_skipped": result.Skipped,
+		"duration_ms":   result.DurationMS,
+		"warnings":      pq.StringArray(nonNilStrings(result.Warnings)),
+	}
+
+	query, args, err := psql.
+		Insert("crawl_source_result").
+		SetMap(clauses).
+		Suffix(`RETURNING id`).
+		ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	if err := s.connFromContext(ctx).GetContext(ctx, &id, query, args...); err != nil {
+		return "", fmt.Errorf("failed to return crawl source result id: %w", err)
+	}
+	return id, nil
+}
+
+func applySelectCrawlRunFilters(f *domain.SelectCrawlRunFilters, query sq.SelectBuilder) sq.SelectBuilder {
+	if len(f.Statuses) > 0 {
+		query = query.Where(sq.Eq{"status": f.Statuses})
+	}
+
+	if f.Limit != nil {
+		query = query.Limit(*f.Limit)
+	}
+	if f.Offset != nil {
+		query = query.Offset(*f.Offset)
+	}
+
+	return query
+}
+
+// SelectCrawlRuns lists crawl runs, most recent first.
+func (s Store) SelectCrawlRuns(ctx context.Context, f *domain.SelectCrawlRunFilters) ([]*domain.CrawlRun, error) {
+	queryBuilder := psql.Select().
+		Columns(crawlRunColumns...).
+		From("crawl_run").
+		OrderBy("started_at DESC")
+
+	if f != nil {
+		queryBuilder = applySelectCrawlRunFilters(f, queryBuilder)
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*domain.CrawlRun
+	if err = s.connFromContext(ctx).SelectContext(ctx, &runs, query, args...); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// GetCrawlRun returns a single crawl run without its source results.
+func (s Store) GetCrawlRun(ctx context.Context, id uuid.UUID) (*domain.CrawlRun, error) {
+	query, args, err := psql.Select().
+		Columns(crawlRunColumns...).
+		From("crawl_run").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var run domain.CrawlRun
+	if err := s.connFromContext(ctx).GetContext(ctx, &run, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrCrawlRunNotFound
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+func applySelectCrawlSourceResultFilters(f *domain.SelectCrawlSourceResultFilters, query sq.SelectBuilder) sq.SelectBuilder {
+	if len(f.CrawlRunIDs) > 0 {
+		query = query.Where(sq.Eq{"crawl_run_id": f.CrawlRunIDs})
+	}
+
+	if len(f.SourceIDs) > 0 {
+		query = query.Where(sq.Eq{"source_id": f.SourceIDs})
+	}
+
+	if len(f.Sources) > 0 {
+		query = query.Where(sq.Eq{"source": f.Sources})
+	}
+
+	if len(f.Statuses) > 0 {
+		query = query.Where(sq.Eq{"status": f.Statuses})
+	}
+
+	if f.Limit != nil {
+		query = query.Limit(*f.Limit)
+	}
+	if f.Offset != nil {
+		query = query.Offset(*f.Offset)
+	}
+
+	return query
+}
+
+// SelectCrawlSourceResults lists per-source crawl results, most recent first.
+func (s Store) SelectCrawlSourceResults(ctx context.Context, f *domain.SelectCrawlSourceResultFilters) ([]*domain.CrawlSourceResult, error) {
+	queryBuilder := psql.Select().
+		Columns(crawlSourceResultColumns...).
+		From("crawl_source_result").
+		OrderBy("created_at DESC", "source")
+
+	if f != nil {
+		queryBuilder = applySelectCrawlSourceResultFilters(f, queryBuilder)
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*domain.CrawlSourceResult
+	if err = s.connFromContext(ctx).SelectContext(ctx, &results, query, args...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -15,20 +15,22 @@ var createFeedSQLErrors = map[string]error{
 
 func (s Store) CreateFeed(ctx context.Context, feed *domain.Feed) (string, error) {
 	clauses := map[string]interface{}{
-		"title":       feed.Title,
-		"description": feed.Description,
-		"link":        feed.Link,
-		"feed_link":   feed.FeedLink,
-		"category":    feed.Category,
-		"language":    feed.Language,
-		"provider":    feed.Provider,
-		"updated_at":  feed.UpdatedAt,
+		"title":         feed.Title,
+		"description":   feed.Description,
+		"link":          feed.Link,
+		"feed_link":     feed.FeedLink,
+		"category":      feed.Category,
+		"language":      feed.Language,
+		"provider":      feed.Provider,
+		"updated_at":    feed.UpdatedAt,
+		"etag":          feed.ETag,
+		"last_modified": feed.LastModified,
 	}
 
 	query, args, err := psql.
 		Insert("feed").
 		SetMap(clauses).
-		Suffix(`ON CONFLICT (feed_link) DO UPDATE SET feed_link = excluded.feed_link RETURNING id`).
+		Suffix(`ON CONFLICT (feed_link) DO UPDATE SET category = excluded.category, provider = excluded.provider, etag = COALESCE(NULLIF(excluded.etag, ''), feed.etag), last_modified = COALESCE(NULLIF(excluded.last_modified, ''), feed.last_modified) RETURNING id`).
 		ToSql()
 	if err != nil {
 		return "", err
@@ -61,6 +63,10 @@ func applySelectFeedFilters(f *domain.SelectFeedFilters, query sq.SelectBuilder)
 		query = query.Where(sq.Eq{"provider": f.Providers})
 	}
 
+	if len(f.FeedLinks) > 0 {
+		query = query.Where(sq.Eq{"feed_link": f.FeedLinks})
+	}
+
 	if f.Limit != nil {
 		query = query.Limit(*f.Limit)
 	}
@@ -82,6 +88,8 @@ func (s Store) SelectFeeds(ctx context.Context, f *domain.SelectFeedFilters) ([]
 			"category",
 			"language",
 			"provider",
+			"etag",
+			"last_modified",
 			"created_at",
 			"updated_at",
 		).
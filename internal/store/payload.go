@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+var payloadColumns = []string{
+	"id",
+	"source_id",
+	"origin",
+	"feed_link",
+	"url",
+	"content_type",
+	"etag",
+	"last_modified",
+	"size",
+	"stored_size",
+	"storage",
+	"location",
+	"fetched_at",
+}
+
+// CreatePayload records an archived payload, whose body was already stored,
+// and returns its id.
+func (s Store) CreatePayload(ctx context.Context, payload *domain.Payload) (string, error) {
+	clauses := map[string]interface{}{
+		"source_id":     payload.SourceID,
+		"origin":        payload.Origin,
+		"feed_link":     payload.FeedLink,
+		"url":           payload.URL,
+		"content_type":  payload.ContentType,
+		"etag":          payload.ETag,
+		"last_modified": payload.LastModified,
+		"size":          payload.Size,
+		"stored_size":   payload.StoredSize,
+		"storage":       payload.Storage,
+		"location":      payload.Location,
+		"fetched_at":    payload.FetchedAt,
+	}
+
+	query, args, err := psql.
+		Insert("payload").
+		SetMap(clauses).
+		Suffix(`RETURNING id`).
+		ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	if err := s.connFromContext(ctx).GetContext(ctx, &id, query, args...); err != nil {
+		return "", fmt.Errorf("failed to return payload id: %w", err)
+	}
+	return id, nil
+}
+
+func applySelectPayloadFilters(f *domain.SelectPayloadFilters, query sq.SelectBuilder) sq.SelectBuilder {
+	if len(f.SourceIDs) > 0 {
+		query = query.Where(sq.Eq{"source_id": f.SourceIDs})
+	}
+
+	if f.From != nil {
+		query = query.Where(sq.GtOrEq{"fetched_at": *f.From})
+	}
+	if f.To != nil {
+		query = query.Where(sq.Lt{"fetched_at": *f.To})
+	}
+
+	if f.Limit != nil {
+		query = query.Limit(*f.Limit)
+	}
+	if f.Offset != nil {
+		query = query.Offset(*f.Offset)
+	}
+
+	return query
+}
+
+// SelectPayloads lists archived payloads, without their bodies, oldest first.
+func (s Store) SelectPayloads(ctx context.Context, f *domain.SelectPayloadFilters) ([]*domain.Payload, error) {
+	queryBuilder := psql.Select().
+		Columns(payloadColumns...).
+		From("payload").
+		OrderBy("fetched_at", "id")
+
+	if f != nil {
+		queryBuilder = applySelectPayloadFilters(f, queryBuilder)
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var payloads []*domain.Payload
+	if err = s.connFromContext(ctx).SelectContext(ctx, &payloads, query, args...); err != nil {
+		return nil, err
+	}
+	return payloads, nil
+}
+
+// DeletePayload removes the record of an archived payload, its body is
+// removed on its own.
+func (s Store) DeletePayload(ctx context.Context, id uuid.UUID) error {
+	query, args, err := psql.
+		Delete("payload").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// PutPayloadBody stores the body of a payload as a large object, returning
+// its oid as the location of the body.
+func (s Store) PutPayloadBody(ctx context.Context, body []byte) (string, error) {
+	query, args, err := psql.Select().
+		Column(sq.Expr("lo_from_bytea(0, ?)", body)).
+		ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var oid uint32
+	if err := s.connFromContext(ctx).GetContext(ctx, &oid, query, args...); err != nil {
+		return "", fmt.Errorf("failed to create large object: %w", err)
+	}
+	return strconv.FormatUint(uint64(oid), 10), nil
+}
+
+// GetPayloadBody reads the large object of a payload body.
+func (s Store) GetPayloadBody(ctx context.Context, location string) ([]byte, error) {
+	oid, err := strconv.ParseUint(location, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: large object %q", ErrInvalidParam, location)
+	}
+
+	query, args, err := psql.Select().
+		Column(sq.Expr("lo_get(?::oid)", oid)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	if err := s.connFromContext(ctx).GetContext(ctx, &body, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to read large object: %w", err)
+	}
+	return body, nil
+}
+
+// DeletePayloadBody removes the large object of a payload body.
+func (s Store) DeletePayloadBody(ctx context.Context, location string) error {
+	oid, err := strconv.ParseUint(location, 10, 32)
+	if err != nil {
+		return fmt.Errorf("%w: large object %q", ErrInvalidParam, location)
+	}
+
+	query, args, err := psql.Select().
+		Column(sq.Expr("lo_unlink(?::oid)", oid)).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	return err
+}
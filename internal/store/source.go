@@ -0,0 +1,438 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	uuid "github.com/kevinburke/go.uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var createSourceSQLErrors = map[string]error{
+	"source_pkey":    domain.ErrSourceAlreadyExists,
+	"source_url_key": domain.ErrSourceAlreadyExists,
+
+	"source_category_fkey": domain.ErrInvalidSource,
+	"source_provider_fkey": domain.ErrInvalidSource,
+}
+
+var sourceColumns = []string{
+	"id",
+	"url",
+	"type",
+	"scrape_rules",
+	"mapping_rules",
+	"enabled",
+	"COALESCE(category, '') AS category",
+	"COALESCE(provider, '') AS provider",
+	"poll_interval",
+	"notes",
+	"last_crawled_at",
+	"created_at",
+	"updated_at",
+	"next_poll_at",
+	"scheduled_interval",
+	"ttl",
+	"skip_hours",
+	"skip_days",
+	"dead_at",
+	"auth",
+}
+
+func mapSourceSQLError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		if mappedErr, ok := createSourceSQLErrors[pqErr.Constraint]; ok {
+			return fmt.Errorf("%w: %s", mappedErr, pqErr.Detail)
+		}
+	}
+	return err
+}
+
+// CreateSource inserts a new source and returns its id.
+func (s Store) CreateSource(ctx context.Context, source *domain.Source) (string, error) {
+	clauses := map[string]interface{}{
+		"url":           source.URL,
+		"enabled":       source.Enabled,
+		"category":      nullIfEmpty(string(source.Category)),
+		"provider":      nullIfEmpty(string(source.Provider)),
+		"poll_interval": source.PollInterval,
+		"notes":         source.Notes,
+	}
+	if source.Type != "" {
+		clauses["type"] = source.Type
+	}
+	if source.ScrapeRules != nil {
+		clauses["scrape_rules"] = *source.ScrapeRules
+	}
+	if source.MappingRules != nil && !source.MappingRules.Empty() {
+		clauses["mapping_rules"] = *source.MappingRules
+	}
+	if source.Auth != nil {
+		// the credentials are bound to the id of the source
+		id := source.ID
+		if id == uuid.Nil {
+			id = uuid.NewV4()
+		}
+		sealed, err := s.sealAuth(id, source.Auth)
+		if err != nil {
+			return "", err
+		}
+		clauses["id"] = id
+		clauses["auth"] = sealed
+	}
+
+	query, args, err := psql.
+		Insert("source").
+		SetMap(clauses).
+		Suffix(`RETURNING id`).
+		ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	if err := s.connFromContext(ctx).GetContext(ctx, &id, query, args...); err != nil {
+		return "", mapSourceSQLError(err)
+	}
+	return id, nil
+}
+
+// UpdateSource applies the non-nil fields of the update to the source.
+func (s Store) UpdateSource(ctx context.Context, id uuid.UUID, update *domain.SourceUpdate) error {
+	clauses := map[string]interface{}{
+		"updated_at": sq.Expr("now()"),
+	}
+	if update.URL != nil {
+		clauses["url"] = *update.URL
+	}
+	if update.Type != nil {
+		clauses["type"] = *update.Type
+	}
+	if update.ScrapeRules != nil {
+		clauses["scrape_rules"] = *update.ScrapeRules
+	}
+	if update.MappingRules != nil {
+		clauses["mapping_rules"] = nil
+		if !update.MappingRules.Empty() {
+			clauses["mapping_rules"] = *update.MappingRules
+		}
+	}
+	if update.Auth != nil {
+		clauses["auth"] = nil
+		if update.Auth.Type != "" {
+			sealed, err := s.sealAuth(id, update.Auth)
+			if err != nil {
+				return err
+			}
+			clauses["auth"] = sealed
+		}
+	}
+	if update.Enabled != nil {
+		clauses["enabled"] = *update.Enabled
+		if *update.Enabled {
+			// brought back to life
+			clauses["dead_at"] = nil
+		}
+	}
+	if update.Category != nil {
+		clauses["category"] = nullIfEmpty(string(*update.Category))
+	}
+	if update.Provider != nil {
+		clauses["provider"] = nullIfEmpty(string(*update.Provider))
+	}
+	if update.PollInterval != nil {
+		clauses["poll_interval"] = *update.PollInterval
+		// reschedule at the new interval straight away
+		clauses["next_poll_at"] = nil
+	}
+	if update.Notes != nil {
+		clauses["notes"] = *update.Notes
+	}
+
+	query, args, err := psql.
+		Update("source").
+		SetMap(clauses).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return mapSourceSQLError(err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrSourceNotFound
+	}
+	return nil
+}
+
+// ScheduleSource records when the source was crawled and when it is next due.
+func (s Store) ScheduleSource(ctx context.Context, id uuid.UUID, schedule *domain.SourceSchedule) error {
+	clauses := map[string]interface{}{
+		"last_crawled_at":    schedule.CrawledAt,
+		"next_poll_at":       schedule.NextPollAt,
+		"scheduled_interval": schedule.Interval,
+	}
+	if schedule.Hints != nil {
+		clauses["ttl"] = schedule.Hints.TTL
+		clauses["skip_hours"] = pq.Int64Array(nonNilInt64s(schedule.Hints.SkipHours))
+		clauses["skip_days"] = pq.StringArray(nonNilStrings(schedule.Hints.SkipDays))
+	}
+
+	query, args, err := psql.
+		Update("source").
+		SetMap(clauses).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// MarkSourceGone disables the source, recording when it was found gone.
+func (s Store) MarkSourceGone(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query, args, err := psql.
+		Update("source").
+		Set("enabled", false).
+		Set("dead_at", at).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrSourceNotFound
+	}
+	return nil
+}
+
+// CreateSourceEvent records a change made to a source on its own.
+func (s Store) CreateSourceEvent(ctx context.Context, event *domain.SourceEvent) (string, error) {
+	clauses := map[string]interface{}{
+		"source_id": event.SourceID,
+		"kind":      event.Kind,
+		"old_url":   event.OldURL,
+		"new_url":   event.NewURL,
+		"detail":    event.Detail,
+	}
+
+	query, args, err := psql.
+		Insert("source_event").
+		SetMap(clauses).
+		Suffix(`RETURNING id`).
+		ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	if err := s.connFromContext(ctx).GetContext(ctx, &id, query, args...); err != nil {
+		return "", fmt.Errorf("failed to return source event id: %w", err)
+	}
+	return id, nil
+}
+
+// SelectSourceEvents lists the events of a source, most recent first.
+func (s Store) SelectSourceEvents(ctx context.Context, sourceID uuid.UUID) ([]*domain.SourceEvent, error) {
+	query, args, err := psql.Select().
+		Columns(
+			"id",
+			"source_id",
+			"kind",
+			"old_url",
+			"new_url",
+			"detail",
+			"created_at",
+		).
+		From("source_event").
+		Where(sq.Eq{"source_id": sourceID}).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*domain.SourceEvent
+	if err = s.connFromContext(ctx).SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// DeleteSource removes the source. Feeds and articles already crawled from it are kept.
+func (s Store) DeleteSource(ctx context.Context, id uuid.UUID) error {
+	query, args, err := psql.
+		Delete("source").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrSourceNotFound
+	}
+	return nil
+}
+
+// GetSource returns a single source.
+func (s Store) GetSource(ctx context.Context, id uuid.UUID) (*domain.Source, error) {
+	query, args, err := psql.Select().
+		Columns(sourceColumns...).
+		From("source").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var source domain.Source
+	if err := s.connFromContext(ctx).GetContext(ctx, &source, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrSourceNotFound
+		}
+		return nil, err
+	}
+	s.openAuth(ctx, &source)
+	return &source, nil
+}
+
+func applySelectSourceFilters(f *domain.SelectSourceFilters, query sq.SelectBuilder) sq.SelectBuilder {
+	if len(f.IDs) > 0 {
+		query = query.Where(sq.Eq{"id": f.IDs})
+	}
+
+	if len(f.URLs) > 0 {
+		query = query.Where(sq.Eq{"url": f.URLs})
+	}
+
+	if f.Enabled != nil {
+		query = query.Where(sq.Eq{"enabled": *f.Enabled})
+	}
+
+	if f.DueAt != nil {
+		query = query.Where(sq.Or{
+			sq.Eq{"next_poll_at": nil},
+			sq.LtOrEq{"next_poll_at": *f.DueAt},
+		})
+	}
+
+	if f.Limit != nil {
+		query = query.Limit(*f.Limit)
+	}
+	if f.Offset != nil {
+		query = query.Offset(*f.Offset)
+	}
+
+	return query
+}
+
+// SelectSources lists sources in the order they were registered.
+func (s Store) SelectSources(ctx context.Context, f *domain.SelectSourceFilters) ([]*domain.Source, error) {
+	queryBuilder := psql.Select().
+		Columns(sourceColumns...).
+		From("source").
+		OrderBy("created_at", "url")
+
+	if f != nil {
+		queryBuilder = applySelectSourceFilters(f, queryBuilder)
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []*domain.Source
+	if err = s.connFromContext(ctx).SelectContext(ctx, &sources, query, args...); err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		s.openAuth(ctx, source)
+	}
+	return sources, nil
+}
+
+// sealedAuth is marshalled with its secret, unlike domain.SourceAuth.
+type sealedAuth domain.SourceAuth
+
+// sealAuth encrypts the credentials of a source to be stored, bound to its
+// id so that they cannot be copied onto another source.
+func (s Store) sealAuth(sourceID uuid.UUID, auth *domain.SourceAuth) ([]byte, error) {
+	if s.box == nil {
+		return nil, fmt.Errorf("%w: credentials cannot be stored without a secret key", domain.ErrInvalidSource)
+	}
+	plaintext, err := json.Marshal(sealedAuth(*auth))
+	if err != nil {
+		return nil, err
+	}
+	return s.box.Seal(plaintext, sourceID.Bytes())
+}
+
+// openAuth decrypts the stored credentials of the source into its Auth. A
+// source whose credentials cannot be decrypted, e.g. after the secret key was
+// rotated, is returned without them and with the error in its AuthErr, so
+// that it fails alone rather than every listing it is part of.
+func (s Store) openAuth(ctx context.Context, source *domain.Source) {
+	if err := s.decryptAuth(source); err != nil {
+		logging.Error(ctx, "failed to decrypt source credentials", zap.String("source_id", source.ID.String()), zap.Error(err))
+		source.Auth = nil
+		source.AuthErr = err
+	}
+}
+
+func (s Store) decryptAuth(source *domain.Source) error {
+	if len(source.SealedAuth) == 0 {
+		return nil
+	}
+	if s.box == nil {
+		return fmt.Errorf("credentials of source %s cannot be read without a secret key", source.ID)
+	}
+	plaintext, err := s.box.Open(source.SealedAuth, source.ID.Bytes())
+	if err != nil {
+		return fmt.Errorf("error opening credentials of source %s: %w", source.ID, err)
+	}
+
+	var auth sealedAuth
+	if err := json.Unmarshal(plaintext, &auth); err != nil {
+		return fmt.Errorf("error reading credentials of source %s: %w", source.ID, err)
+	}
+	source.Auth = (*domain.SourceAuth)(&auth)
+	return nil
+}
+
+// CountSources returns the number of registered sources.
+func (s Store) CountSources(ctx context.Context) (int, error) {
+	query, args, err := psql.Select("count(*)").From("source").ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	if err := s.connFromContext(ctx).GetContext(ctx, &n, query, args...); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -11,6 +11,8 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+
+	"github.com/jeffreyyong/news-feeder/internal/secret"
 )
 
 const (
@@ -35,14 +37,30 @@ type conn interface {
 // Store represents a data repository backed by PostgreSQL.
 type Store struct {
 	db *sqlx.DB
+	// box seals the credentials of sources, which cannot be stored without it.
+	box *secret.Box
+}
+
+type Option func(*Store)
+
+// WithSecretBox functionally configures the store with the box the
+// credentials of sources are sealed with.
+func WithSecretBox(box *secret.Box) Option {
+	return func(s *Store) {
+		s.box = box
+	}
 }
 
 // New creates and returns a new instance of the Store.
-func New(db *sqlx.DB) (*Store, error) {
+func New(db *sqlx.DB, opts ...Option) (*Store, error) {
 	if db == nil {
 		return nil, fmt.Errorf("%w: db", ErrInvalidParam)
 	}
-	return &Store{db: db}, nil
+	s := &Store{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 
 func (s Store) Migrate(path string) (updated bool, version uint, err error) {
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+// CreateStory inserts a story, which articles are then assigned to.
+func (s Store) CreateStory(ctx context.Context, story *domain.Story) (string, error) {
+	query, args, err := psql.
+		Insert("story").
+		Columns("title", "last_article_at").
+		Values(story.Title, story.LastArticleAt).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	if err := s.connFromContext(ctx).GetContext(ctx, &id, query, args...); err != nil {
+		return "", fmt.Errorf("failed to return story id: %w", err)
+	}
+	return id, nil
+}
+
+// AssignArticleStory groups the article into the story, moving the story's
+// last article time forward when the article is more recent.
+func (s Store) AssignArticleStory(ctx context.Context, articleID, storyID uuid.UUID, publishedAt time.Time) error {
+	query, args, err := psql.
+		Update("article").
+		Set("story_id", storyID).
+		Where(sq.Eq{"id": articleID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.connFromContext(ctx).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to assign article to story: %w", err)
+	}
+
+	query, args, err = psql.
+		Update("story").
+		Set("last_article_at", sq.Expr("GREATEST(last_article_at, ?)", publishedAt)).
+		Set("updated_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": storyID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.connFromContext(ctx).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update story: %w", err)
+	}
+	return nil
+}
+
+// SelectStoryCandidates returns the fingerprinted articles published within
+// the given time range that share a shingle with the new articles, the ones
+// those may be near-duplicates of, the most recent first. Only the fields
+// needed to compare and group them are filled in.
+func (s Store) SelectStoryCandidates(ctx context.Context, f *domain.SelectStoryCandidateFilters) ([]*domain.Article, error) {
+	queryBuilder := psql.Select().
+		Columns(
+			"id",
+			"feed_id",
+			"title",
+			"published_at",
+			"story_id",
+			"shingles",
+			"ARRAY(SELECT DISTINCT feed.provider FROM article_feed JOIN feed ON feed.id = article_feed.feed_id WHERE article_feed.article_id = article.id AND feed.provider <> '' ORDER BY feed.provider) as providers",
+		).
+		From("article").
+		Where(sq.GtOrEq{"published_at": f.PublishedFrom}).
+		Where(sq.LtOrEq{"published_at": f.PublishedTo}).
+		// backed by article_shingles_idx
+		Where("shingles && ?", f.Shingles).
+		OrderBy("published_at DESC")
+
+	if f.ExcludeFeedID != nil {
+		queryBuilder = queryBuilder.Where(sq.NotEq{"feed_id": *f.ExcludeFeedID})
+	}
+	if f.Limit != nil {
+		queryBuilder = queryBuilder.Limit(*f.Limit)
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []*domain.Article
+	if err = s.connFromContext(ctx).SelectContext(ctx, &articles, query, args...); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+// SelectStories lists stories, the most recently active first.
+func (s Store) SelectStories(ctx context.Context, f *domain.SelectStoryFilters) ([]*domain.Story, error) {
+	queryBuilder := psql.Select().
+		Columns(
+			"id",
+			"title",
+			"last_article_at",
+			"created_at",
+			"updated_at",
+		).
+		From("story").
+		OrderBy("last_article_at DESC")
+
+	if f != nil {
+		if f.Limit != nil {
+			queryBuilder = queryBuilder.Limit(*f.Limit)
+		}
+		if f.Offset != nil {
+			queryBuilder = queryBuilder.Offset(*f.Offset)
+		}
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var stories []*domain.Story
+	if err = s.connFromContext(ctx).SelectContext(ctx, &stories, query, args...); err != nil {
+		return nil, err
+	}
+	return stories, nil
+}
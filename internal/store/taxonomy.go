@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+)
+
+// PutCategory creates the category or updates it if the slug already exists.
+func (s Store) PutCategory(ctx context.Context, category *domain.CategoryInfo) error {
+	query, args, err := psql.
+		Insert("category").
+		Columns("slug", "display_name").
+		Values(category.Slug, category.DisplayName).
+		Suffix(`ON CONFLICT (slug) DO UPDATE SET display_name = excluded.display_name, updated_at = now()`).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// SelectCategories lists the categories of the taxonomy.
+func (s Store) SelectCategories(ctx context.Context) ([]*domain.CategoryInfo, error) {
+	query, args, err := psql.Select().
+		Columns("slug", "display_name", "created_at", "updated_at").
+		From("category").
+		OrderBy("slug").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []*domain.CategoryInfo
+	if err = s.connFromContext(ctx).SelectContext(ctx, &categories, query, args...); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// PutProvider creates the provider or updates it if the slug already exists.
+func (s Store) PutProvider(ctx context.Context, provider *domain.ProviderInfo) error {
+	query, args, err := psql.
+		Insert("provider").
+		Columns("slug", "display_name", "homepage_url", "logo_url").
+		Values(provider.Slug, provider.DisplayName, provider.HomepageURL, provider.LogoURL).
+		Suffix(`ON CONFLICT (slug) DO UPDATE SET display_name = excluded.display_name, homepage_url = excluded.homepage_url, logo_url = excluded.logo_url, updated_at = now()`).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	return err
+}
+
+// SelectProviders lists the providers of the taxonomy.
+func (s Store) SelectProviders(ctx context.Context) ([]*domain.ProviderInfo, error) {
+	query, args, err := psql.Select().
+		Columns("slug", "display_name", "homepage_url", "logo_url", "created_at", "updated_at").
+		From("provider").
+		OrderBy("slug").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var providers []*domain.ProviderInfo
+	if err = s.connFromContext(ctx).SelectContext(ctx, &providers, query, args...); err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
+
+// nullIfEmpty stores empty taxonomy assignments as NULL so they satisfy the foreign keys.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
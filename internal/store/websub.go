@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	uuid "github.com/kevinburke/go.uuid"
+)
+
+var webSubSubscriptionColumns = []string{
+	"id",
+	"source_id",
+	"hub",
+	"topic",
+	"secret",
+	"status",
+	"lease_seconds",
+	"lease_expires_at",
+	"last_error",
+	"created_at",
+	"updated_at",
+	"previous_secret",
+	"attempts",
+	"next_attempt_at",
+}
+
+// CreateWebSubSubscription inserts a new subscription and returns its id.
+func (s Store) CreateWebSubSubscription(ctx context.Context, sub *domain.WebSubSubscription) (string, error) {
+	clauses := map[string]interface{}{
+		"source_id":     sub.SourceID,
+		"hub":           sub.Hub,
+		"topic":         sub.Topic,
+		"secret":        sub.Secret,
+		"status":        sub.Status,
+		"lease_seconds": sub.LeaseSeconds,
+	}
+
+	query, args, err := psql.
+		Insert("websub_subscription").
+		SetMap(clauses).
+		Suffix(`RETURNING id`).
+		ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	if err := s.connFromContext(ctx).GetContext(ctx, &id, query, args...); err != nil {
+		return "", fmt.Errorf("failed to return websub subscription id: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateWebSubSubscription records the hub, status and lease of the subscription.
+func (s Store) UpdateWebSubSubscription(ctx context.Context, sub *domain.WebSubSubscription) error {
+	clauses := map[string]interface{}{
+		"hub":              sub.Hub,
+		"topic":            sub.Topic,
+		"secret":           sub.Secret,
+		"status":           sub.Status,
+		"lease_seconds":    sub.LeaseSeconds,
+		"lease_expires_at": sub.LeaseExpiresAt,
+		"last_error":       sub.LastError,
+		"previous_secret":  sub.PreviousSecret,
+		"attempts":         sub.Attempts,
+		"next_attempt_at":  sub.NextAttemptAt,
+		"updated_at":       sq.Expr("now()"),
+	}
+
+	query, args, err := psql.
+		Update("websub_subscription").
+		SetMap(clauses).
+		Where(sq.Eq{"id": sub.ID}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := s.connFromContext(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+// GetWebSubSubscription returns a single subscription.
+func (s Store) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (*domain.WebSubSubscription, error) {
+	query, args, err := psql.Select().
+		Columns(webSubSubscriptionColumns...).
+		From("websub_subscription").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var sub domain.WebSubSubscription
+	if err := s.connFromContext(ctx).GetContext(ctx, &sub, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func applySelectWebSubSubscriptionFilters(f *domain.SelectWebSubSubscriptionFilters, query sq.SelectBuilder) sq.SelectBuilder {
+	if len(f.SourceIDs) > 0 {
+		query = query.Where(sq.Eq{"source_id": f.SourceIDs})
+	}
+
+	if len(f.Statuses) > 0 {
+		query = query.Where(sq.Eq{"status": f.Statuses})
+	}
+
+	if f.ExpiringBefore != nil {
+		query = query.Where(sq.Lt{"lease_expires_at": *f.ExpiringBefore})
+	}
+
+	return query
+}
+
+// SelectWebSubSubscriptions lists subscriptions, soonest expiring first.
+func (s Store) SelectWebSubSubscriptions(ctx context.Context, f *domain.SelectWebSubSubscriptionFilters) ([]*domain.WebSubSubscription, error) {
+	queryBuilder := psql.Select().
+		Columns(webSubSubscriptionColumns...).
+		From("websub_subscription").
+		OrderBy("lease_expires_at NULLS FIRST", "created_at")
+
+	if f != nil {
+		queryBuilder = applySelectWebSubSubscriptionFilters(f, queryBuilder)
+	}
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*domain.WebSubSubscription
+	if err = s.connFromContext(ctx).SelectContext(ctx, &subs, query, args...); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
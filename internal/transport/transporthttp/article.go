@@ -0,0 +1,199 @@
+package transporthttp
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/kevinburke/go.uuid"
+	"go.uber.org/zap"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+)
+
+// GetArticleContent allows the client to get the full content extracted from an
+// article's page, as sanitized HTML and plain text, along with the state of its extraction.
+// Example: GET /articles/{id}/content
+func (h *httpHandler) GetArticleContent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := articleID(w, r)
+	if !ok {
+		return
+	}
+
+	content, err := h.feedService.GetArticleContent(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrArticleContentNotFound) {
+			_ = WriteError(w, "article content not found", CodeNotFound)
+			return
+		}
+		errMsg := "error getting article content"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, content)
+}
+
+// ListArticleRevisions allows the client to see how an article was edited by
+// its publisher. Revisions are the previous versions of the article, most recent
+// first, each listing the fields the version after it changed.
+// Example: GET /articles/{id}/revisions
+func (h *httpHandler) ListArticleRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := articleID(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := h.feedService.ListArticleRevisions(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrArticleNotFound) {
+			_ = WriteError(w, "article not found", CodeNotFound)
+			return
+		}
+		errMsg := "error listing article revisions"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	if h.proxyImages {
+		for _, rev := range revisions {
+			rev.ThumbnailURL = h.imageURL(id, rev.ThumbnailURL)
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, revisions)
+}
+
+// ListStories allows the client to list the stories near-duplicate articles
+// of different feeds were grouped into, the most recently active first, each
+// with its articles.
+// Pagination is also supported by providing "limit" and "offset"
+// Example: GET /stories?limit=10
+func (h *httpHandler) ListStories(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		errMsg := "bad query params"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	stories, err := h.feedService.ListStories(ctx, &domain.SelectStoryFilters{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		errMsg := "error getting stories"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	for _, story := range stories {
+		h.rewriteImages(story.Articles)
+	}
+
+	writeJSON(w, r, http.StatusOK, stories)
+}
+
+// GetArticleImage allows the client to load an image of an article from this
+// service rather than the publisher, scaled down to the width "w". Widths are
+// rounded up to one of the widths served, the largest by default. The image
+// is the one of the version "v" the urls of listed articles carry, or the
+// current thumbnail without it, which is then only cached briefly.
+// Example: GET /images/0b7e2c4a-8f0e-4a55-9d0d-1f2a3b4c5d6e?v=3f2a9c01d4e5b6a7&w=320
+func (h *httpHandler) GetArticleImage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := articleID(w, r)
+	if !ok {
+		return
+	}
+
+	var width int
+	if v := r.URL.Query().Get("w"); v != "" {
+		var err error
+		width, err = strconv.Atoi(v)
+		if err != nil || width < 0 {
+			errMsg := "bad query params"
+			logging.Error(ctx, errMsg, zap.String("w", v))
+			_ = WriteError(w, errMsg, CodeBadRequest)
+			return
+		}
+	}
+	version := r.URL.Query().Get("v")
+
+	img, err := h.feedService.GetArticleImage(ctx, id, version, width)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrArticleNotFound):
+			_ = WriteError(w, "article not found", CodeNotFound)
+		case errors.Is(err, domain.ErrImageNotFound):
+			_ = WriteError(w, "image not found", CodeNotFound)
+		default:
+			errMsg := "error getting article image"
+			logging.Error(ctx, errMsg, zap.Error(err))
+			_ = WriteError(w, errMsg, CodeBadResponse)
+		}
+		return
+	}
+
+	w.Header().Set(ContentType, img.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	if version != "" {
+		w.Header().Set("Cache-Control", "private, max-age=604800")
+	} else {
+		// the thumbnail of an article changes when it is revised
+		w.Header().Set("Cache-Control", "private, max-age=300")
+	}
+	w.Header().Set("ETag", img.ETag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(img.Data))
+}
+
+// rewriteImages points the thumbnails and the images of the articles at the
+// image proxy.
+func (h *httpHandler) rewriteImages(articles []*domain.Article) {
+	if !h.proxyImages {
+		return
+	}
+	for _, a := range articles {
+		a.ThumbnailURL = h.imageURL(a.ID, a.ThumbnailURL)
+		for _, img := range a.Images {
+			img.URL = h.imageURL(a.ID, img.URL)
+		}
+	}
+}
+
+// imageURL is the url the image proxy serves the image of the article at.
+func (h *httpHandler) imageURL(articleID uuid.UUID, sourceURL string) string {
+	if sourceURL == "" {
+		return ""
+	}
+	return h.imageBaseURL + strings.Replace(EndpointArticleImage, "{id}", articleID.String(), 1) +
+		"?v=" + domain.ImageVersion(sourceURL)
+}
+
+func articleID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		errMsg := "bad article id"
+		logging.Error(r.Context(), errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
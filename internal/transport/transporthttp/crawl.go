@@ -0,0 +1,120 @@
+package transporthttp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/kevinburke/go.uuid"
+	"go.uber.org/zap"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+)
+
+// ListCrawlRuns allows the client to list the crawl runs, most recent first, optionally by "statuses".
+// Pagination is also supported by providing "limit" and "offset"
+// Example: GET /crawl-runs?statuses=failed,partial&limit=10
+func (h *httpHandler) ListCrawlRuns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		errMsg := "bad query params"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	filters := &domain.SelectCrawlRunFilters{
+		Statuses: parseCrawlStatuses(r.URL.Query().Get("statuses")),
+		Limit:    limit,
+		Offset:   offset,
+	}
+
+	runs, err := h.feedService.ListCrawlRuns(ctx, filters)
+	if err != nil {
+		errMsg := "error getting crawl runs"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, runs)
+}
+
+// GetCrawlRun allows the client to get a crawl run along with the result of each source crawled.
+// Example: GET /crawl-runs/0b7e2c4a-8f0e-4a55-9d0d-1f2a3b4c5d6e
+func (h *httpHandler) GetCrawlRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		errMsg := "bad crawl run id"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	run, err := h.feedService.GetCrawlRun(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrCrawlRunNotFound) {
+			_ = WriteError(w, "crawl run not found", CodeNotFound)
+			return
+		}
+		errMsg := "error getting crawl run"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, run)
+}
+
+// ListCrawlResults allows the client to list per-source crawl results by "sources" and "statuses",
+// which is handy to see which feeds are broken.
+// Pagination is also supported by providing "limit" and "offset"
+// Example: GET /crawl-results?statuses=failed&sources=http://feeds.bbci.co.uk/news/uk/rss.xml
+func (h *httpHandler) ListCrawlResults(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		errMsg := "bad query params"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	filters := &domain.SelectCrawlSourceResultFilters{
+		Statuses: parseCrawlStatuses(r.URL.Query().Get("statuses")),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	if sources := r.URL.Query().Get("sources"); sources != "" {
+		filters.Sources = strings.Split(sources, ",")
+	}
+
+	results, err := h.feedService.ListCrawlSourceResults(ctx, filters)
+	if err != nil {
+		errMsg := "error getting crawl results"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, results)
+}
+
+func parseCrawlStatuses(query string) []domain.CrawlStatus {
+	if query == "" {
+		return nil
+	}
+
+	var statuses []domain.CrawlStatus
+	for _, s := range strings.Split(query, ",") {
+		statuses = append(statuses, domain.CrawlStatus(s))
+	}
+	return statuses
+}
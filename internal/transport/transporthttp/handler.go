@@ -9,16 +9,39 @@ import (
 	"strings"
 
 	"github.com/gorilla/mux"
+	uuid "github.com/kevinburke/go.uuid"
 	"go.uber.org/zap"
 
 	"github.com/jeffreyyong/news-feeder/internal/app/listeners/httplistener"
 	"github.com/jeffreyyong/news-feeder/internal/domain"
 	"github.com/jeffreyyong/news-feeder/internal/logging"
+	"github.com/jeffreyyong/news-feeder/internal/opml"
 )
 
 const (
-	EndpointListArticles = "/articles"
-	EndpointShareArticle = "/article/share"
+	EndpointListArticles     = "/articles"
+	EndpointArticleContent   = "/articles/{id}/content"
+	EndpointArticleRevisions = "/articles/{id}/revisions"
+	EndpointShareArticle     = "/article/share"
+	EndpointListStories      = "/stories"
+	EndpointArticleImage     = "/images/{id}"
+	EndpointListCategories   = "/categories"
+	EndpointListProviders    = "/providers"
+	EndpointListCrawlRuns    = "/crawl-runs"
+	EndpointGetCrawlRun      = "/crawl-runs/{id}"
+	EndpointListCrawlResults = "/crawl-results"
+
+	EndpointAdmin             = "/admin"
+	EndpointAdminSources      = "/sources"
+	EndpointAdminSource       = "/sources/{id}"
+	EndpointAdminSourceEvents = "/sources/{id}/events"
+	EndpointAdminOPML         = "/sources/opml"
+	EndpointAdminPreview      = "/sources/preview"
+	EndpointAdminCategory     = "/categories/{slug}"
+	EndpointAdminProvider     = "/providers/{slug}"
+	EndpointAdminDiscover     = "/discover"
+
+	EndpointWebSubCallback = "/websub/callback/{id}"
 
 	ContentType     = "Content-Type"
 	ApplicationJSON = "application/json"
@@ -27,6 +50,33 @@ const (
 type FeedService interface {
 	ListArticles(ctx context.Context, f *domain.SelectArticleFilters) ([]*domain.Article, error)
 	ListFeeds(ctx context.Context, f *domain.SelectFeedFilters) ([]*domain.Feed, error)
+	GetArticleContent(ctx context.Context, articleID uuid.UUID) (*domain.ArticleContent, error)
+	ListArticleRevisions(ctx context.Context, articleID uuid.UUID) ([]*domain.ArticleRevision, error)
+	ListStories(ctx context.Context, f *domain.SelectStoryFilters) ([]*domain.Story, error)
+	GetArticleImage(ctx context.Context, articleID uuid.UUID, version string, width int) (*domain.Image, error)
+
+	ListCategories(ctx context.Context) ([]*domain.CategoryInfo, error)
+	ListProviders(ctx context.Context) ([]*domain.ProviderInfo, error)
+	PutCategory(ctx context.Context, category *domain.CategoryInfo) error
+	PutProvider(ctx context.Context, provider *domain.ProviderInfo) error
+
+	ListCrawlRuns(ctx context.Context, f *domain.SelectCrawlRunFilters) ([]*domain.CrawlRun, error)
+	GetCrawlRun(ctx context.Context, id uuid.UUID) (*domain.CrawlRun, error)
+	ListCrawlSourceResults(ctx context.Context, f *domain.SelectCrawlSourceResultFilters) ([]*domain.CrawlSourceResult, error)
+
+	CreateSource(ctx context.Context, source *domain.Source) (*domain.Source, error)
+	GetSource(ctx context.Context, id uuid.UUID) (*domain.Source, error)
+	ListSources(ctx context.Context, f *domain.SelectSourceFilters) ([]*domain.Source, error)
+	UpdateSource(ctx context.Context, id uuid.UUID, update *domain.SourceUpdate) (*domain.Source, error)
+	DeleteSource(ctx context.Context, id uuid.UUID) error
+	ListSourceEvents(ctx context.Context, id uuid.UUID) ([]*domain.SourceEvent, error)
+	PreviewScrape(ctx context.Context, req *domain.ScrapePreviewRequest) (*domain.ScrapePreview, error)
+	DiscoverFeeds(ctx context.Context, pageURL string) ([]*domain.FeedCandidate, error)
+	ImportOPML(ctx context.Context, doc *opml.Document) (*domain.SourceImportResult, error)
+	ExportOPML(ctx context.Context) (*opml.Document, error)
+
+	VerifyIntent(ctx context.Context, id uuid.UUID, mode, topic, challenge string, leaseSeconds int, reason string) (string, error)
+	HandlePush(ctx context.Context, id uuid.UUID, signature string, body []byte) error
 }
 
 type SocialService interface {
@@ -36,9 +86,13 @@ type SocialService interface {
 // httpHandler is the http handler that will enable
 // calls to this service via HTTP REST
 type httpHandler struct {
-	feedService     FeedService
-	socialService   SocialService
-	middlewareFuncs []mux.MiddlewareFunc
+	feedService          FeedService
+	socialService        SocialService
+	middlewareFuncs      []mux.MiddlewareFunc
+	adminMiddlewareFuncs []mux.MiddlewareFunc
+
+	proxyImages  bool
+	imageBaseURL string
 }
 
 // NewHTTPHandler will create a new instance of httpHandler
@@ -51,7 +105,12 @@ func NewHTTPHandler(feedService FeedService, socialService SocialService, opts .
 		return nil, fmt.Errorf("nil social service")
 	}
 
-	h := &httpHandler{feedService: feedService, socialService: socialService}
+	h := &httpHandler{
+		feedService:   feedService,
+		socialService: socialService,
+		// admin routes are closed until admin tokens are given
+		adminMiddlewareFuncs: []mux.MiddlewareFunc{NewAuthorizationMiddleware(nil)},
+	}
 	for _, opt := range opts {
 		if err := opt(h); err != nil {
 			return nil, err
@@ -63,9 +122,43 @@ func NewHTTPHandler(feedService FeedService, socialService SocialService, opts .
 
 // ApplyRoutes will link the HTTP REST endpoint to the corresponding function in this handler
 func (h *httpHandler) ApplyRoutes(m *httplistener.Mux) {
-	m.HandleFunc(EndpointListArticles, h.ListArticles).Methods(http.MethodGet)
-	m.HandleFunc(EndpointShareArticle, h.ShareArticle).Methods(http.MethodPost)
-	m.Use(h.middlewareFuncs...)
+	// hubs call back without a token, pushes are authenticated by their signature instead
+	m.HandleFunc(EndpointWebSubCallback, h.VerifyWebSubIntent).Methods(http.MethodGet)
+	m.HandleFunc(EndpointWebSubCallback, h.ReceiveWebSubPush).Methods(http.MethodPost)
+
+	// admin routes change what is crawled and hold credentials, so they take
+	// admin tokens rather than those of the read API
+	m.Group(m.PathPrefix(EndpointAdmin), func(m *httplistener.Mux) {
+		// registered ahead of the source routes so "opml" and "preview" are not taken for an id
+		m.HandleFunc(EndpointAdminOPML, h.ImportOPML).Methods(http.MethodPost)
+		m.HandleFunc(EndpointAdminOPML, h.ExportOPML).Methods(http.MethodGet)
+		m.HandleFunc(EndpointAdminPreview, h.PreviewScrape).Methods(http.MethodPost)
+		m.HandleFunc(EndpointAdminSources, h.CreateSource).Methods(http.MethodPost)
+		m.HandleFunc(EndpointAdminSources, h.ListSources).Methods(http.MethodGet)
+		m.HandleFunc(EndpointAdminSource, h.GetSource).Methods(http.MethodGet)
+		m.HandleFunc(EndpointAdminSource, h.UpdateSource).Methods(http.MethodPatch)
+		m.HandleFunc(EndpointAdminSource, h.DeleteSource).Methods(http.MethodDelete)
+		m.HandleFunc(EndpointAdminSourceEvents, h.ListSourceEvents).Methods(http.MethodGet)
+		m.HandleFunc(EndpointAdminCategory, h.PutCategory).Methods(http.MethodPut)
+		m.HandleFunc(EndpointAdminProvider, h.PutProvider).Methods(http.MethodPut)
+		m.HandleFunc(EndpointAdminDiscover, h.DiscoverFeeds).Methods(http.MethodGet)
+		m.Use(h.adminMiddlewareFuncs...)
+	})
+
+	m.Group(m.NewRoute(), func(m *httplistener.Mux) {
+		m.HandleFunc(EndpointListArticles, h.ListArticles).Methods(http.MethodGet)
+		m.HandleFunc(EndpointArticleContent, h.GetArticleContent).Methods(http.MethodGet)
+		m.HandleFunc(EndpointArticleRevisions, h.ListArticleRevisions).Methods(http.MethodGet)
+		m.HandleFunc(EndpointListStories, h.ListStories).Methods(http.MethodGet)
+		m.HandleFunc(EndpointArticleImage, h.GetArticleImage).Methods(http.MethodGet)
+		m.HandleFunc(EndpointShareArticle, h.ShareArticle).Methods(http.MethodPost)
+		m.HandleFunc(EndpointListCategories, h.ListCategories).Methods(http.MethodGet)
+		m.HandleFunc(EndpointListProviders, h.ListProviders).Methods(http.MethodGet)
+		m.HandleFunc(EndpointListCrawlRuns, h.ListCrawlRuns).Methods(http.MethodGet)
+		m.HandleFunc(EndpointGetCrawlRun, h.GetCrawlRun).Methods(http.MethodGet)
+		m.HandleFunc(EndpointListCrawlResults, h.ListCrawlResults).Methods(http.MethodGet)
+		m.Use(h.middlewareFuncs...)
+	})
 }
 
 // ShareArticle allows the client to share an interesting article link
@@ -106,9 +199,10 @@ func (h *httpHandler) ShareArticle(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ListArticles allows the client to list the articles by "categories" and "providers".
+// ListArticles allows the client to list the articles by "categories", "providers", "authors" and "tags".
+// With "collapse=story" only the most recent article of every story is listed.
 // Pagination is also supported by providing "limit" and "offset"
-// Example: GET /articles?categories=uk,technology&providers=bbc
+// Example: GET /articles?categories=uk,technology&providers=bbc&tags=Politics&collapse=story
 func (h *httpHandler) ListArticles(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	// get query params
@@ -117,11 +211,9 @@ func (h *httpHandler) ListArticles(w http.ResponseWriter, r *http.Request) {
 	var err error
 	if categoryQuery != "" {
 		categories := strings.Split(categoryQuery, ",")
-		domainCategories, err = mapCategory(categories)
+		domainCategories, err = h.mapCategory(ctx, categories)
 		if err != nil {
-			errMsg := "bad query params"
-			logging.Error(ctx, errMsg, zap.Error(err))
-			_ = WriteError(w, errMsg, CodeBadRequest)
+			writeTaxonomyError(w, r, "error getting categories", err)
 			return
 		}
 	}
@@ -130,11 +222,9 @@ func (h *httpHandler) ListArticles(w http.ResponseWriter, r *http.Request) {
 	var domainProviders []domain.Provider
 	if providerQuery != "" {
 		providers := strings.Split(providerQuery, ",")
-		domainProviders, err = mapProvider(providers)
+		domainProviders, err = h.mapProvider(ctx, providers)
 		if err != nil {
-			errMsg := "bad query params"
-			logging.Error(ctx, errMsg, zap.Error(err))
-			_ = WriteError(w, errMsg, CodeBadRequest)
+			writeTaxonomyError(w, r, "error getting providers", err)
 			return
 		}
 	}
@@ -168,6 +258,25 @@ func (h *httpHandler) ListArticles(w http.ResponseWriter, r *http.Request) {
 		Providers:  domainProviders,
 	}
 
+	if authors := r.URL.Query().Get("authors"); authors != "" {
+		selectArticlesFilter.Authors = strings.Split(authors, ",")
+	}
+
+	if tags := r.URL.Query().Get("tags"); tags != "" {
+		selectArticlesFilter.Tags = strings.Split(tags, ",")
+	}
+
+	switch collapse := r.URL.Query().Get("collapse"); collapse {
+	case "":
+	case "story":
+		selectArticlesFilter.CollapseStories = true
+	default:
+		errMsg := "bad query params"
+		logging.Error(ctx, errMsg, zap.String("collapse", collapse))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
 	if limitInt != 0 {
 		selectArticlesFilter.Limit = &limitInt
 	}
@@ -184,6 +293,8 @@ func (h *httpHandler) ListArticles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.rewriteImages(articles)
+
 	w.Header().Add(ContentType, ApplicationJSON)
 	err = json.NewEncoder(w).Encode(articles)
 	if err != nil {
@@ -194,28 +305,82 @@ func (h *httpHandler) ListArticles(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func mapCategory(categories []string) ([]domain.Category, error) {
-	domainCategories := make([]domain.Category, len(categories))
+// mapCategory checks the categories against the live taxonomy.
+func (h *httpHandler) mapCategory(ctx context.Context, categories []string) ([]domain.Category, error) {
+	supported, err := h.feedService.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	supportedCategory := make(map[domain.Category]bool, len(supported))
+	for _, c := range supported {
+		supportedCategory[c.Slug] = true
+	}
 
+	domainCategories := make([]domain.Category, 0, len(categories))
 	for _, c := range categories {
 		category := domain.Category(c)
-		if _, ok := domain.SupportedCategory[category]; !ok {
-			return nil, fmt.Errorf("unsupported category: %s", category)
+		if _, ok := supportedCategory[category]; !ok {
+			return nil, fmt.Errorf("%w: unsupported category: %s", domain.ErrInvalidTaxonomy, category)
 		}
 		domainCategories = append(domainCategories, category)
 	}
 	return domainCategories, nil
 }
 
-func mapProvider(providers []string) ([]domain.Provider, error) {
-	domainProviders := make([]domain.Provider, len(providers))
+// mapProvider checks the providers against the live taxonomy.
+func (h *httpHandler) mapProvider(ctx context.Context, providers []string) ([]domain.Provider, error) {
+	supported, err := h.feedService.ListProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
 
+	supportedProvider := make(map[domain.Provider]bool, len(supported))
+	for _, p := range supported {
+		supportedProvider[p.Slug] = true
+	}
+
+	domainProviders := make([]domain.Provider, 0, len(providers))
 	for _, c := range providers {
 		provider := domain.Provider(c)
-		if _, ok := domain.SupportedProvider[provider]; !ok {
-			return nil, fmt.Errorf("unsupported provider: %s", provider)
+		if _, ok := supportedProvider[provider]; !ok {
+			return nil, fmt.Errorf("%w: unsupported provider: %s", domain.ErrInvalidTaxonomy, provider)
 		}
 		domainProviders = append(domainProviders, provider)
 	}
 	return domainProviders, nil
 }
+
+// parsePagination reads the optional "limit" and "offset" query params.
+func parsePagination(r *http.Request) (limit, offset *uint64, err error) {
+	if l := r.URL.Query().Get("limit"); l != "" {
+		v, err := strconv.ParseUint(l, 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		if v != 0 {
+			limit = &v
+		}
+	}
+
+	if o := r.URL.Query().Get("offset"); o != "" {
+		v, err := strconv.ParseUint(o, 10, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		if v != 0 {
+			offset = &v
+		}
+	}
+
+	return limit, offset, nil
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Add(ContentType, ApplicationJSON)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		// the status has already been written, so all that is left is to log
+		logging.Error(r.Context(), "error encoding json response", zap.Error(err))
+	}
+}
@@ -0,0 +1,82 @@
+package transporthttp
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	"github.com/jeffreyyong/news-feeder/internal/opml"
+)
+
+const (
+	ContentTypeOPML = "text/x-opml"
+
+	maxOPMLSize = 10 << 20
+)
+
+// ImportOPML allows an admin to register the feeds of an OPML file as sources.
+// The file is either the raw request body or the "file" field of a multipart form.
+// Example: POST /admin/sources/opml
+func (h *httpHandler) ImportOPML(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxOPMLSize)
+	if strings.HasPrefix(r.Header.Get(ContentType), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxOPMLSize); err != nil {
+			errMsg := "bad multipart form"
+			logging.Error(ctx, errMsg, zap.Error(err))
+			_ = WriteError(w, errMsg, CodeBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			errMsg := "missing file"
+			logging.Error(ctx, errMsg, zap.Error(err))
+			_ = WriteError(w, errMsg, CodeBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	doc, err := opml.Parse(body)
+	if err != nil {
+		errMsg := "bad opml"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	result, err := h.feedService.ImportOPML(ctx, doc)
+	if err != nil {
+		errMsg := "error importing opml"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// ExportOPML allows an admin to download the registered sources as an OPML 2.0 file.
+// Example: GET /admin/sources/opml
+func (h *httpHandler) ExportOPML(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	doc, err := h.feedService.ExportOPML(ctx)
+	if err != nil {
+		errMsg := "error exporting opml"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	w.Header().Set(ContentType, ContentTypeOPML)
+	w.Header().Set("Content-Disposition", `attachment; filename="sources.opml"`)
+	if err := opml.Write(w, doc); err != nil {
+		logging.Error(ctx, "error encoding opml response", zap.Error(err))
+	}
+}
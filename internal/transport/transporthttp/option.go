@@ -4,6 +4,7 @@ import (
 	"github.com/gorilla/mux"
 
 	"net/http"
+	"strings"
 )
 
 const (
@@ -21,6 +22,16 @@ func WithAuth(privilegedTokens map[string]string) MiddlewareFunc {
 	}
 }
 
+// WithAdminAuth is a function configuration for the authorization of the
+// admin routes, which only take admin tokens. Without it the admin routes
+// refuse every token.
+func WithAdminAuth(adminTokens map[string]string) MiddlewareFunc {
+	return func(h *httpHandler) error {
+		h.adminMiddlewareFuncs = []mux.MiddlewareFunc{NewAuthorizationMiddleware(adminTokens)}
+		return nil
+	}
+}
+
 // HTTPAuthorizeRequest is the type to handles authorization of request
 type HTTPAuthorizeRequest struct {
 	next             http.Handler
@@ -52,3 +63,13 @@ func (a HTTPAuthorizeRequest) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	ctx := r.Context()
 	a.next.ServeHTTP(w, r.WithContext(ctx))
 }
+
+// WithImageProxy makes the thumbnails of listed articles point at the image
+// proxy, under baseURL when given or as a path otherwise.
+func WithImageProxy(baseURL string) MiddlewareFunc {
+	return func(h *httpHandler) error {
+		h.proxyImages = true
+		h.imageBaseURL = strings.TrimSuffix(baseURL, "/")
+		return nil
+	}
+}
@@ -0,0 +1,285 @@
+package transporthttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/kevinburke/go.uuid"
+	"go.uber.org/zap"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+)
+
+// CreateSource allows an admin to register a new feed source. The type is
+// "feed" unless given, scrape sources also take their "scrape_rules", feed
+// sources their "mapping_rules" and private sources their "auth", whose
+// secret is never returned.
+// Example: POST /admin/sources
+//
+//	{"url": "http://feeds.bbci.co.uk/news/uk/rss.xml", "enabled": true, "category": "uk", "provider": "bbc", "poll_interval": 300}
+func (h *httpHandler) CreateSource(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	type ReqBody struct {
+		URL          string               `json:"url"`
+		Type         domain.SourceType    `json:"type"`
+		ScrapeRules  *domain.ScrapeRules  `json:"scrape_rules"`
+		MappingRules *domain.MappingRules `json:"mapping_rules"`
+		Auth         *domain.SourceAuth   `json:"auth"`
+		Enabled      *bool                `json:"enabled"`
+		Category     domain.Category      `json:"category"`
+		Provider     domain.Provider      `json:"provider"`
+		PollInterval int                  `json:"poll_interval"`
+		Notes        string               `json:"notes"`
+	}
+
+	var reqBody ReqBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		errMsg := "bad request body"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	source := &domain.Source{
+		URL:          reqBody.URL,
+		Type:         reqBody.Type,
+		ScrapeRules:  reqBody.ScrapeRules,
+		MappingRules: reqBody.MappingRules,
+		Auth:         reqBody.Auth,
+		Enabled:      true,
+		Category:     reqBody.Category,
+		Provider:     reqBody.Provider,
+		PollInterval: reqBody.PollInterval,
+		Notes:        reqBody.Notes,
+	}
+	if reqBody.Enabled != nil {
+		source.Enabled = *reqBody.Enabled
+	}
+
+	created, err := h.feedService.CreateSource(ctx, source)
+	if err != nil {
+		writeSourceError(w, r, "error creating source", err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusCreated, created)
+}
+
+// ListSources allows an admin to list the registered sources, optionally by "enabled".
+// Pagination is also supported by providing "limit" and "offset"
+// Example: GET /admin/sources?enabled=false
+func (h *httpHandler) ListSources(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		errMsg := "bad query params"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	filters := &domain.SelectSourceFilters{
+		Limit:  limit,
+		Offset: offset,
+	}
+	if enabled := r.URL.Query().Get("enabled"); enabled != "" {
+		v, err := strconv.ParseBool(enabled)
+		if err != nil {
+			errMsg := "bad query params"
+			logging.Error(ctx, errMsg, zap.Error(err))
+			_ = WriteError(w, errMsg, CodeBadRequest)
+			return
+		}
+		filters.Enabled = &v
+	}
+
+	sources, err := h.feedService.ListSources(ctx, filters)
+	if err != nil {
+		errMsg := "error getting sources"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, sources)
+}
+
+// GetSource allows an admin to get a registered source.
+// Example: GET /admin/sources/0b7e2c4a-8f0e-4a55-9d0d-1f2a3b4c5d6e
+func (h *httpHandler) GetSource(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := sourceID(w, r)
+	if !ok {
+		return
+	}
+
+	source, err := h.feedService.GetSource(ctx, id)
+	if err != nil {
+		writeSourceError(w, r, "error getting source", err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, source)
+}
+
+// UpdateSource allows an admin to change some fields of a registered source,
+// fields missing from the body are left as they are.
+// Example: PATCH /admin/sources/0b7e2c4a-8f0e-4a55-9d0d-1f2a3b4c5d6e
+//
+//	{"enabled": false, "notes": "returns 500 since May"}
+func (h *httpHandler) UpdateSource(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := sourceID(w, r)
+	if !ok {
+		return
+	}
+
+	var update domain.SourceUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		errMsg := "bad request body"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	source, err := h.feedService.UpdateSource(ctx, id, &update)
+	if err != nil {
+		writeSourceError(w, r, "error updating source", err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, source)
+}
+
+// DeleteSource allows an admin to remove a registered source.
+// Example: DELETE /admin/sources/0b7e2c4a-8f0e-4a55-9d0d-1f2a3b4c5d6e
+func (h *httpHandler) DeleteSource(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := sourceID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.feedService.DeleteSource(ctx, id); err != nil {
+		writeSourceError(w, r, "error deleting source", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSourceEvents allows an admin to see how a source was updated or disabled
+// on its own, after it permanently redirected or answered 410 Gone.
+// Example: GET /admin/sources/0b7e2c4a-8f0e-4a55-9d0d-1f2a3b4c5d6e/events
+func (h *httpHandler) ListSourceEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := sourceID(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := h.feedService.ListSourceEvents(ctx, id)
+	if err != nil {
+		writeSourceError(w, r, "error getting source events", err)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, events)
+}
+
+// PreviewScrape allows an admin to try scrape rules on a listing page before
+// saving them on a source, nothing is stored. The warnings tell which
+// selectors missed.
+// Example: POST /admin/sources/preview
+//
+//	{"url": "https://example.com/news", "scrape_rules": {"item": "article.teaser", "title": "h2", "link": "a", "date": "time"}}
+func (h *httpHandler) PreviewScrape(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req domain.ScrapePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errMsg := "bad request body"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	preview, err := h.feedService.PreviewScrape(ctx, &req)
+	switch {
+	case errors.Is(err, domain.ErrInvalidSource):
+		_ = WriteError(w, err.Error(), CodeBadRequest)
+		return
+	case err != nil:
+		// the page could not be read, which is worth telling whoever tunes the rules
+		logging.Error(ctx, "error previewing scrape", zap.Error(err))
+		_ = WriteError(w, err.Error(), CodeBadResponse)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, preview)
+}
+
+// DiscoverFeeds allows an admin to find the feeds of a website from the url of
+// any of its pages. Candidates already registered carry their source id, any
+// other one is registered through CreateSource.
+// Example: GET /admin/discover?url=https://example.com
+func (h *httpHandler) DiscoverFeeds(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	pageURL := r.URL.Query().Get("url")
+	if pageURL == "" {
+		_ = WriteError(w, "missing url", CodeBadRequest)
+		return
+	}
+
+	candidates, err := h.feedService.DiscoverFeeds(ctx, pageURL)
+	switch {
+	case errors.Is(err, domain.ErrInvalidSource):
+		_ = WriteError(w, err.Error(), CodeBadRequest)
+		return
+	case err != nil:
+		logging.Error(ctx, "error discovering feeds", zap.Error(err))
+		_ = WriteError(w, err.Error(), CodeBadResponse)
+		return
+	}
+	if candidates == nil {
+		candidates = []*domain.FeedCandidate{}
+	}
+
+	writeJSON(w, r, http.StatusOK, candidates)
+}
+
+func sourceID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		errMsg := "bad source id"
+		logging.Error(r.Context(), errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeSourceError(w http.ResponseWriter, r *http.Request, errMsg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrSourceNotFound):
+		_ = WriteError(w, "source not found", CodeNotFound)
+	case errors.Is(err, domain.ErrSourceAlreadyExists):
+		_ = WriteError(w, "source already exists", CodeConflict)
+	case errors.Is(err, domain.ErrInvalidSource):
+		_ = WriteError(w, err.Error(), CodeBadRequest)
+	default:
+		logging.Error(r.Context(), errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+	}
+}
@@ -0,0 +1,102 @@
+package transporthttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+)
+
+// ListCategories allows the client to list the categories articles can be filtered by.
+// Example: GET /categories
+func (h *httpHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	categories, err := h.feedService.ListCategories(ctx)
+	if err != nil {
+		errMsg := "error getting categories"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, categories)
+}
+
+// ListProviders allows the client to list the providers articles can be filtered by.
+// Example: GET /providers
+func (h *httpHandler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	providers, err := h.feedService.ListProviders(ctx)
+	if err != nil {
+		errMsg := "error getting providers"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, providers)
+}
+
+// PutCategory allows an admin to create or update a category.
+// Example: PUT /admin/categories/politics
+//
+//	{"display_name": "Politics"}
+func (h *httpHandler) PutCategory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var category domain.CategoryInfo
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		errMsg := "bad request body"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+	category.Slug = domain.Category(mux.Vars(r)["slug"])
+
+	if err := h.feedService.PutCategory(ctx, &category); err != nil {
+		writeTaxonomyError(w, r, "error putting category", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PutProvider allows an admin to create or update a provider.
+// Example: PUT /admin/providers/guardian
+//
+//	{"display_name": "The Guardian", "homepage_url": "https://www.theguardian.com", "logo_url": ""}
+func (h *httpHandler) PutProvider(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var provider domain.ProviderInfo
+	if err := json.NewDecoder(r.Body).Decode(&provider); err != nil {
+		errMsg := "bad request body"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+	provider.Slug = domain.Provider(mux.Vars(r)["slug"])
+
+	if err := h.feedService.PutProvider(ctx, &provider); err != nil {
+		writeTaxonomyError(w, r, "error putting provider", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeTaxonomyError(w http.ResponseWriter, r *http.Request, errMsg string, err error) {
+	if errors.Is(err, domain.ErrInvalidTaxonomy) {
+		_ = WriteError(w, err.Error(), CodeBadRequest)
+		return
+	}
+	logging.Error(r.Context(), errMsg, zap.Error(err))
+	_ = WriteError(w, errMsg, CodeUnknownFailure)
+}
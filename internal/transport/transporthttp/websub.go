@@ -0,0 +1,89 @@
+package transporthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/kevinburke/go.uuid"
+	"go.uber.org/zap"
+
+	"github.com/jeffreyyong/news-feeder/internal/domain"
+	"github.com/jeffreyyong/news-feeder/internal/logging"
+	"github.com/jeffreyyong/news-feeder/internal/websub"
+)
+
+const maxPushSize = 10 << 20
+
+// VerifyWebSubIntent answers a hub verifying a subscription request by echoing the challenge.
+// Example: GET /websub/callback/{id}?hub.mode=subscribe&hub.topic=...&hub.challenge=...&hub.lease_seconds=864000
+func (h *httpHandler) VerifyWebSubIntent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		_ = WriteError(w, "subscription not found", CodeNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	var leaseSeconds int
+	if lease := q.Get("hub.lease_seconds"); lease != "" {
+		if leaseSeconds, err = strconv.Atoi(lease); err != nil {
+			_ = WriteError(w, "bad hub.lease_seconds", CodeBadRequest)
+			return
+		}
+	}
+
+	challenge, err := h.feedService.VerifyIntent(ctx, id, q.Get("hub.mode"), q.Get("hub.topic"), q.Get("hub.challenge"), leaseSeconds, q.Get("hub.reason"))
+	if err != nil {
+		// any error response tells the hub the request was not ours
+		logging.Error(ctx, "websub intent not verified", zap.String("id", id.String()), zap.Error(err))
+		_ = WriteError(w, "subscription not found", CodeNotFound)
+		return
+	}
+
+	w.Header().Set(ContentType, "text/plain")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, challenge)
+}
+
+// ReceiveWebSubPush takes the content a hub pushes for a subscription. Content
+// with an invalid signature is acknowledged but ignored, as the spec requires.
+// Example: POST /websub/callback/{id}
+func (h *httpHandler) ReceiveWebSubPush(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := uuid.FromString(mux.Vars(r)["id"])
+	if err != nil {
+		_ = WriteError(w, "subscription not found", CodeNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPushSize))
+	if err != nil {
+		errMsg := "bad request body"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeBadRequest)
+		return
+	}
+
+	err = h.feedService.HandlePush(ctx, id, r.Header.Get(websub.SignatureHeader), body)
+	switch {
+	case err == nil:
+	case errors.Is(err, domain.ErrInvalidSignature):
+		logging.Error(ctx, "ignoring websub push", zap.String("id", id.String()), zap.Error(err))
+	case errors.Is(err, domain.ErrSubscriptionNotFound):
+		_ = WriteError(w, "subscription not found", CodeNotFound)
+		return
+	default:
+		errMsg := "error handling websub push"
+		logging.Error(ctx, errMsg, zap.Error(err))
+		_ = WriteError(w, errMsg, CodeUnknownFailure)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
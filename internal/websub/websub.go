@@ -0,0 +1,236 @@
+package websub
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ModeSubscribe   = "subscribe"
+	ModeUnsubscribe = "unsubscribe"
+	ModeDenied      = "denied"
+
+	SignatureHeader = "X-Hub-Signature"
+
+	defaultTimeout = 30 * time.Second
+)
+
+var signatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// Client sends subscription requests to WebSub hubs, see https://www.w3.org/TR/websub/.
+type Client struct {
+	client *http.Client
+}
+
+func NewClient() *Client {
+	return &Client{client: &http.Client{Timeout: defaultTimeout}}
+}
+
+// SubscriptionRequest asks a hub to start or stop pushing a topic to a callback.
+type SubscriptionRequest struct {
+	Hub          string
+	Topic        string
+	Callback     string
+	Secret       string
+	LeaseSeconds int
+}
+
+// Subscribe asks the hub to push updates of the topic to the callback. The hub
+// acknowledges the request and then verifies the intent asynchronously by
+// calling the callback.
+func (c *Client) Subscribe(ctx context.Context, req SubscriptionRequest) error {
+	return c.send(ctx, ModeSubscribe, req)
+}
+
+// Unsubscribe asks the hub to stop pushing updates of the topic to the callback.
+func (c *Client) Unsubscribe(ctx context.Context, req SubscriptionRequest) error {
+	return c.send(ctx, ModeUnsubscribe, req)
+}
+
+func (c *Client) send(ctx context.Context, mode string, req SubscriptionRequest) error {
+	form := url.Values{
+		"hub.mode":     {mode},
+		"hub.topic":    {req.Topic},
+		"hub.callback": {req.Callback},
+	}
+	if req.Secret != "" {
+		form.Set("hub.secret", req.Secret)
+	}
+	if req.LeaseSeconds > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(req.LeaseSeconds))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("error creating hub request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("error sending hub request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub rejected %s request: %s: %s", mode, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Sign returns the signature header value for the body, as a hub would send it.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks the X-Hub-Signature header of a pushed body against
+// the secret the subscription was made with. Nothing verifies against an
+// empty secret, which anyone could sign with.
+func VerifySignature(secret, header string, body []byte) bool {
+	if secret == "" {
+		return false
+	}
+	parts := strings.SplitN(header, "=", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	method, sig := parts[0], parts[1]
+
+	newHash, ok := signatureHashes[strings.ToLower(method)]
+	if !ok {
+		return false
+	}
+
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// DiscoverLinks finds the hubs and the self url a feed advertises, either
+// through HTTP Link headers, rel="hub" and rel="self" links in an RSS or
+// Atom document, or the hubs of a JSON Feed.
+func DiscoverLinks(header http.Header, body []byte) (hubs []string, self string) {
+	seen := make(map[string]bool)
+	add := func(hub string) {
+		hub = strings.TrimSpace(hub)
+		if hub != "" && !seen[hub] {
+			seen[hub] = true
+			hubs = append(hubs, hub)
+		}
+	}
+
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, rels := parseLinkHeader(link)
+			for _, rel := range rels {
+				switch rel {
+				case "hub":
+					add(target)
+				case "self":
+					if self == "" {
+						self = target
+					}
+				}
+			}
+		}
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		var feed struct {
+			FeedURL string `json:"feed_url"`
+			Hubs    []struct {
+				Type string `json:"type"`
+				URL  string `json:"url"`
+			} `json:"hubs"`
+		}
+		if err := json.Unmarshal(trimmed, &feed); err == nil {
+			for _, h := range feed.Hubs {
+				if strings.EqualFold(h.Type, "websub") || strings.EqualFold(h.Type, "pubsubhubbub") {
+					add(h.URL)
+				}
+			}
+			if self == "" {
+				self = feed.FeedURL
+			}
+		}
+		return hubs, self
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.Strict = false
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok || el.Name.Local != "link" {
+			continue
+		}
+
+		var rel, href string
+		for _, attr := range el.Attr {
+			switch attr.Name.Local {
+			case "rel":
+				rel = attr.Value
+			case "href":
+				href = attr.Value
+			}
+		}
+		for _, r := range strings.Fields(rel) {
+			switch r {
+			case "hub":
+				add(href)
+			case "self":
+				if self == "" {
+					self = href
+				}
+			}
+		}
+	}
+
+	return hubs, self
+}
+
+// parseLinkHeader parses a single link of a Link header such as
+// <https://hub.example.com/>; rel="hub".
+func parseLinkHeader(link string) (target string, rels []string) {
+	parts := strings.Split(link, ";")
+	target = strings.Trim(strings.TrimSpace(parts[0]), "<>")
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "rel") {
+			continue
+		}
+		rels = append(rels, strings.Fields(strings.Trim(strings.TrimSpace(kv[1]), `"`))...)
+	}
+	return target, rels
+}
@@ -0,0 +1,47 @@
+package websub
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "s3cret"
+	body := []byte(`<feed><title>Storm hits coast</title></feed>`)
+
+	sha1Mac := hmac.New(sha1.New, []byte(secret))
+	sha1Mac.Write(body)
+	sha1Sig := "sha1=" + hex.EncodeToString(sha1Mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		want   bool
+	}{
+		{name: "sha256", secret: secret, header: Sign(secret, body), body: body, want: true},
+		{name: "sha1", secret: secret, header: sha1Sig, body: body, want: true},
+		{name: "method in upper case", secret: secret, header: strings.Replace(Sign(secret, body), "sha256", "SHA256", 1), body: body, want: true},
+		{name: "other secret", secret: "other", header: Sign(secret, body), body: body},
+		{name: "tampered body", secret: secret, header: Sign(secret, body), body: append([]byte(" "), body...)},
+		{name: "empty secret", secret: "", header: Sign("", body), body: body},
+		{name: "unknown method", secret: secret, header: strings.Replace(Sign(secret, body), "sha256", "md5", 1), body: body},
+		{name: "no method", secret: secret, header: strings.TrimPrefix(Sign(secret, body), "sha256="), body: body},
+		{name: "empty signature", secret: secret, header: "sha256=", body: body},
+		{name: "truncated signature", secret: secret, header: Sign(secret, body)[:20], body: body},
+		{name: "not hex", secret: secret, header: "sha256=zz", body: body},
+		{name: "missing header", secret: secret, header: "", body: body},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, tt.header, tt.body); got != tt.want {
+				t.Errorf("VerifySignature(%q, %q) = %v, want %v", tt.secret, tt.header, got, tt.want)
+			}
+		})
+	}
+}
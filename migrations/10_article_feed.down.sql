@@ -0,0 +1,7 @@
+DROP INDEX article_canonical_url_idx;
+
+ALTER TABLE article DROP COLUMN IF EXISTS canonical_url;
+
+DROP INDEX article_feed_feed_id_idx;
+
+DROP TABLE article_feed;
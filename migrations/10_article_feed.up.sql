@@ -0,0 +1,17 @@
+-- Letting an article belong to every feed that carried it and deduplicating articles by canonical url
+CREATE TABLE IF NOT EXISTS article_feed (
+    article_id uuid NOT NULL REFERENCES article (id) ON DELETE CASCADE,
+    feed_id uuid NOT NULL REFERENCES feed (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (article_id, feed_id)
+);
+
+CREATE INDEX article_feed_feed_id_idx ON article_feed (feed_id);
+
+INSERT INTO article_feed (article_id, feed_id, created_at)
+SELECT id, feed_id, created_at FROM article
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE article ADD COLUMN IF NOT EXISTS canonical_url text NOT NULL DEFAULT '';
+
+CREATE INDEX article_canonical_url_idx ON article (canonical_url) WHERE canonical_url <> '';
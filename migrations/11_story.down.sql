@@ -0,0 +1,10 @@
+DROP INDEX article_published_at_idx;
+
+DROP INDEX article_story_id_idx;
+
+ALTER TABLE article DROP COLUMN IF EXISTS shingles;
+ALTER TABLE article DROP COLUMN IF EXISTS story_id;
+
+DROP INDEX story_last_article_at_idx;
+
+DROP TABLE story;
@@ -0,0 +1,16 @@
+-- Grouping near-duplicate articles from different feeds into stories
+CREATE TABLE IF NOT EXISTS story (
+    id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+    title text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    last_article_at timestamptz NOT NULL
+);
+
+CREATE INDEX story_last_article_at_idx ON story (last_article_at);
+
+ALTER TABLE article ADD COLUMN IF NOT EXISTS story_id uuid REFERENCES story (id) ON DELETE SET NULL;
+ALTER TABLE article ADD COLUMN IF NOT EXISTS shingles bigint[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX article_story_id_idx ON article (story_id) WHERE story_id IS NOT NULL;
+CREATE INDEX article_published_at_idx ON article (published_at);
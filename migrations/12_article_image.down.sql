@@ -0,0 +1,4 @@
+DROP TABLE article_image;
+
+ALTER TABLE article DROP COLUMN IF EXISTS thumbnail_height;
+ALTER TABLE article DROP COLUMN IF EXISTS thumbnail_width;
@@ -0,0 +1,14 @@
+-- Keeping the size of article thumbnails and every image they were picked from
+ALTER TABLE article ADD COLUMN IF NOT EXISTS thumbnail_width integer NOT NULL DEFAULT 0;
+ALTER TABLE article ADD COLUMN IF NOT EXISTS thumbnail_height integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS article_image (
+    article_id uuid NOT NULL REFERENCES article (id) ON DELETE CASCADE,
+    position integer NOT NULL,
+    url text NOT NULL,
+    width integer NOT NULL DEFAULT 0,
+    height integer NOT NULL DEFAULT 0,
+    type text NOT NULL DEFAULT '',
+    origin varchar(16) NOT NULL,
+    PRIMARY KEY (article_id, position)
+);
@@ -0,0 +1,14 @@
+ALTER TABLE crawl_source_result ALTER COLUMN source TYPE varchar(255) USING left(source, 255);
+
+ALTER TABLE feed ALTER COLUMN last_modified TYPE varchar(255) USING left(last_modified, 255);
+ALTER TABLE feed ALTER COLUMN etag TYPE varchar(255) USING left(etag, 255);
+ALTER TABLE feed ALTER COLUMN feed_link TYPE varchar(255) USING left(feed_link, 255);
+ALTER TABLE feed ALTER COLUMN link TYPE varchar(255) USING left(link, 255);
+ALTER TABLE feed ALTER COLUMN description TYPE varchar(255) USING left(description, 255);
+ALTER TABLE feed ALTER COLUMN title TYPE varchar(255) USING left(title, 255);
+
+ALTER TABLE article ALTER COLUMN thumbnail_url TYPE varchar(255) USING left(thumbnail_url, 255);
+ALTER TABLE article ALTER COLUMN guid TYPE varchar(255) USING left(guid, 255);
+ALTER TABLE article ALTER COLUMN link TYPE varchar(255) USING left(link, 255);
+ALTER TABLE article ALTER COLUMN description TYPE varchar(255) USING left(description, 255);
+ALTER TABLE article ALTER COLUMN title TYPE varchar(255) USING left(title, 255);
@@ -0,0 +1,15 @@
+-- Widening the columns ingested text is stored in, so that one verbose item cannot fail a whole feed
+ALTER TABLE article ALTER COLUMN title TYPE text;
+ALTER TABLE article ALTER COLUMN description TYPE text;
+ALTER TABLE article ALTER COLUMN link TYPE text;
+ALTER TABLE article ALTER COLUMN guid TYPE text;
+ALTER TABLE article ALTER COLUMN thumbnail_url TYPE text;
+
+ALTER TABLE feed ALTER COLUMN title TYPE text;
+ALTER TABLE feed ALTER COLUMN description TYPE text;
+ALTER TABLE feed ALTER COLUMN link TYPE text;
+ALTER TABLE feed ALTER COLUMN feed_link TYPE text;
+ALTER TABLE feed ALTER COLUMN etag TYPE text;
+ALTER TABLE feed ALTER COLUMN last_modified TYPE text;
+
+ALTER TABLE crawl_source_result ALTER COLUMN source TYPE text;
@@ -0,0 +1,5 @@
+DROP INDEX source_event_source_id_idx;
+
+DROP TABLE source_event;
+
+ALTER TABLE source DROP COLUMN IF EXISTS dead_at;
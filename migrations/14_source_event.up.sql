@@ -0,0 +1,14 @@
+-- Recording the sources that moved or died, which are updated or disabled on their own
+ALTER TABLE source ADD COLUMN IF NOT EXISTS dead_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS source_event (
+    id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+    source_id uuid NOT NULL REFERENCES source (id) ON DELETE CASCADE,
+    kind varchar(32) NOT NULL,
+    old_url text NOT NULL,
+    new_url text NOT NULL DEFAULT '',
+    detail text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX source_event_source_id_idx ON source_event (source_id, created_at DESC);
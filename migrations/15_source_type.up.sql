@@ -0,0 +1,2 @@
+-- Sources are parsed according to their type, all sources so far are feeds
+ALTER TABLE source ADD COLUMN IF NOT EXISTS type varchar(32) NOT NULL DEFAULT 'feed';
@@ -0,0 +1,3 @@
+ALTER TABLE crawl_source_result DROP COLUMN IF EXISTS warnings;
+
+ALTER TABLE source DROP COLUMN IF EXISTS scrape_rules;
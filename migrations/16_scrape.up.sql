@@ -0,0 +1,5 @@
+-- Scrape sources keep the selectors their items are extracted with, and crawls
+-- report warnings when the selectors stop matching
+ALTER TABLE source ADD COLUMN IF NOT EXISTS scrape_rules jsonb;
+
+ALTER TABLE crawl_source_result ADD COLUMN IF NOT EXISTS warnings text[] NOT NULL DEFAULT '{}';
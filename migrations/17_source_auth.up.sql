@@ -0,0 +1,3 @@
+-- Private sources keep the credentials they are fetched with, encrypted with
+-- the key of the secrets config
+ALTER TABLE source ADD COLUMN IF NOT EXISTS auth bytea;
@@ -0,0 +1,6 @@
+SELECT lo_unlink(location::oid) FROM payload WHERE storage = 'postgres';
+
+DROP INDEX payload_source_id_idx;
+DROP INDEX payload_fetched_at_idx;
+
+DROP TABLE payload;
@@ -0,0 +1,21 @@
+-- Archived feed documents, replayed by the reprocess command. Bodies are kept
+-- on disk or as large objects, as configured when they were archived.
+CREATE TABLE IF NOT EXISTS payload (
+    id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+    -- not a reference, payloads outlive their source until they expire
+    source_id uuid NOT NULL,
+    origin varchar(16) NOT NULL,
+    feed_link text NOT NULL,
+    url text NOT NULL,
+    content_type text NOT NULL DEFAULT '',
+    etag text NOT NULL DEFAULT '',
+    last_modified text NOT NULL DEFAULT '',
+    size bigint NOT NULL,
+    stored_size bigint NOT NULL,
+    storage varchar(16) NOT NULL,
+    location text NOT NULL,
+    fetched_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX payload_fetched_at_idx ON payload (fetched_at);
+CREATE INDEX payload_source_id_idx ON payload (source_id, fetched_at);
@@ -0,0 +1 @@
+ALTER TABLE source DROP COLUMN IF EXISTS mapping_rules;
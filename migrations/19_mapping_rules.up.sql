@@ -0,0 +1,2 @@
+-- Feed sources may override how their items are mapped into articles
+ALTER TABLE source ADD COLUMN IF NOT EXISTS mapping_rules jsonb;
@@ -0,0 +1,9 @@
+DROP INDEX crawl_source_result_source_idx;
+
+DROP INDEX crawl_source_result_crawl_run_id_idx;
+
+DROP TABLE crawl_source_result;
+
+DROP INDEX crawl_run_started_at_idx;
+
+DROP TABLE crawl_run;
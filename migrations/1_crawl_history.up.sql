@@ -0,0 +1,32 @@
+-- Creating crawl_run table + indexes
+CREATE TABLE IF NOT EXISTS crawl_run (
+    id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+    status varchar(32) NOT NULL,
+    source_count integer NOT NULL DEFAULT 0,
+    failed_count integer NOT NULL DEFAULT 0,
+    items_new integer NOT NULL DEFAULT 0,
+    items_updated integer NOT NULL DEFAULT 0,
+    items_skipped integer NOT NULL DEFAULT 0,
+    started_at timestamptz NOT NULL DEFAULT now(),
+    finished_at timestamptz,
+    duration_ms bigint
+);
+
+CREATE INDEX crawl_run_started_at_idx ON crawl_run (started_at DESC);
+
+-- Creating crawl_source_result table + indexes
+CREATE TABLE IF NOT EXISTS crawl_source_result (
+    id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+    crawl_run_id uuid NOT NULL REFERENCES crawl_run (id) ON DELETE CASCADE,
+    source varchar(255) NOT NULL,
+    status varchar(32) NOT NULL,
+    error text NOT NULL DEFAULT '',
+    items_new integer NOT NULL DEFAULT 0,
+    items_updated integer NOT NULL DEFAULT 0,
+    items_skipped integer NOT NULL DEFAULT 0,
+    duration_ms bigint NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX crawl_source_result_crawl_run_id_idx ON crawl_source_result (crawl_run_id);
+CREATE INDEX crawl_source_result_source_idx ON crawl_source_result (source, created_at DESC);
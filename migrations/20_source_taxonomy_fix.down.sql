@@ -0,0 +1,13 @@
+-- The sources and feeds backfilled get back the taxonomy they had before,
+-- including any assigned to them through the admin API since
+UPDATE source SET category = source_taxonomy_fix.category, provider = source_taxonomy_fix.provider
+FROM source_taxonomy_fix
+WHERE source_taxonomy_fix.id = source.id;
+
+UPDATE feed SET category = feed_taxonomy_fix.category, provider = feed_taxonomy_fix.provider
+FROM feed_taxonomy_fix
+WHERE feed_taxonomy_fix.id = feed.id;
+
+DROP TABLE IF EXISTS feed_taxonomy_fix;
+
+DROP TABLE IF EXISTS source_taxonomy_fix;
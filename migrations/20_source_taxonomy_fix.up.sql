@@ -0,0 +1,55 @@
+-- 4_taxonomy assigned the sources seeded from the config by sniffing their
+-- url, which gave the bbc technology feed the uk category, and sources seeded
+-- later were registered without any taxonomy. This is a one-time backfill of
+-- the seeded sources alone, by their exact url; other sources are assigned
+-- through the admin API. Sources edited since are left as they are. What
+-- they had before is kept in source_taxonomy_fix and feed_taxonomy_fix for
+-- the down migration.
+CREATE TEMPORARY TABLE seeded_source (
+    url varchar(2048) NOT NULL PRIMARY KEY,
+    category varchar(64) NOT NULL,
+    provider varchar(64) NOT NULL
+);
+
+INSERT INTO seeded_source (url, category, provider) VALUES
+    ('http://feeds.bbci.co.uk/news/uk/rss.xml', 'uk', 'bbc'),
+    ('http://feeds.bbci.co.uk/news/technology/rss.xml', 'technology', 'bbc'),
+    ('http://feeds.skynews.com/feeds/rss/uk.xml', 'uk', 'sky'),
+    ('http://feeds.skynews.com/feeds/rss/technology.xml', 'technology', 'sky');
+
+CREATE TABLE IF NOT EXISTS source_taxonomy_fix (
+    id uuid NOT NULL PRIMARY KEY,
+    category varchar(64),
+    provider varchar(64)
+);
+
+CREATE TABLE IF NOT EXISTS feed_taxonomy_fix (
+    id uuid NOT NULL PRIMARY KEY,
+    category varchar(255) NOT NULL,
+    provider varchar(255) NOT NULL
+);
+
+INSERT INTO source_taxonomy_fix (id, category, provider)
+SELECT source.id, source.category, source.provider
+FROM source
+JOIN seeded_source ON seeded_source.url = source.url
+WHERE source.updated_at IS NULL
+    AND (source.category IS DISTINCT FROM seeded_source.category OR source.provider IS DISTINCT FROM seeded_source.provider);
+
+UPDATE source SET category = seeded_source.category, provider = seeded_source.provider
+FROM seeded_source, source_taxonomy_fix
+WHERE seeded_source.url = source.url AND source_taxonomy_fix.id = source.id;
+
+-- Feeds take the taxonomy of their source when crawled, the feeds of the
+-- sources fixed are brought in line right away
+INSERT INTO feed_taxonomy_fix (id, category, provider)
+SELECT feed.id, feed.category, feed.provider
+FROM feed
+JOIN source ON source.url = feed.feed_link
+JOIN source_taxonomy_fix ON source_taxonomy_fix.id = source.id;
+
+UPDATE feed SET category = source.category, provider = source.provider
+FROM source, feed_taxonomy_fix
+WHERE feed_taxonomy_fix.id = feed.id AND source.url = feed.feed_link;
+
+DROP TABLE seeded_source;
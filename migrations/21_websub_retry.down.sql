@@ -0,0 +1,5 @@
+ALTER TABLE websub_subscription DROP COLUMN IF EXISTS next_attempt_at;
+
+ALTER TABLE websub_subscription DROP COLUMN IF EXISTS attempts;
+
+ALTER TABLE websub_subscription DROP COLUMN IF EXISTS previous_secret;
@@ -0,0 +1,5 @@
+-- Failed subscriptions are retried with a backoff, and the secret a renewal
+-- replaces stays valid until the hub verifies the new one
+ALTER TABLE websub_subscription ADD COLUMN IF NOT EXISTS previous_secret varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE websub_subscription ADD COLUMN IF NOT EXISTS attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE websub_subscription ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz;
@@ -0,0 +1 @@
+ALTER TABLE article DROP COLUMN IF EXISTS hash_version;
@@ -0,0 +1,3 @@
+-- The version of the ingestion the hash of an article was computed under, so
+-- that articles hashed before it changed are re-baselined rather than revised
+ALTER TABLE article ADD COLUMN IF NOT EXISTS hash_version integer NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+-- Story candidates are only the articles sharing a shingle with the new ones
+CREATE INDEX IF NOT EXISTS article_shingles_idx ON article USING GIN (shingles);
@@ -0,0 +1,3 @@
+ALTER TABLE feed DROP COLUMN IF EXISTS last_modified;
+
+ALTER TABLE feed DROP COLUMN IF EXISTS etag;
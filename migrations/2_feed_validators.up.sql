@@ -0,0 +1,3 @@
+-- HTTP cache validators for conditional feed fetching
+ALTER TABLE feed ADD COLUMN IF NOT EXISTS etag varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE feed ADD COLUMN IF NOT EXISTS last_modified varchar(255) NOT NULL DEFAULT '';
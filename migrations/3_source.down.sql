@@ -0,0 +1,7 @@
+DROP INDEX crawl_source_result_source_id_idx;
+
+ALTER TABLE crawl_source_result DROP COLUMN IF EXISTS source_id;
+
+DROP INDEX source_enabled_idx;
+
+DROP TABLE source;
@@ -0,0 +1,19 @@
+-- Creating source table + indexes
+CREATE TABLE IF NOT EXISTS source (
+    id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+    url varchar(2048) NOT NULL UNIQUE,
+    enabled boolean NOT NULL DEFAULT true,
+    category varchar(255) NOT NULL DEFAULT '',
+    provider varchar(255) NOT NULL DEFAULT '',
+    poll_interval integer NOT NULL DEFAULT 0,
+    notes text NOT NULL DEFAULT '',
+    last_crawled_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz
+);
+
+CREATE INDEX source_enabled_idx ON source (enabled);
+
+ALTER TABLE crawl_source_result ADD COLUMN IF NOT EXISTS source_id uuid REFERENCES source (id) ON DELETE SET NULL;
+
+CREATE INDEX crawl_source_result_source_id_idx ON crawl_source_result (source_id);
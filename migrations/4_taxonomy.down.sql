@@ -0,0 +1,15 @@
+ALTER TABLE source DROP CONSTRAINT IF EXISTS source_provider_fkey;
+
+ALTER TABLE source DROP CONSTRAINT IF EXISTS source_category_fkey;
+
+UPDATE source SET category = '' WHERE category IS NULL;
+UPDATE source SET provider = '' WHERE provider IS NULL;
+
+ALTER TABLE source ALTER COLUMN category SET DEFAULT '';
+ALTER TABLE source ALTER COLUMN category SET NOT NULL;
+ALTER TABLE source ALTER COLUMN provider SET DEFAULT '';
+ALTER TABLE source ALTER COLUMN provider SET NOT NULL;
+
+DROP TABLE provider;
+
+DROP TABLE category;
@@ -0,0 +1,48 @@
+-- Creating category table
+CREATE TABLE IF NOT EXISTS category (
+    slug varchar(64) NOT NULL PRIMARY KEY,
+    display_name varchar(255) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz
+);
+
+-- Creating provider table
+CREATE TABLE IF NOT EXISTS provider (
+    slug varchar(64) NOT NULL PRIMARY KEY,
+    display_name varchar(255) NOT NULL,
+    homepage_url varchar(2048) NOT NULL DEFAULT '',
+    logo_url varchar(2048) NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz
+);
+
+INSERT INTO category (slug, display_name) VALUES
+    ('uk', 'UK'),
+    ('technology', 'Technology')
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO provider (slug, display_name, homepage_url, logo_url) VALUES
+    ('bbc', 'BBC News', 'https://www.bbc.co.uk/news', ''),
+    ('sky', 'Sky News', 'https://news.sky.com', '')
+ON CONFLICT (slug) DO NOTHING;
+
+-- Sources are explicitly assigned to the taxonomy, unassigned being NULL
+ALTER TABLE source ALTER COLUMN category DROP NOT NULL;
+ALTER TABLE source ALTER COLUMN category DROP DEFAULT;
+ALTER TABLE source ALTER COLUMN provider DROP NOT NULL;
+ALTER TABLE source ALTER COLUMN provider DROP DEFAULT;
+
+UPDATE source SET category = NULL WHERE category NOT IN (SELECT slug FROM category);
+UPDATE source SET provider = NULL WHERE provider NOT IN (SELECT slug FROM provider);
+
+-- Assign the sources that relied on the feed link being sniffed
+UPDATE source SET category = 'uk' WHERE category IS NULL AND lower(url) LIKE '%uk%';
+UPDATE source SET category = 'technology' WHERE category IS NULL AND lower(url) LIKE '%technology%';
+UPDATE source SET provider = 'sky' WHERE provider IS NULL AND lower(url) LIKE '%sky%';
+UPDATE source SET provider = 'bbc' WHERE provider IS NULL AND lower(url) LIKE '%bbc%';
+
+ALTER TABLE source ADD CONSTRAINT source_category_fkey FOREIGN KEY (category) REFERENCES category (slug) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE source ADD CONSTRAINT source_provider_fkey FOREIGN KEY (provider) REFERENCES provider (slug) ON UPDATE CASCADE ON DELETE SET NULL;
+
+UPDATE feed SET category = '' WHERE category = 'unknown';
+UPDATE feed SET provider = '' WHERE provider = 'unknown';
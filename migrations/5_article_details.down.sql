@@ -0,0 +1,11 @@
+DROP INDEX article_tags_idx;
+
+DROP INDEX article_authors_idx;
+
+ALTER TABLE article DROP COLUMN IF EXISTS item_updated_at;
+
+ALTER TABLE article DROP COLUMN IF EXISTS content;
+
+ALTER TABLE article DROP COLUMN IF EXISTS tags;
+
+ALTER TABLE article DROP COLUMN IF EXISTS authors;
@@ -0,0 +1,8 @@
+-- Keeping authors, tags, full content and the item's updated time of articles
+ALTER TABLE article ADD COLUMN IF NOT EXISTS authors text[] NOT NULL DEFAULT '{}';
+ALTER TABLE article ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+ALTER TABLE article ADD COLUMN IF NOT EXISTS content text NOT NULL DEFAULT '';
+ALTER TABLE article ADD COLUMN IF NOT EXISTS item_updated_at timestamptz;
+
+CREATE INDEX article_authors_idx ON article USING GIN (authors);
+CREATE INDEX article_tags_idx ON article USING GIN (tags);
@@ -0,0 +1,3 @@
+DROP INDEX websub_subscription_lease_expires_at_idx;
+
+DROP TABLE websub_subscription;
@@ -0,0 +1,16 @@
+-- Creating websub_subscription table + indexes
+CREATE TABLE IF NOT EXISTS websub_subscription (
+    id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+    source_id uuid NOT NULL UNIQUE REFERENCES source (id) ON DELETE CASCADE,
+    hub varchar(2048) NOT NULL,
+    topic varchar(2048) NOT NULL,
+    secret varchar(255) NOT NULL,
+    status varchar(32) NOT NULL,
+    lease_seconds integer NOT NULL DEFAULT 0,
+    lease_expires_at timestamptz,
+    last_error text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz
+);
+
+CREATE INDEX websub_subscription_lease_expires_at_idx ON websub_subscription (lease_expires_at);
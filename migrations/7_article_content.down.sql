@@ -0,0 +1,3 @@
+DROP INDEX article_content_status_idx;
+
+DROP TABLE article_content;
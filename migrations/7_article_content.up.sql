@@ -0,0 +1,15 @@
+-- Creating article_content table for the extracted full text of articles
+CREATE TABLE IF NOT EXISTS article_content (
+    article_id uuid NOT NULL PRIMARY KEY REFERENCES article (id) ON DELETE CASCADE,
+    status varchar(32) NOT NULL,
+    html text NOT NULL DEFAULT '',
+    text text NOT NULL DEFAULT '',
+    word_count integer NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    attempts integer NOT NULL DEFAULT 0,
+    extracted_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz
+);
+
+CREATE INDEX article_content_status_idx ON article_content (status, created_at);
@@ -0,0 +1,7 @@
+DROP INDEX source_next_poll_at_idx;
+
+ALTER TABLE source DROP COLUMN IF EXISTS skip_days;
+ALTER TABLE source DROP COLUMN IF EXISTS skip_hours;
+ALTER TABLE source DROP COLUMN IF EXISTS ttl;
+ALTER TABLE source DROP COLUMN IF EXISTS scheduled_interval;
+ALTER TABLE source DROP COLUMN IF EXISTS next_poll_at;
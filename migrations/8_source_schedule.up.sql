@@ -0,0 +1,11 @@
+-- Scheduling every source on its own, adapting to how often it publishes
+ALTER TABLE source ADD COLUMN IF NOT EXISTS next_poll_at timestamptz;
+ALTER TABLE source ADD COLUMN IF NOT EXISTS scheduled_interval integer NOT NULL DEFAULT 0;
+ALTER TABLE source ADD COLUMN IF NOT EXISTS ttl integer NOT NULL DEFAULT 0;
+ALTER TABLE source ADD COLUMN IF NOT EXISTS skip_hours integer[] NOT NULL DEFAULT '{}';
+ALTER TABLE source ADD COLUMN IF NOT EXISTS skip_days text[] NOT NULL DEFAULT '{}';
+
+-- sources keep their current due time, the ones never crawled stay due right away
+UPDATE source SET next_poll_at = last_crawled_at + make_interval(secs => poll_interval) WHERE last_crawled_at IS NOT NULL;
+
+CREATE INDEX source_next_poll_at_idx ON source (next_poll_at);
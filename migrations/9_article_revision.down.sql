@@ -0,0 +1,5 @@
+DROP INDEX article_revision_article_id_idx;
+
+DROP TABLE article_revision;
+
+ALTER TABLE article DROP COLUMN IF EXISTS content_hash;
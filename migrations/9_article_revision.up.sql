@@ -0,0 +1,20 @@
+-- Hashing articles to detect edits and keeping their previous versions
+ALTER TABLE article ADD COLUMN IF NOT EXISTS content_hash varchar(64) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS article_revision (
+    id uuid NOT NULL PRIMARY KEY DEFAULT uuid_generate_v4(),
+    article_id uuid NOT NULL REFERENCES article (id) ON DELETE CASCADE,
+    title text NOT NULL,
+    description text NOT NULL,
+    link text NOT NULL,
+    thumbnail_url text NOT NULL,
+    authors text[] NOT NULL DEFAULT '{}',
+    tags text[] NOT NULL DEFAULT '{}',
+    content text NOT NULL DEFAULT '',
+    item_updated_at timestamptz,
+    content_hash varchar(64) NOT NULL,
+    changed_fields text[] NOT NULL DEFAULT '{}',
+    revised_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX article_revision_article_id_idx ON article_revision (article_id, revised_at);